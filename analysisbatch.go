@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 형태소 분석 요청 하나에 묶을 최대 문서 수 (ANALYSIS_BATCH_SIZE로 변경, 1이면 문서마다 따로 요청)
+var analysisBatchSize = 1
+
+// 요청 하나에 묶을 본문의 추정 토큰 수 합의 상한 (ANALYSIS_BATCH_TOKENS로 변경, 혼자서 넘는 문서는 따로 요청)
+var analysisBatchTokens = 1000
+
+// 첫 문서가 들어온 뒤 배치가 차기를 기다리는 최대 시간 (ANALYSIS_BATCH_LINGER로 변경)
+// 단건 색인은 배치가 차지 않아도 이 시간만 기다린 뒤 요청한다.
+var analysisBatchLinger = 20 * time.Millisecond
+
+// 짧은 문서 여러 개를 요청 하나로 묶어 형태소 분석하는 도구
+// 동시에 분석을 요청한 문서를 analysisBatchSize개 또는 analysisBatchTokens 토큰까지 모아 문서 번호별 토큰 배열을 담은 JSON 객체를 요청하고,
+// 응답을 해석할 수 없거나 빠진 문서가 있으면 그 문서들만 따로 다시 요청한다.
+type analysisBatcher struct {
+	mu      sync.Mutex
+	pending []*batchedAnalysis
+	tokens  int
+	// 모으고 있는 배치의 번호 (linger 타이머가 이미 보낸 배치를 다시 보내지 않도록 확인)
+	generation int
+}
+
+// 배치에 넣은 문서 하나
+type batchedAnalysis struct {
+	ctx    context.Context
+	text   string
+	result chan batchedResult
+}
+
+type batchedResult struct {
+	tokens []string
+	err    error
+}
+
+var analysisBatches = &analysisBatcher{}
+
+// 동시에 분석할 수 있는 문서 수 (배치를 채울 수 있도록 작업자 수에 배치 크기를 곱함)
+func analysisConcurrency(workers int) int {
+	return workers * analysisBatchSize
+}
+
+// 배치에 넣어 형태소 분석하는 함수 (배치를 쓰지 않거나 본문이 길면 바로 요청)
+// ctx가 끝나면 기다리지 않고 반환하지만, 같은 배치의 다른 문서가 기다리는 동안에는 배치 요청을 멈추지 않는다.
+func (b *analysisBatcher) analyze(ctx context.Context, text string) ([]string, error) {
+	tokens := estimateTokens(text)
+	if analysisBatchSize <= 1 || tokens > analysisBatchTokens {
+		return requestMorphologicalAnalysis(ctx, text)
+	}
+
+	item := &batchedAnalysis{ctx: ctx, text: text, result: make(chan batchedResult, 1)}
+	b.mu.Lock()
+	if len(b.pending) > 0 && b.tokens+tokens > analysisBatchTokens {
+		b.sendLocked()
+	}
+	b.pending = append(b.pending, item)
+	b.tokens += tokens
+	if len(b.pending) >= analysisBatchSize {
+		b.sendLocked()
+	} else if len(b.pending) == 1 {
+		generation := b.generation
+		time.AfterFunc(analysisBatchLinger, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.generation == generation && len(b.pending) > 0 {
+				b.sendLocked()
+			}
+		})
+	}
+	b.mu.Unlock()
+
+	select {
+	case result := <-item.result:
+		return result.tokens, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// 모은 배치를 보내고 새 배치를 시작하는 함수 (mu를 잡은 채 호출)
+func (b *analysisBatcher) sendLocked() {
+	batch := b.pending
+	b.pending = nil
+	b.tokens = 0
+	b.generation++
+	go sendAnalysisBatch(batch)
+}
+
+// 배치 하나를 요청하고 문서별 결과를 돌려주는 함수
+func sendAnalysisBatch(batch []*batchedAnalysis) {
+	if len(batch) == 1 {
+		tokens, err := requestMorphologicalAnalysis(batch[0].ctx, batch[0].text)
+		batch[0].result <- batchedResult{tokens: tokens, err: err}
+		return
+	}
+
+	ctx, cancel := batchContext(batch)
+	defer cancel()
+	results, err := requestBatchAnalysis(ctx, batch)
+	if err != nil {
+		for _, item := range batch {
+			item.result <- batchedResult{err: err}
+		}
+		return
+	}
+	missing := 0
+	for i, item := range batch {
+		if tokens, ok := results[strconv.Itoa(i)]; ok {
+			item.result <- batchedResult{tokens: tokens}
+			continue
+		}
+		missing++
+		go func() {
+			tokens, err := requestMorphologicalAnalysis(item.ctx, item.text)
+			item.result <- batchedResult{tokens: tokens, err: err}
+		}()
+	}
+	if missing > 0 && results != nil {
+		log.Printf("Analysis batch response was missing %d of %d documents, analyzing them separately", missing, len(batch))
+	}
+}
+
+// 배치의 모든 문서를 기다리는 쪽이 떠나면 끝나는 context
+func batchContext(batch []*batchedAnalysis) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for _, item := range batch {
+			select {
+			case <-item.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// OpenAI API에 배치의 형태소 분석을 요청하는 함수 (문서 번호별 토큰 배열을 반환)
+// 응답을 해석할 수 없으면 빈 결과를 반환해 모든 문서를 따로 다시 요청하게 한다. 응답은 decodeModelJSON으로 너그럽게 해석한다.
+func requestBatchAnalysis(ctx context.Context, batch []*batchedAnalysis) (map[string][]string, error) {
+	texts := make(map[string]string, len(batch))
+	for i, item := range batch {
+		texts[strconv.Itoa(i)] = item.text
+	}
+	encoded, err := json.Marshal(texts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode analysis batch: %w", err)
+	}
+	prompt := fmt.Sprintf("Please analyze each of the following texts into its morphological components. The texts are given as a JSON object mapping text numbers to texts. Return only a JSON object mapping the same numbers to JSON arrays of strings: %s", encoded)
+
+	resp, err := createChatCompletion(ctx, analysisRequest(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API request failed: %v", err)
+	}
+
+	var results map[string][]string
+	if err := decodeModelJSON(responseContent(resp), &results); err != nil {
+		log.Printf("Failed to parse analysis batch response, analyzing %d documents separately: %v", len(batch), err)
+		return nil, nil
+	}
+	// 토큰이 비어 있는 문서는 빠진 것으로 보고 따로 다시 요청
+	for key, tokens := range results {
+		if validateAnalysisTokens(tokens) != nil {
+			delete(results, key)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// 형태소 분석 프롬프트의 버전 (프롬프트를 바꾸면 올려서 이전 프롬프트의 캐시를 쓰지 않도록 함)
+const analysisPromptVersion = 2
+
+// 메모리에 보관할 형태소 분석 결과 수 (ANALYSIS_CACHE_SIZE로 변경, 0이면 Postgres 캐시만 사용)
+var analysisCacheSize = 10000
+
+// 형태소 분석 결과 캐시
+// 같은 본문을 같은 모델과 프롬프트로 분석한 결과를 analysis_cache 테이블에 저장하고, 최근에 쓴 결과는 메모리(LRU)에도 보관한다.
+// 캐시를 읽거나 쓰지 못하면 기록만 하고 OpenAI API로 분석한다.
+type analysisCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// 메모리에 보관하는 캐시 항목
+type analysisCacheEntry struct {
+	key    string
+	tokens []string
+}
+
+var analysisResults = &analysisCache{entries: make(map[string]*list.Element), order: list.New()}
+
+// 본문, 모델, 프롬프트 버전으로 캐시 키를 만드는 함수
+func analysisCacheKey(text string) string {
+	sum := sha256.Sum256([]byte(analysisModel + "\x00" + strconv.Itoa(analysisPromptVersion) + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// 캐시된 분석 결과를 찾는 함수 (메모리에 없으면 Postgres에서 찾아 메모리에 보관)
+func (c *analysisCache) lookup(ctx context.Context, key string) ([]string, bool) {
+	if tokens, ok := c.cached(key); ok {
+		return tokens, true
+	}
+	if db == nil {
+		return nil, false
+	}
+	var tokens []string
+	err := db.QueryRowContext(ctx, "SELECT tokens FROM analysis_cache WHERE key = $1", key).Scan(pq.Array(&tokens))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to read analysis cache: %v", err)
+		return nil, false
+	}
+	c.remember(key, tokens)
+	return tokens, true
+}
+
+// 분석 결과를 Postgres와 메모리에 저장하는 함수 (데이터베이스 없이 실행하면 메모리에만 저장)
+func (c *analysisCache) store(ctx context.Context, key string, tokens []string) {
+	if db == nil {
+		c.remember(key, tokens)
+		return
+	}
+	_, err := db.ExecContext(ctx, "INSERT INTO analysis_cache(key, model, prompt_version, tokens) VALUES($1, $2, $3, $4) ON CONFLICT (key) DO NOTHING",
+		key, analysisModel, analysisPromptVersion, pq.Array(tokens))
+	if err != nil {
+		log.Printf("Failed to write analysis cache: %v", err)
+	}
+	c.remember(key, tokens)
+}
+
+// 메모리에 보관한 분석 결과를 찾는 함수 (찾으면 가장 최근에 쓴 항목으로 옮김)
+func (c *analysisCache) cached(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*analysisCacheEntry).tokens, true
+}
+
+// 분석 결과를 메모리에 보관하는 함수 (analysisCacheSize를 넘으면 가장 오래 쓰지 않은 항목을 버림)
+func (c *analysisCache) remember(key string, tokens []string) {
+	if analysisCacheSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&analysisCacheEntry{key: key, tokens: tokens})
+	for c.order.Len() > analysisCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*analysisCacheEntry).key)
+	}
+}
+
+// 캐시를 비우는 함수 (model을 지정하면 그 모델의 결과만 지움, 지운 Postgres 행 수를 반환)
+// 메모리 항목은 모델을 기록하지 않으므로 항상 모두 비운다.
+func (c *analysisCache) invalidate(ctx context.Context, model string) (int64, error) {
+	var result sql.Result
+	var err error
+	if db == nil {
+		c.clear()
+		return 0, nil
+	}
+	if model == "" {
+		result, err = db.ExecContext(ctx, "DELETE FROM analysis_cache")
+	} else {
+		result, err = db.ExecContext(ctx, "DELETE FROM analysis_cache WHERE model = $1", model)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed to clear analysis cache: %w", err)
+	}
+	c.clear()
+	return result.RowsAffected()
+}
+
+// 메모리에 보관한 분석 결과를 모두 버리는 함수
+func (c *analysisCache) clear() {
+	c.mu.Lock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.mu.Unlock()
+}
+
+// 캐시를 거쳐 형태소 분석을 수행하는 함수 (캐시에서 찾았으면 true)
+// analysisChunkTokens보다 긴 본문은 조각으로 나눠 조각마다 이 함수로 분석한다.
+func analyzeContent(ctx context.Context, text string) ([]string, bool, error) {
+	if chunks := splitAnalysisChunks(text, analysisChunkTokens); len(chunks) > 1 {
+		return analyzeChunks(ctx, chunks, analyzeContent)
+	}
+	key := analysisCacheKey(text)
+	if tokens, ok := analysisResults.lookup(ctx, key); ok {
+		return tokens, true, nil
+	}
+	tokens, err := analysisBatches.analyze(ctx, text)
+	if err != nil {
+		return nil, false, err
+	}
+	analysisResults.store(ctx, key, tokens)
+	return tokens, false, nil
+}
+
+// 형태소 분석 캐시 삭제 핸들러 (DELETE /admin/analysis-cache?model=gpt-4)
+// 프롬프트나 모델을 바꾼 뒤 이전 결과가 남지 않도록 캐시를 비우며, model을 지정하면 그 모델의 결과만 지운다.
+func analysisCacheHandler(w http.ResponseWriter, r *http.Request) {
+	deleted, err := analysisResults.invalidate(r.Context(), r.URL.Query().Get("model"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"deleted": deleted})
+}
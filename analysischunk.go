@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 형태소 분석 요청 하나에 보낼 본문의 추정 토큰 수 상한 (ANALYSIS_CHUNK_TOKENS로 변경)
+// 응답은 본문의 analysisCompletionRatio배 정도이므로 기본값은 응답이 analysisMaxTokens 안에 들어오도록 정했다.
+// 더 긴 문서는 문단, 문장, 단어 경계에서 나눠 조각마다 분석한 뒤 토큰 목록을 순서대로 이어 붙인다.
+var analysisChunkTokens = 1500
+
+// 문서 하나의 조각을 동시에 분석하는 최대 수
+const analysisChunkWorkers = 8
+
+// 조각을 나누는 경계 (문단, 문장, 단어 순으로 더 잘게 나눔)
+// 경계의 공백과 문장 부호는 앞 조각에 붙여, 조각을 이어 붙이면 원래 본문이 된다.
+var chunkBoundaries = []*regexp.Regexp{
+	regexp.MustCompile(`\n[ \t\r]*\n\s*`),
+	regexp.MustCompile(`[.!?。！？…]+["'”’)\]]*\s+`),
+	regexp.MustCompile(`\s+`),
+}
+
+// 본문을 추정 토큰 수가 budget 이하인 조각으로 나누는 함수 (공백만 있는 조각은 버림)
+// 경계 안에서 최대한 많이 이어 붙이며, 경계 없이 budget을 넘는 단어만 글자 단위로 나눈다.
+func splitAnalysisChunks(text string, budget int) []string {
+	if estimateTokens(text) <= budget {
+		return []string{text}
+	}
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+	for _, piece := range chunkPieces(text, budget, 0) {
+		tokens := estimateTokens(piece)
+		if currentTokens+tokens > budget {
+			flush()
+		}
+		current.WriteString(piece)
+		currentTokens += tokens
+	}
+	flush()
+	return chunks
+}
+
+// 본문을 각각 budget 이하인 조각으로 나누는 함수 (level번째 경계부터 사용)
+func chunkPieces(text string, budget, level int) []string {
+	if estimateTokens(text) <= budget {
+		return []string{text}
+	}
+	if level == len(chunkBoundaries) {
+		return splitRunes(text, budget)
+	}
+	var pieces []string
+	start := 0
+	for _, match := range chunkBoundaries[level].FindAllStringIndex(text, -1) {
+		pieces = append(pieces, chunkPieces(text[start:match[1]], budget, level+1)...)
+		start = match[1]
+	}
+	if start < len(text) {
+		pieces = append(pieces, chunkPieces(text[start:], budget, level+1)...)
+	}
+	return pieces
+}
+
+// 경계 없이 긴 단어를 글자 단위로 나누는 함수 (여러 바이트인 글자는 나누지 않음)
+// 조각의 토큰 수는 estimateTokens와 같은 방식으로 글자를 읽으며 센다.
+func splitRunes(text string, budget int) []string {
+	var pieces []string
+	start := 0
+	var cjkChars, otherChars int
+	for i, r := range text {
+		nextCJK, nextOther := cjkChars, otherChars
+		switch {
+		case unicode.In(r, unicode.Hangul, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			nextCJK++
+		case !unicode.IsSpace(r):
+			nextOther++
+		}
+		if i > start && nextCJK+(nextOther+latinCharsPerToken-1)/latinCharsPerToken > budget {
+			pieces = append(pieces, text[start:i])
+			start = i
+			nextCJK, nextOther = nextCJK-cjkChars, nextOther-otherChars
+		}
+		cjkChars, otherChars = nextCJK, nextOther
+	}
+	return append(pieces, text[start:])
+}
+
+// 긴 본문의 조각을 analyze로 동시에 분석하고 토큰 목록을 순서대로 이어 붙이는 함수 (모든 조각을 캐시에서 찾았으면 true)
+// analyzeContent를 넘기면 조각마다 캐시를 거치므로, 긴 문서의 일부만 바뀌면 바뀐 조각만 다시 분석한다.
+// 한 조각이라도 실패하면 나머지 조각의 분석을 멈추고 오류를 반환한다.
+func analyzeChunks(ctx context.Context, chunks []string, analyze func(context.Context, string) ([]string, bool, error)) ([]string, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]string, len(chunks))
+	hits := make([]bool, len(chunks))
+	var firstErr error
+	var errOnce sync.Once
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(analysisChunkWorkers, len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tokens, hit, err := analyze(ctx, chunks[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[i], hits[i] = tokens, hit
+			}
+		}()
+	}
+	for i := range chunks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, false, firstErr
+	}
+
+	var tokens []string
+	cacheHit := true
+	for i := range chunks {
+		tokens = append(tokens, results[i]...)
+		cacheHit = cacheHit && hits[i]
+	}
+	return tokens, cacheHit, nil
+}
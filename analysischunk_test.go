@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 공백을 모두 지운 문자열 (조각을 나눈 경계의 공백은 버려지므로 비교할 때 사용)
+func withoutSpace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+func TestSplitAnalysisChunks(t *testing.T) {
+	paragraph := "서울시는 오늘 시청 앞 광장에서 행사를 열었다. The mayor opened the festival! 시민들은 무엇을 했을까? 모두 즐거워했다.\n\n"
+	for _, tt := range []struct {
+		name   string
+		text   string
+		budget int
+		chunks int // 0이면 개수를 확인하지 않음
+	}{
+		{"short text", "서울 시청 호텔", 50, 1},
+		{"paragraphs", strings.Repeat(paragraph, 10), 60, 0},
+		{"sentences without paragraphs", strings.Repeat("문장이 하나 있다. ", 40), 30, 0},
+		{"long word without boundaries", strings.Repeat("가", 1000), 300, 4},
+		{"latin word without boundaries", strings.Repeat("abcd", 500), 100, 5},
+		{"multi-byte runes", strings.Repeat("日本語テキスト🙂é ", 200), 40, 0},
+		{"leading and trailing space", "\n\n  " + strings.Repeat("단어 ", 200) + "  \n", 50, 0},
+		{"100KB document", strings.Repeat(paragraph, 100*1024/len(paragraph)+1), analysisChunkTokens, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitAnalysisChunks(tt.text, tt.budget)
+			if tt.chunks > 0 && len(chunks) != tt.chunks {
+				t.Errorf("got %d chunks, want %d", len(chunks), tt.chunks)
+			}
+			rest := tt.text
+			for i, chunk := range chunks {
+				if tokens := estimateTokens(chunk); tokens > tt.budget {
+					t.Errorf("chunk %d has %d tokens, budget is %d", i, tokens, tt.budget)
+				}
+				if !utf8.ValidString(chunk) {
+					t.Errorf("chunk %d is not valid UTF-8", i)
+				}
+				if strings.TrimSpace(chunk) == "" {
+					t.Errorf("chunk %d is blank", i)
+				}
+				// 조각은 원래 순서대로 본문에 나타나야 함
+				at := strings.Index(rest, chunk)
+				if at < 0 {
+					t.Fatalf("chunk %d does not follow the previous chunk in the text", i)
+				}
+				rest = rest[at+len(chunk):]
+			}
+			if got, want := withoutSpace(strings.Join(chunks, "")), withoutSpace(tt.text); got != want {
+				t.Errorf("joined chunks lost text: got %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestAnalyzeChunks(t *testing.T) {
+	chunks := []string{"하나 둘", "셋", "넷 다섯", "여섯", "일곱", "여덟", "아홉", "열", "열하나"}
+
+	var calls atomic.Int32
+	split := func(_ context.Context, chunk string) ([]string, bool, error) {
+		calls.Add(1)
+		return strings.Fields(chunk), chunk != "셋", nil
+	}
+	tokens, cacheHit, err := analyzeChunks(context.Background(), chunks, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(tokens, " "), strings.Join(chunks, " "); got != want {
+		t.Errorf("tokens = %q, want %q in chunk order", got, want)
+	}
+	if cacheHit {
+		t.Error("cacheHit = true although one chunk was analyzed")
+	}
+	if int(calls.Load()) != len(chunks) {
+		t.Errorf("analyzed %d chunks, want %d", calls.Load(), len(chunks))
+	}
+
+	_, cacheHit, err = analyzeChunks(context.Background(), chunks[:2], func(_ context.Context, chunk string) ([]string, bool, error) {
+		return []string{chunk}, true, nil
+	})
+	if err != nil || !cacheHit {
+		t.Errorf("all cached chunks: cacheHit = %t, err = %v, want true and nil", cacheHit, err)
+	}
+
+	// 한 조각이 실패하면 나머지 조각의 context가 취소되고 첫 오류를 반환
+	failed := errors.New("analysis failed")
+	_, _, err = analyzeChunks(context.Background(), chunks, func(ctx context.Context, chunk string) ([]string, bool, error) {
+		if chunk == chunks[0] {
+			return nil, false, failed
+		}
+		<-ctx.Done()
+		return nil, false, ctx.Err()
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("err = %v, want %v", err, failed)
+	}
+}
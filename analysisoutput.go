@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// 오류에 붙이는 모델 응답의 최대 길이 (글자 수)
+const analysisOutputPreviewLength = 200
+
+// JSON 응답 형식(response_format)을 받지 않는 모델 (이 모델은 프롬프트만으로 JSON을 요청하고 응답을 너그럽게 해석함)
+var jsonModeUnsupportedModels = map[string]bool{
+	openai.GPT4:        true,
+	openai.GPT40314:    true,
+	openai.GPT40613:    true,
+	openai.GPT432K:     true,
+	openai.GPT432K0314: true,
+	openai.GPT432K0613: true,
+}
+
+// 응답의 첫 번째 선택지 내용 (선택지가 없으면 빈 문자열)
+func responseContent(resp openai.ChatCompletionResponse) string {
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Message.Content
+}
+
+// 모델 응답에서 JSON 값을 읽는 함수
+// 응답 전체를 먼저 해석하고, 실패하면 마크다운 코드 블록(```json ... ```)을 벗겨 다시 해석하며,
+// 그래도 실패하면 앞뒤에 붙은 설명을 건너뛰고 처음 나오는 JSON 객체나 배열을 읽는다.
+func decodeModelJSON(content string, v interface{}) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return errors.New("empty response")
+	}
+	err := json.Unmarshal([]byte(content), v)
+	if err == nil {
+		return nil
+	}
+	if fenced, ok := stripCodeFence(content); ok {
+		if json.Unmarshal([]byte(fenced), v) == nil {
+			return nil
+		}
+	}
+	if start := strings.IndexAny(content, "{["); start >= 0 {
+		if json.NewDecoder(strings.NewReader(content[start:])).Decode(v) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// 응답의 첫 마크다운 코드 블록 안의 내용을 반환하는 함수 (코드 블록이 없으면 false)
+func stripCodeFence(content string) (string, bool) {
+	_, rest, ok := strings.Cut(content, "```")
+	if !ok {
+		return "", false
+	}
+	// 여는 줄의 언어 표시(json 등)는 버림
+	if newline := strings.IndexByte(rest, '\n'); newline >= 0 {
+		rest = rest[newline+1:]
+	}
+	body, _, _ := strings.Cut(rest, "```")
+	return strings.TrimSpace(body), true
+}
+
+// 형태소 분석 응답을 토큰 배열로 해석하는 함수
+// {"tokens": [...]} 형식을 요청하지만, 배열만 돌려준 응답도 받는다.
+func parseAnalysisTokens(content string) ([]string, error) {
+	var raw json.RawMessage
+	if err := decodeModelJSON(content, &raw); err != nil {
+		return nil, fmt.Errorf("Failed to parse JSON response: %v", err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		var wrapped struct {
+			Tokens json.RawMessage `json:"tokens"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("Failed to parse JSON response: %v", err)
+		}
+		if wrapped.Tokens == nil {
+			return nil, errors.New("response has no 'tokens' field")
+		}
+		raw = wrapped.Tokens
+	}
+	var tokens []string
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return nil, errors.New("'tokens' must be an array of strings")
+	}
+	if err := validateAnalysisTokens(tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// 형태소 분석 결과가 빈 문자열이 없는 비어 있지 않은 배열인지 확인하는 함수
+func validateAnalysisTokens(tokens []string) error {
+	if len(tokens) == 0 {
+		return errors.New("'tokens' is empty")
+	}
+	for i, token := range tokens {
+		if strings.TrimSpace(token) == "" {
+			return fmt.Errorf("token %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// 잘못된 응답을 받은 뒤 한 번 더 요청할 때 덧붙이는 대화 (모델의 응답과 무엇이 잘못됐는지)
+func correctionMessages(content string, err error) []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: content,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("Your reply could not be used (%v). Reply with only a JSON object of the form {\"tokens\": [\"...\"]} where tokens is a non-empty array of non-empty strings, without code fences or any other text.", err),
+		},
+	}
+}
+
+// 해석할 수 없는 응답의 오류 (디버깅할 수 있도록 응답 앞부분을 붙임)
+func invalidAnalysisError(content string, err error) error {
+	return fmt.Errorf("Invalid analysis response: %v (output: %q)", err, previewText(content, analysisOutputPreviewLength))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// FIELD_ANALYZERS로 지정한 필드별 분석기 (지정하지 않은 필드는 기본 분석기 사용, 변경 시 인덱스를 다시 만들어야 적용됨)
+var fieldAnalyzers map[string]string
+
+// FIELD_ANALYZERS 값을 필드별 분석기로 해석하는 함수 (예: title:cjk,tags:keyword,metadata.abstract:standard)
+// 분석기 이름이 올바른지는 매핑을 만들 때 applyFieldAnalyzers에서 확인한다.
+func parseFieldAnalyzers(raw string) (map[string]string, error) {
+	analyzers := make(map[string]string)
+	for _, entry := range splitParamList(raw) {
+		field, analyzer, ok := strings.Cut(entry, ":")
+		field, analyzer = strings.TrimSpace(field), strings.TrimSpace(analyzer)
+		if !ok || field == "" || analyzer == "" {
+			return nil, fmt.Errorf("expected field:analyzer, got '%s'", entry)
+		}
+		analyzers[field] = analyzer
+	}
+	return analyzers, nil
+}
+
+// 문서 매핑의 텍스트 필드에 필드별 분석기를 적용하는 함수
+// 분석기는 bleve에 등록된 분석기(cjk, keyword, standard, en 등)이거나 매핑에 추가한 사용자 정의 분석기여야 하며,
+// 분석기를 지정할 수 없는 필드나 없는 분석기를 지정하면 오류를 반환한다. (메타데이터 키는 METADATA_FIELDS에 있어야 함)
+func applyFieldAnalyzers(indexMapping *mapping.IndexMappingImpl, docMapping *mapping.DocumentMapping, analyzers map[string]string) error {
+	fields := make([]string, 0, len(analyzers))
+	for field := range analyzers {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		analyzer := analyzers[field]
+		if indexMapping.AnalyzerNamed(analyzer) == nil {
+			return fmt.Errorf("unknown analyzer '%s' for field '%s'", analyzer, field)
+		}
+		property := docMapping
+		for _, name := range strings.Split(field, ".") {
+			property = property.Properties[name]
+			if property == nil {
+				return fmt.Errorf("unknown field '%s'", field)
+			}
+		}
+		if len(property.Fields) == 0 || property.Fields[0].Type != "text" {
+			return fmt.Errorf("field '%s' is not a text field", field)
+		}
+		// 여러 필드가 같은 필드 매핑을 공유하므로 복사해서 바꿈
+		fieldMapping := *property.Fields[0]
+		fieldMapping.Analyzer = analyzer
+		property.Fields[0] = &fieldMapping
+	}
+	return nil
+}
+
+// 인덱스 매핑에서 FIELD_ANALYZERS로 지정한 필드들의 분석기가 설정과 같은지 확인하는 함수
+func fieldAnalyzersMatch(m mapping.IndexMapping) bool {
+	fields := mappedFields(m)
+	for field, analyzer := range fieldAnalyzers {
+		if mapped, ok := fields[field]; !ok || mapped.Analyzer != analyzer {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	indexapi "github.com/blevesearch/bleve_index_api"
+)
+
+// 백업 파일 이름의 접두사와 확장자 (가운데에 만든 시각이 붙음), 묶기 전의 사본을 만드는 임시 디렉터리 이름의 접두사
+const (
+	backupFilePrefix    = "index-"
+	backupFileExtension = ".tar.gz"
+	backupSnapshotDir   = ".backup-"
+)
+
+// 백업 파일을 저장하는 위치 (BACKUP_PATH로 변경, 비어 있으면 인덱스 디렉터리 아래 .backups)
+var backupDir = ""
+
+// 백업 파일 하나의 정보
+type backupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	SizeBytes int64     `json:"size_bytes"`
+	// 백업한 시점의 문서 수 (백업을 만들 때만 채워짐)
+	Documents *uint64 `json:"documents,omitempty"`
+}
+
+// 백업 파일을 저장하는 디렉터리
+func backupDirectory() string {
+	if backupDir != "" {
+		return backupDir
+	}
+	return filepath.Join(indexDir, ".backups")
+}
+
+// 인덱스 백업 핸들러 (POST /admin/backup?stream=true)
+// 현재 인덱스의 특정 시점 사본을 만들어 tar.gz로 묶는다. 기본은 백업 디렉터리에 저장하고 파일 정보를 응답하며,
+// stream=true이면 저장하지 않고 응답 본문으로 보낸다 (문서 수는 X-Backup-Documents 헤더).
+func backupHandler(w http.ResponseWriter, r *http.Request) {
+	if indexInMemory {
+		http.Error(w, errInMemoryIndex.Error(), http.StatusNotImplemented)
+		return
+	}
+	if !indexReady(w) {
+		return
+	}
+	live, ok := index.(*swappableIndex)
+	if !ok {
+		http.Error(w, "Index does not support backups", http.StatusInternalServerError)
+		return
+	}
+	stream := r.URL.Query().Get("stream") == "true"
+
+	snapshot, err := os.MkdirTemp(indexDir, backupSnapshotDir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create backup directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(snapshot)
+
+	createdAt := time.Now().UTC()
+	documents, err := live.snapshot(snapshot)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := backupFilePrefix + createdAt.Format(indexDirTimeFormat) + backupFileExtension
+
+	if stream {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		w.Header().Set("X-Backup-Documents", strconv.FormatUint(documents, 10))
+		if err := writeTarGz(w, snapshot); err != nil {
+			// 이미 본문을 보내기 시작했으므로 상태 코드를 바꿀 수 없음
+			log.Printf("Failed to stream backup: %v", err)
+		}
+		return
+	}
+
+	info, err := saveBackup(snapshot, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	info.CreatedAt = createdAt
+	info.Documents = &documents
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(info)
+}
+
+// 백업 목록 핸들러 (GET /admin/backups, 최근 백업부터)
+func listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(backupDirectory())
+	if err != nil && !os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("Failed to list backups: %v", err), http.StatusInternalServerError)
+		return
+	}
+	backups := []backupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), backupFilePrefix) || !strings.HasSuffix(entry.Name(), backupFileExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupInfo{
+			Name:      entry.Name(),
+			Path:      filepath.Join(backupDirectory(), entry.Name()),
+			CreatedAt: info.ModTime().UTC(),
+			SizeBytes: info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"backups": backups})
+}
+
+// 현재 인덱스의 특정 시점 사본을 dest 디렉터리에 만드는 함수 (사본의 문서 수를 반환)
+// scorch 인덱스는 온라인 복사(CopyTo)로 스냅샷을 복사하므로 색인을 막지 않는다.
+// 온라인 복사를 지원하지 않는 인덱스는 색인을 잠시 막고(쓰기 잠금) 디렉터리를 그대로 복사한다.
+// 문서 수는 만든 사본을 열어 세므로 복원한 인덱스의 문서 수와 같다.
+func (s *swappableIndex) snapshot(dest string) (uint64, error) {
+	if err := s.copyCurrent(dest); err != nil {
+		return 0, err
+	}
+	copied, err := bleve.Open(dest)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to open index backup: %w", err)
+	}
+	defer copied.Close()
+	count, err := copied.DocCount()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to count documents in index backup: %w", err)
+	}
+	return count, nil
+}
+
+func (s *swappableIndex) copyCurrent(dest string) error {
+	// 복사하는 동안 교체되어 이전 인덱스가 닫히지 않도록 잠금을 잡고 복사
+	s.mu.RLock()
+	if s.current == nil {
+		// 인덱스를 다시 만드는 중
+		s.mu.RUnlock()
+		return errIndexBuilding
+	}
+	advanced, err := s.current.Advanced()
+	if err != nil {
+		s.mu.RUnlock()
+		return fmt.Errorf("Failed to access index: %w", err)
+	}
+	if _, ok := advanced.(indexapi.CopyIndex); ok {
+		defer s.mu.RUnlock()
+		copyable, ok := s.current.(bleve.IndexCopyable)
+		if !ok {
+			return fmt.Errorf("Index does not support backups")
+		}
+		if err := copyable.CopyTo(bleve.FileSystemDirectory(dest)); err != nil {
+			return fmt.Errorf("Failed to copy index: %w", err)
+		}
+		return nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return errIndexBuilding
+	}
+	if err := copyDirectory(s.path, dest); err != nil {
+		return fmt.Errorf("Failed to copy index: %w", err)
+	}
+	return nil
+}
+
+// 디렉터리 내용을 그대로 복사하는 함수
+func copyDirectory(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// 사본 디렉터리를 tar.gz로 묶어 백업 디렉터리에 저장하는 함수
+// 임시 파일에 쓴 뒤 이름을 바꾸므로 목록에는 완성된 백업만 나타난다.
+func saveBackup(snapshot, name string) (backupInfo, error) {
+	dir := backupDirectory()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return backupInfo{}, fmt.Errorf("Failed to create backup directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return backupInfo{}, fmt.Errorf("Failed to create backup file: %w", err)
+	}
+	defer os.Remove(path + ".tmp")
+	if err := writeTarGz(file, snapshot); err != nil {
+		file.Close()
+		return backupInfo{}, err
+	}
+	if err := file.Close(); err != nil {
+		return backupInfo{}, fmt.Errorf("Failed to write backup file: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return backupInfo{}, fmt.Errorf("Failed to write backup file: %w", err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return backupInfo{}, fmt.Errorf("Failed to read backup file: %w", err)
+	}
+	return backupInfo{Name: name, Path: path, SizeBytes: stat.Size()}, nil
+}
+
+// 디렉터리 내용을 tar.gz로 묶어 쓰는 함수 (경로는 디렉터리 기준 상대 경로)
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(archive, file)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to archive index backup: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("Failed to archive index backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("Failed to archive index backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// 문서 가중치를 색인하는 숫자 필드 이름 (가중치가 1이 아닌 문서만 색인됨)
+const boostField = "boost"
+
+// 문서에 지정할 수 있는 가중치의 최대값
+const maxDocumentBoost = 100.0
+
+// 점수순 검색에서 가중치를 반영해 다시 정렬하는 앞쪽 히트 수
+// bleve는 색인 시점의 문서 가중치를 지원하지 않으므로 이 범위의 히트 점수에 가중치를 곱해 다시 정렬하며,
+// 요청한 페이지가 이 범위를 넘으면 가중치 없이 원래 순서로 응답한다.
+const boostRescoreWindow = 200
+
+// 저장할 문서 가중치가 올바른지 확인하는 함수 (지정하지 않으면 1)
+func validateDocumentBoost(boost *float64) error {
+	if boost != nil && (*boost <= 0 || *boost > maxDocumentBoost) {
+		return fmt.Errorf("Invalid request body: boost must be greater than 0 and at most %g", maxDocumentBoost)
+	}
+	return nil
+}
+
+// 색인할 문서 가중치 (기본값 1이면 색인하지 않도록 nil)
+func indexedBoost(boost *float64) *float64 {
+	if boost == nil || *boost == 1 {
+		return nil
+	}
+	return boost
+}
+
+// 검색 요청이 기본 정렬(점수 내림차순)인지 확인하는 함수
+func sortedByScore(request *bleve.SearchRequest) bool {
+	if len(request.Sort) != 1 {
+		return len(request.Sort) == 0
+	}
+	scoreSort, ok := request.Sort[0].(*search.SortScore)
+	return ok && scoreSort.Desc
+}
+
+// 인덱스에 가중치가 1이 아닌 문서가 있는지 확인하는 함수 (가중치 필드가 없는 이전 인덱스면 false)
+func hasBoostedDocuments(ctx context.Context, target bleve.Index) (bool, error) {
+	if _, ok := mappedFields(target.Mapping())[boostField]; !ok {
+		return false, nil
+	}
+	lowest := 0.0
+	boosted := bleve.NewNumericRangeQuery(&lowest, nil)
+	boosted.SetField(boostField)
+	result, err := target.SearchInContext(ctx, bleve.NewSearchRequestOptions(boosted, 0, 0, false))
+	if err != nil {
+		return false, fmt.Errorf("Failed to look up boosted documents: %w", err)
+	}
+	return result.Total > 0, nil
+}
+
+// 히트 점수에 문서 가중치를 곱하고 다시 정렬하는 함수 (가중치가 없는 히트는 1)
+func applyDocumentBoosts(result *bleve.SearchResult) {
+	result.MaxScore = 0
+	for _, hit := range result.Hits {
+		if boost, ok := hit.Fields[boostField].(float64); ok {
+			hit.Score *= boost
+		}
+		result.MaxScore = max(result.MaxScore, hit.Score)
+	}
+	sort.SliceStable(result.Hits, func(i, j int) bool {
+		return result.Hits[i].Score > result.Hits[j].Score
+	})
+}
+
+// 가중치를 읽기 위해 검색 요청의 저장 필드에 가중치 필드를 추가하는 함수 (원래 요청에 없었으면 true)
+func requestBoostField(request *bleve.SearchRequest) bool {
+	if slices.Contains(request.Fields, "*") || slices.Contains(request.Fields, boostField) {
+		return false
+	}
+	request.Fields = append(request.Fields, boostField)
+	return true
+}
+
+// 요청하지 않은 가중치 필드를 히트에서 지우는 함수
+func dropBoostField(result *bleve.SearchResult) {
+	for _, hit := range result.Hits {
+		delete(hit.Fields, boostField)
+	}
+}
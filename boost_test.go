@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestValidateDocumentBoost(t *testing.T) {
+	for _, tt := range []struct {
+		boost   *float64
+		wantErr bool
+	}{
+		{nil, false},
+		{new(float64), true},
+		{ptrTo(-1.0), true},
+		{ptrTo(0.5), false},
+		{ptrTo(maxDocumentBoost), false},
+		{ptrTo(maxDocumentBoost + 1), true},
+	} {
+		if err := validateDocumentBoost(tt.boost); (err != nil) != tt.wantErr {
+			t.Errorf("validateDocumentBoost(%v) error = %v, wantErr %t", tt.boost, err, tt.wantErr)
+		}
+	}
+}
+
+// 검색 결과의 문서 ID를 순서대로 가장 높은 점수와 함께 반환하는 함수
+func boostedSearch(t *testing.T, q string) ([]string, float64) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {q}}.Encode(), nil))
+	var response struct {
+		Hits []struct {
+			ID string `json:"id"`
+		} `json:"hits"`
+		MaxScore float64 `json:"max_score"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("search = %d %s", rec.Code, rec.Body)
+	}
+	var ids []string
+	for _, hit := range response.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, response.MaxScore
+}
+
+func TestBoostedDocumentOutranksIdenticalDocument(t *testing.T) {
+	memIndex := useMemoryIndex(t)
+	indexTestDocuments(t, memIndex,
+		documentBody{Title: "서울 호텔", Content: "서울 시청 호텔"},
+		documentBody{Title: "서울 호텔", Content: "서울 시청 호텔"},
+	)
+	_, unboosted := boostedSearch(t, "호텔")
+
+	// 두 번째 문서만 가중치를 2로 다시 색인
+	doc := documentBody{Title: "서울 호텔", Content: "서울 시청 호텔", Boost: ptrTo(2.0)}
+	if err := memIndex.Index("2", doc.indexDocument(doc.Content, time.Now(), 2)); err != nil {
+		t.Fatal(err)
+	}
+	ids, maxScore := boostedSearch(t, "호텔")
+	if len(ids) != 2 || ids[0] != "2" {
+		t.Errorf("hits = %v, want the boosted document 2 first", ids)
+	}
+	if maxScore < 1.9*unboosted {
+		t.Errorf("max_score = %v, want about twice the unboosted %v", maxScore, unboosted)
+	}
+}
+
+func TestPatchDocumentBoost(t *testing.T) {
+	useTestDB(t)
+	useMemoryIndex(t)
+	first := insertTestDocument(t, "서울 시청 호텔 안내")
+	second := insertTestDocument(t, "서울 시청 호텔 소개")
+
+	if rec := serveDocument(http.MethodPatch, "/documents/"+strconv.Itoa(second), `{"boost": 3}`); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH boost = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if ids, _ := boostedSearch(t, "호텔"); len(ids) != 2 || ids[0] != strconv.Itoa(second) {
+		t.Errorf("hits after boosting document %d = %v, want it first", second, ids)
+	}
+	if rec := serveDocument(http.MethodPatch, "/documents/"+strconv.Itoa(first), `{"boost": 0}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH boost 0 = %d, want 400", rec.Code)
+	}
+}
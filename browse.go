@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 전체 문서 목록 핸들러 (GET /browse?sort=-created_at&from=0&size=20)
+// 색인된 내용을 점검하기 위한 용도로, 검색어 없이 모든 문서를 정렬/페이지 단위로 반환한다.
+func browseHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	m := index.Mapping()
+	from, err := parseIntParam(values, "from", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	size, err := parseIntParam(values, "size", defaultSearchSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	browseRequest := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), min(size, maxSearchSize), from, false)
+	var includeFields []string
+	browseRequest.Fields, includeFields = selectStoredFields(values, m)
+
+	// 정렬을 지정하지 않으면 문서 ID 순 (모든 점수가 같으므로 점수순은 의미가 없음)
+	sortOrder, err := parseSortParam(values.Get("sort"), values, m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(sortOrder) > 0 {
+		browseRequest.SortByCustom(sortOrder)
+	} else {
+		browseRequest.SortBy([]string{"_id"})
+	}
+
+	searchResult, err := searchWithTimeout(r.Context(), browseRequest, defaultSearchTimeout)
+	if err != nil {
+		writeSearchError(w, err, defaultSearchTimeout)
+		return
+	}
+	decodeDistanceSorts(searchResult, browseRequest.Sort)
+	markMissingFields(searchResult, includeFields)
+
+	response := searchResponse{
+		SearchResult: searchResult,
+		From:         browseRequest.From,
+		Size:         browseRequest.Size,
+		QueryType:    "match_all",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 일괄 추가의 최대 문서 수와 동시에 보낼 형태소 분석 요청 수 (배치를 쓰면 요청마다 analysisBatchSize개까지 분석)
+const (
+	maxBulkDocuments   = 1000
+	bulkAnalyzeWorkers = 4
+)
+
+// 일괄 추가의 문서별 결과 (실패한 문서는 error만 채워짐)
+type bulkItem struct {
+	ID *int `json:"id,omitempty"`
+	// external_id가 같은 기존 문서를 갱신했으면 updated, 내용이 같은 문서가 이미 있으면 duplicate (id는 기존 문서)
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// 일괄 추가 대상 문서와 분석 결과
+type bulkDocument struct {
+	body     *documentBody
+	analysis string
+	source   string
+}
+
+// 문서 일괄 추가 핸들러 (POST /documents/bulk)
+// 분석에 성공한 문서는 하나의 트랜잭션으로 저장한 뒤 bleve Batch로 한 번에 색인한다. 저장이나 색인이 실패하면 트랜잭션을 롤백해
+// DB와 인덱스가 어긋나지 않게 하며, 이때는 분석에 성공한 문서도 모두 실패로 보고한다.
+func bulkInsertHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	start := time.Now()
+	rejectDuplicates := r.URL.Query().Get("reject_duplicates") == "true"
+	var bodies []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&bodies); err != nil {
+		http.Error(w, "Invalid request body: expected an array of documents", http.StatusBadRequest)
+		return
+	}
+	if len(bodies) > maxBulkDocuments {
+		http.Error(w, fmt.Sprintf("Invalid request body: at most %d documents are allowed", maxBulkDocuments), http.StatusBadRequest)
+		return
+	}
+
+	items := make([]bulkItem, len(bodies))
+	reqs := make([]*documentBody, len(bodies))
+	for i, body := range bodies {
+		req, err := decodeDocumentBody(bytes.NewReader(body))
+		if err != nil {
+			items[i].Error = err.Error()
+			continue
+		}
+		reqs[i] = req
+	}
+	insertDocuments(r.Context(), reqs, items, rejectDuplicates)
+	if requestCancelled(r) {
+		return
+	}
+
+	inserted, duplicates := 0, 0
+	for _, item := range items {
+		switch {
+		case item.Result == "duplicate":
+			duplicates++
+		case item.ID != nil:
+			inserted++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":      items,
+		"inserted":   inserted,
+		"duplicates": duplicates,
+		"failed":     len(items) - inserted - duplicates,
+		"took_ms":    time.Since(start).Milliseconds(),
+	})
+}
+
+// 검증된 문서를 형태소 분석해 저장하고 색인하는 함수 (nil인 문서는 이미 실패한 것으로 건너뜀)
+// 형태소 분석은 제한된 수의 작업자가 병렬로 수행하고, 문서별 결과는 items의 같은 위치에 기록한다.
+// 내용이 같은 문서가 이미 있거나 같은 요청 안에 먼저 나오면 분석하지 않고 duplicate로 보고하며,
+// rejectDuplicates가 true이면 실패로 보고한다.
+func insertDocuments(ctx context.Context, reqs []*documentBody, items []bulkItem, rejectDuplicates bool) {
+	// 같은 요청 안에서 내용이 같은 문서는 처음 나온 문서의 결과를 따름 (external_id가 같으면 갱신이므로 제외)
+	// 지정한 ID가 같은 요청 안에서 겹치면 뒤의 문서는 실패로 보고
+	duplicateOf := make(map[int]int)
+	firsts := make(map[string]int)
+	ids := make(map[int]bool)
+	for i, req := range reqs {
+		if req == nil {
+			continue
+		}
+		if req.ID != nil {
+			if ids[*req.ID] {
+				items[i].Error = (&documentExistsError{id: *req.ID}).Error()
+				reqs[i] = nil
+				continue
+			}
+			ids[*req.ID] = true
+		}
+		hash := contentHash(req.Content)
+		first, ok := firsts[hash]
+		if !ok {
+			firsts[hash] = i
+			continue
+		}
+		if req.ExternalID == nil || reqs[first].ExternalID == nil || *req.ExternalID != *reqs[first].ExternalID {
+			duplicateOf[i] = first
+		}
+	}
+
+	docs := make([]*bulkDocument, len(reqs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(analysisConcurrency(bulkAnalyzeWorkers), len(reqs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if reqs[i].ID != nil {
+					if err := checkDocumentID(ctx, reqs[i]); err != nil {
+						items[i].Error = err.Error()
+						continue
+					}
+				}
+				duplicateID, err := findDuplicateDocument(ctx, db, nil, contentHash(reqs[i].Content), reqs[i].ExternalID, 0)
+				if err != nil {
+					items[i].Error = err.Error()
+					continue
+				}
+				if duplicateID != 0 {
+					items[i] = duplicateItem(duplicateID, rejectDuplicates)
+					continue
+				}
+
+				// OpenAI API를 사용하여 형태소 분석 수행
+				tokens, source, err := getMorphologicalAnalysis(ctx, reqs[i].Content)
+				if err != nil {
+					items[i].Error = fmt.Sprintf("Failed to analyze text: %v", err)
+					continue
+				}
+				docs[i] = &bulkDocument{body: reqs[i], analysis: analysisText(tokens), source: source}
+			}
+		}()
+	}
+	for i, req := range reqs {
+		if _, ok := duplicateOf[i]; req != nil && !ok {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := storeBulkDocuments(ctx, docs, items); err != nil {
+		for i := range items {
+			if _, ok := duplicateOf[i]; items[i].Error == "" && items[i].Result != "duplicate" && !ok {
+				items[i] = bulkItem{Error: err.Error()}
+			}
+		}
+	}
+
+	for i, first := range duplicateOf {
+		switch {
+		case items[first].ID != nil:
+			items[i] = duplicateItem(*items[first].ID, rejectDuplicates)
+		default:
+			items[i] = bulkItem{Error: items[first].Error}
+		}
+	}
+}
+
+// 내용이 같은 문서가 이미 있는 문서의 결과를 만드는 함수
+func duplicateItem(id int, reject bool) bulkItem {
+	if reject {
+		return bulkItem{Error: (&duplicateDocumentError{id: id}).Error()}
+	}
+	return bulkItem{ID: &id, Result: "duplicate"}
+}
+
+// 분석된 문서를 하나의 트랜잭션으로 저장하고 Batch로 색인하는 함수
+// 성공하면 items의 해당 위치에 부여된 ID를 기록한다. 색인하기 전에 ctx가 끝나면 롤백하고 ctx의 오류를 반환한다.
+func storeBulkDocuments(ctx context.Context, docs []*bulkDocument, items []bulkItem) error {
+	if !slices.ContainsFunc(docs, func(doc *bulkDocument) bool { return doc != nil }) {
+		return nil
+	}
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertDocumentSQL)
+	if err != nil {
+		return fmt.Errorf("Failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	batch := index.NewBatch()
+	indexed := make(map[string]interface{})
+	ids := make([]int, len(docs))
+	created := make([]bool, len(docs))
+	maxID := 0
+	for i, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		var createdAt time.Time
+		var version int
+		var row *sql.Row
+		if doc.body.ID != nil {
+			// 지정한 ID로 추가하는 문서는 갱신하지 않으므로 별도 쿼리 사용
+			query, args := doc.body.insertStatement(doc.analysis, doc.source)
+			row = tx.QueryRowContext(ctx, query, args...)
+			maxID = max(maxID, *doc.body.ID)
+		} else {
+			row = stmt.QueryRowContext(ctx, doc.body.upsertArgs(doc.analysis, doc.source)...)
+		}
+		if err := row.Scan(&ids[i], &createdAt, &version, &created[i]); err != nil {
+			if exists := asDocumentExistsError(doc.body, err); exists != nil {
+				return exists
+			}
+			return fmt.Errorf("Failed to insert data: %w", err)
+		}
+		indexDoc := doc.body.indexDocument(doc.analysis, createdAt, version)
+		if err := batch.Index(strconv.Itoa(ids[i]), indexDoc); err != nil {
+			return fmt.Errorf("Failed to index data: %w", err)
+		}
+		indexed[strconv.Itoa(ids[i])] = indexDoc
+	}
+	if maxID > 0 {
+		if err := advanceDocumentSequence(ctx, tx, maxID); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := applyBatch(batch, indexed, nil); err != nil {
+		return fmt.Errorf("Failed to index data: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Bulk insert of %d documents was indexed but the database commit failed: %v", batch.Size(), err)
+		return fmt.Errorf("Failed to commit insert: %w", err)
+	}
+	suggester.markDirty()
+
+	for i, doc := range docs {
+		if doc != nil {
+			items[i].ID = &ids[i]
+			items[i].Result = "created"
+			if !created[i] {
+				items[i].Result = "updated"
+			}
+		}
+	}
+	return nil
+}
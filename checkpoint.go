@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 만들고 있는 인덱스 디렉터리 이름을 기록하는 파일 (현재 인덱스 기록과 같은 위치)
+// 처음 인덱스를 만들다 종료되면 다음 시작 때 이 디렉터리를 지우지 않고 마지막 체크포인트부터 이어서 만든다.
+const buildingIndexFile = ".index.building"
+
+// 만들고 있는 인덱스의 내부 저장소에 기록하는 키
+// 동기화 기준 시각은 처음 만들기 시작할 때 구한 값을 이어서 만들 때도 사용하고,
+// 체크포인트는 그 ID 이하의 행을 모두 처리했음을, 완료 표시는 모든 행을 색인했음을 나타낸다.
+var (
+	buildWatermarkKey  = []byte("build_watermark")
+	buildCheckpointKey = []byte("build_checkpoint")
+	buildCompleteKey   = []byte("build_complete")
+)
+
+// 인덱스를 만드는 진행 상황 (이어서 만들면 이전 실행에서 기록한 값)
+type buildProgress struct {
+	watermark []byte
+	// 이 ID 이하의 행은 이미 처리했으므로 건너뜀 (처음부터 만들면 0)
+	checkpoint int
+	complete   bool
+}
+
+// 만들고 있는 인덱스 디렉터리 이름을 기록하는 함수 (메모리 인덱스 모드면 기록하지 않음)
+func writeBuildingIndex(name string) error {
+	if indexInMemory {
+		return nil
+	}
+	path := filepath.Join(indexDir, buildingIndexFile)
+	if err := os.WriteFile(path+".tmp", []byte(name+"\n"), 0o644); err != nil {
+		return fmt.Errorf("Failed to record index build: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("Failed to record index build: %w", err)
+	}
+	return nil
+}
+
+// 만들고 있는 인덱스 디렉터리 이름을 읽는 함수 (기록이 없으면 빈 문자열)
+func readBuildingIndex() (string, error) {
+	name, err := os.ReadFile(filepath.Join(indexDir, buildingIndexFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to read index build record: %w", err)
+	}
+	return strings.TrimSpace(string(name)), nil
+}
+
+// 인덱스를 다 만들었거나 만들던 디렉터리를 지운 뒤 기록을 지우는 함수
+func removeBuildingIndex() {
+	if indexInMemory {
+		return
+	}
+	if err := os.Remove(filepath.Join(indexDir, buildingIndexFile)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove index build record: %v", err)
+	}
+}
+
+// 처음 인덱스를 만들다 중단된 디렉터리를 여는 함수 (이어서 만들 인덱스가 없으면 nil)
+// 열 수 없거나 체크포인트를 기록하기 전에 중단된 디렉터리는 지우고 처음부터 만든다.
+func openInterruptedBuild() (bleve.Index, string) {
+	if indexInMemory {
+		return nil, ""
+	}
+	name, err := readBuildingIndex()
+	if err != nil {
+		log.Printf("Starting index build from scratch: %v", err)
+		return nil, ""
+	}
+	if name == "" {
+		return nil, ""
+	}
+	path := filepath.Join(indexDir, name)
+	opened, err := openIndex(path)
+	if err == nil {
+		var watermark []byte
+		if watermark, err = opened.GetInternal(buildWatermarkKey); err == nil && watermark == nil {
+			err = errors.New("no checkpoint was recorded")
+		}
+		if err != nil {
+			opened.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("Starting index build from scratch, cannot resume %s: %v", path, err)
+		os.RemoveAll(path)
+		removeBuildingIndex()
+		return nil, ""
+	}
+	return opened, path
+}
+
+// 인덱스를 만들기 시작할 때 진행 상황을 읽는 함수
+// 이어서 만드는 인덱스면 기록된 기준 시각과 체크포인트를 반환하고, 새 인덱스면 기준 시각을 구해 인덱스에 기록한다.
+func startBuild(target bleve.Index) (buildProgress, error) {
+	var progress buildProgress
+	watermark, err := target.GetInternal(buildWatermarkKey)
+	if err != nil {
+		return progress, fmt.Errorf("Failed to read index build checkpoint: %w", err)
+	}
+	if watermark == nil {
+		if progress.watermark, err = nextSyncWatermark(); err != nil {
+			return progress, err
+		}
+		if err := target.SetInternal(buildWatermarkKey, progress.watermark); err != nil {
+			return progress, fmt.Errorf("Failed to record index build checkpoint: %w", err)
+		}
+		return progress, nil
+	}
+
+	progress.watermark = watermark
+	complete, err := target.GetInternal(buildCompleteKey)
+	if err != nil {
+		return progress, fmt.Errorf("Failed to read index build checkpoint: %w", err)
+	}
+	progress.complete = complete != nil
+	checkpoint, err := target.GetInternal(buildCheckpointKey)
+	if err != nil {
+		return progress, fmt.Errorf("Failed to read index build checkpoint: %w", err)
+	}
+	if checkpoint != nil {
+		if progress.checkpoint, err = strconv.Atoi(string(checkpoint)); err != nil {
+			return progress, fmt.Errorf("Invalid index build checkpoint: %w", err)
+		}
+	}
+	return progress, nil
+}
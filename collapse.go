@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// 그룹별 내부 히트 수의 최대값
+const maxInnerHits = 10
+
+// 필드 값 기준 그룹 묶기 옵션
+type collapseOptions struct {
+	field     string
+	innerHits int
+}
+
+// 필드 값이 같은 히트의 그룹 (hit는 그룹에서 점수가 가장 높은 히트)
+type hitGroup struct {
+	Value     interface{}                    `json:"value"`
+	Hit       *search.DocumentMatch          `json:"hit"`
+	InnerHits search.DocumentMatchCollection `json:"inner_hits,omitempty"`
+	Total     int                            `json:"total"`
+}
+
+// collapse, inner_hits 파라미터를 파싱하는 함수 (collapse가 없으면 nil 반환)
+func parseCollapseOptions(values url.Values, searchRequest *bleve.SearchRequest, m mapping.IndexMapping) (*collapseOptions, error) {
+	field := values.Get("collapse")
+	if field == "" {
+		if values.Has("inner_hits") {
+			return nil, fmt.Errorf("Invalid query parameter 'inner_hits': requires 'collapse'")
+		}
+		return nil, nil
+	}
+	if values.Get("dedupe_field") != "" {
+		return nil, fmt.Errorf("Invalid query parameter 'collapse': cannot be combined with dedupe_field")
+	}
+	fieldMapping, ok := mappedFields(m)[field]
+	if !ok || !fieldMapping.Store {
+		return nil, fmt.Errorf("Invalid query parameter 'collapse': field '%s' is not a stored field", field)
+	}
+	innerHits, err := parseIntParam(values, "inner_hits", 0)
+	if err != nil {
+		return nil, err
+	}
+	if innerHits > maxInnerHits {
+		return nil, fmt.Errorf("Invalid query parameter 'inner_hits': must be at most %d", maxInnerHits)
+	}
+
+	return newCollapseOptions(searchRequest, field, innerHits), nil
+}
+
+// POST 본문의 collapse, inner_hits를 검증하는 함수 (collapse가 없으면 nil 반환)
+func collapseFromBody(req searchBody, searchRequest *bleve.SearchRequest, m mapping.IndexMapping) (*collapseOptions, error) {
+	if req.Collapse == "" {
+		if req.InnerHits != 0 {
+			return nil, &clauseError{Path: "inner_hits", Message: "requires 'collapse'"}
+		}
+		return nil, nil
+	}
+	if req.DedupeField != "" {
+		return nil, &clauseError{Path: "collapse", Message: "cannot be combined with dedupe_field"}
+	}
+	fieldMapping, ok := mappedFields(m)[req.Collapse]
+	if !ok || !fieldMapping.Store {
+		return nil, &clauseError{Path: "collapse", Message: fmt.Sprintf("field '%s' is not a stored field", req.Collapse)}
+	}
+	if req.InnerHits < 0 || req.InnerHits > maxInnerHits {
+		return nil, &clauseError{Path: "inner_hits", Message: fmt.Sprintf("must be between 0 and %d", maxInnerHits)}
+	}
+	return newCollapseOptions(searchRequest, req.Collapse, req.InnerHits), nil
+}
+
+// 그룹 기준 필드를 히트에 포함시키고 옵션을 만드는 함수
+func newCollapseOptions(searchRequest *bleve.SearchRequest, field string, innerHits int) *collapseOptions {
+	if !slices.Contains(searchRequest.Fields, "*") && !slices.Contains(searchRequest.Fields, field) {
+		searchRequest.Fields = append(searchRequest.Fields, field)
+	}
+	return &collapseOptions{field: field, innerHits: innerHits}
+}
+
+// 히트를 필드 값으로 묶어 그룹 목록을 만드는 함수
+// 그룹은 가장 높은 점수의 히트 순으로 정렬하며, 필드 값이 없는 히트는 각각 하나의 그룹이 된다.
+// 그룹 안의 내부 히트는 대표 히트를 제외하고 점수 순으로 최대 innerHits개까지 담는다.
+func collapseHits(hits search.DocumentMatchCollection, opts *collapseOptions) []*hitGroup {
+	var groups []*hitGroup
+	members := make(map[*hitGroup]search.DocumentMatchCollection)
+	positions := make(map[string]*hitGroup)
+	for _, hit := range hits {
+		value, ok := hit.Fields[opts.field]
+		var group *hitGroup
+		if ok && value != nil {
+			group = positions[fmt.Sprint(value)]
+		}
+		if group == nil {
+			group = &hitGroup{Value: value}
+			groups = append(groups, group)
+			if ok && value != nil {
+				positions[fmt.Sprint(value)] = group
+			}
+		}
+		members[group] = append(members[group], hit)
+	}
+
+	for _, group := range groups {
+		groupHits := members[group]
+		sort.SliceStable(groupHits, func(i, j int) bool { return groupHits[i].Score > groupHits[j].Score })
+		group.Hit = groupHits[0]
+		group.Total = len(groupHits)
+		if opts.innerHits > 0 {
+			group.InnerHits = groupHits[1:min(len(groupHits), opts.innerHits+1)]
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Hit.Score > groups[j].Hit.Score })
+	return groups
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/index/scorch"
+	"github.com/blevesearch/bleve/v2/index/scorch/mergeplan"
+)
+
+// 인덱스 디스크 사용량과 세그먼트 상태
+type segmentUsage struct {
+	SizeBytes      int64  `json:"size_bytes"`
+	Segments       int    `json:"segments"`
+	PendingDeletes uint64 `json:"pending_deletes"`
+}
+
+// 압축(강제 병합) 작업 상태 (상태 값은 재색인 작업과 같음)
+type compactionJob struct {
+	Status     string        `json:"status"`
+	IndexPath  string        `json:"index_path"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Before     segmentUsage  `json:"before"`
+	After      *segmentUsage `json:"after,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// 실행 중이거나 마지막으로 실행한 압축 작업 (서버를 다시 시작하면 사라짐)
+type compactionRegistry struct {
+	mu     sync.Mutex
+	latest *compactionJob
+}
+
+var compactions = &compactionRegistry{}
+
+// 새 압축 작업을 등록하는 함수 (이미 실행 중인 작업이 있으면 그 작업과 false를 반환)
+func (r *compactionRegistry) start(path string, before segmentUsage) (compactionJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latest != nil && r.latest.Status == reindexRunning {
+		return *r.latest, false
+	}
+	r.latest = &compactionJob{Status: reindexRunning, IndexPath: path, StartedAt: time.Now(), Before: before}
+	return *r.latest, true
+}
+
+// 실행 중인 압축 작업의 결과를 기록하는 함수
+func (r *compactionRegistry) finish(after *segmentUsage, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.latest.FinishedAt = &now
+	r.latest.Status = reindexSucceeded
+	r.latest.After = after
+	if err != nil {
+		r.latest.Status = reindexFailed
+		r.latest.Error = err.Error()
+	}
+}
+
+// 실행 중이거나 마지막으로 실행한 압축 작업의 상태를 복사해 반환하는 함수
+func (r *compactionRegistry) current() (compactionJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latest == nil {
+		return compactionJob{}, false
+	}
+	return *r.latest, true
+}
+
+// 인덱스 압축 핸들러 (POST /admin/compact)
+// scorch 인덱스의 세그먼트를 하나로 강제 병합해 삭제된 문서가 차지하던 공간을 회수한다.
+// 작업은 백그라운드에서 실행하고 시작 전 상태를 바로 202로 응답하며, 진행 상황과 결과는 GET /admin/stats의 last_compaction에 나타난다.
+// 병합은 색인과 검색을 막지 않는다. upsidedown 인덱스는 키-값 저장소가 공간을 관리하므로 지원하지 않는다.
+func compactHandler(w http.ResponseWriter, r *http.Request) {
+	if indexInMemory {
+		http.Error(w, errInMemoryIndex.Error(), http.StatusNotImplemented)
+		return
+	}
+	if !indexReady(w) {
+		return
+	}
+	live, ok := index.(*swappableIndex)
+	if !ok {
+		http.Error(w, "Index does not support compaction", http.StatusInternalServerError)
+		return
+	}
+
+	// 병합하는 동안 인덱스를 교체할 수 있도록 잠금을 잡지 않고 현재 인덱스만 가져옴 (교체되어 닫히면 작업이 실패로 끝남)
+	live.mu.RLock()
+	target, path := live.current, live.path
+	live.mu.RUnlock()
+	if target == nil {
+		// 인덱스를 다시 만드는 중
+		writeIndexBuilding(w)
+		return
+	}
+
+	engine, err := scorchIndex(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if engine == nil {
+		http.Error(w, "Compaction requires the scorch backend (INDEX_TYPE=scorch), upsidedown indexes reclaim space in their key-value store", http.StatusNotImplemented)
+		return
+	}
+	before, err := measureSegments(target, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	job, started := compactions.start(path, *before)
+	if !started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+	go func() {
+		err := engine.ForceMerge(context.Background(), &mergeplan.SingleSegmentMergePlanOptions)
+		var after *segmentUsage
+		if err == nil {
+			after, err = measureSegments(target, path)
+		}
+		if err != nil {
+			log.Printf("Compaction of %s failed: %v", path, err)
+		} else {
+			log.Printf("Compacted %s from %d segments (%d bytes) to %d segments (%d bytes)", path, before.Segments, before.SizeBytes, after.Segments, after.SizeBytes)
+		}
+		compactions.finish(after, err)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// 인덱스의 scorch 백엔드를 반환하는 함수 (scorch 인덱스가 아니면 nil)
+func scorchIndex(target bleve.Index) (*scorch.Scorch, error) {
+	advanced, err := target.Advanced()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to access index: %w", err)
+	}
+	engine, _ := advanced.(*scorch.Scorch)
+	return engine, nil
+}
+
+// 인덱스 디렉터리 크기와 세그먼트 수, 삭제 표시만 된 문서 수를 구하는 함수
+func measureSegments(target bleve.Index, path string) (*segmentUsage, error) {
+	usage, err := segmentCounts(target)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = &segmentUsage{}
+	}
+	if usage.SizeBytes, err = directorySize(path); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// 인덱스의 세그먼트 수와 삭제 표시만 된 문서 수를 구하는 함수 (scorch 인덱스가 아니면 nil)
+func segmentCounts(target bleve.Index) (*segmentUsage, error) {
+	advanced, err := target.Advanced()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to access index: %w", err)
+	}
+	reader, err := advanced.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open index reader: %w", err)
+	}
+	defer reader.Close()
+
+	snapshot, ok := reader.(*scorch.IndexSnapshot)
+	if !ok {
+		return nil, nil
+	}
+	usage := &segmentUsage{Segments: len(snapshot.Segments())}
+	for _, segment := range snapshot.Segments() {
+		if deleted := segment.Deleted(); deleted != nil {
+			usage.PendingDeletes += deleted.GetCardinality()
+		}
+	}
+	return usage, nil
+}
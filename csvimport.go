@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/transform"
+)
+
+// 업로드 파일을 메모리에 둘 최대 크기 (넘으면 임시 파일 사용)와 응답에 담을 최대 오류 수
+const (
+	maxCSVMemory    = 32 << 20
+	maxImportErrors = 100
+)
+
+// CSV 열과 문서 필드의 대응 (열 번호, 없으면 -1)
+type csvColumns struct {
+	title, content, tags, price int
+}
+
+// CSV 가져오기 핸들러 (POST /import/csv, multipart의 file 필드)
+// content_column은 필수이며 title_column, tags_column(쉼표로 구분), price_column으로 다른 필드를 지정한다.
+// 첫 줄은 열 이름으로 사용하고, encoding=euc-kr이면 EUC-KR(CP949) 파일을 UTF-8로 변환해 읽는다.
+// 잘못된 행은 건너뛰고 행 번호와 함께 요약에 담으며, 내용이 같은 문서가 이미 있는 행은 duplicates로 센다.
+func importCSVHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	values := r.URL.Query()
+	batchSize, err := parseIntParam(values, "batch_size", defaultImportBatchSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if batchSize == 0 || batchSize > maxImportBatchSize {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'batch_size': must be between 1 and %d", maxImportBatchSize), http.StatusBadRequest)
+		return
+	}
+	if values.Get("content_column") == "" {
+		http.Error(w, "Missing query parameter 'content_column'", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxCSVMemory); err != nil {
+		http.Error(w, "Invalid request body: expected a multipart form", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid request body: missing 'file' field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var source io.Reader = file
+	switch strings.ToLower(values.Get("encoding")) {
+	case "", "utf-8", "utf8":
+	case "euc-kr", "cp949":
+		source = transform.NewReader(file, korean.EUCKR.NewDecoder())
+	default:
+		http.Error(w, "Invalid query parameter 'encoding': must be 'utf-8' or 'euc-kr'", http.StatusBadRequest)
+		return
+	}
+
+	// 엑셀 등이 붙이는 UTF-8 BOM은 첫 열 이름에 섞이지 않도록 제거
+	buffered := bufio.NewReader(source)
+	if bom, err := buffered.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		buffered.Discard(3)
+	}
+	reader := csv.NewReader(buffered)
+	header, err := reader.Read()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid CSV header: %v", err), http.StatusBadRequest)
+		return
+	}
+	columns, err := mapCSVColumns(header, values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	imported, duplicates, rejected := 0, 0, 0
+	lineErrors := []importLineError{}
+	rejectRow := func(line int, message string) {
+		rejected++
+		if len(lineErrors) < maxImportErrors {
+			lineErrors = append(lineErrors, importLineError{Line: line, Error: message})
+		}
+	}
+
+	var lines []int
+	var reqs []*documentBody
+	flush := func() {
+		if len(reqs) == 0 {
+			return
+		}
+		items := make([]bulkItem, len(reqs))
+		insertDocuments(r.Context(), reqs, items, false)
+		for i, item := range items {
+			switch {
+			case item.Result == "duplicate":
+				duplicates++
+			case item.ID != nil:
+				imported++
+			default:
+				rejectRow(lines[i], item.Error)
+			}
+		}
+		lines, reqs = lines[:0], reqs[:0]
+	}
+
+	for {
+		if requestCancelled(r) {
+			return
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rejectRow(parseErr.StartLine, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			if requestCancelled(r) {
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to read CSV: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		req, err := columns.document(record)
+		if err != nil {
+			rejectRow(line, err.Error())
+			continue
+		}
+		lines = append(lines, line)
+		reqs = append(reqs, req)
+		if len(reqs) >= batchSize {
+			flush()
+		}
+	}
+	flush()
+	if requestCancelled(r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported":   imported,
+		"duplicates": duplicates,
+		"rejected":   rejected,
+		"errors":     lineErrors,
+		"took_ms":    time.Since(start).Milliseconds(),
+	})
+}
+
+// 열 이름 파라미터를 헤더의 열 번호로 바꾸는 함수
+func mapCSVColumns(header []string, values url.Values) (*csvColumns, error) {
+	find := func(param string) (int, error) {
+		name := values.Get(param)
+		if name == "" {
+			return -1, nil
+		}
+		for i, column := range header {
+			if strings.TrimSpace(column) == name {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("Invalid query parameter '%s': column '%s' not found in CSV header", param, name)
+	}
+
+	var columns csvColumns
+	var err error
+	if columns.content, err = find("content_column"); err != nil {
+		return nil, err
+	}
+	if columns.title, err = find("title_column"); err != nil {
+		return nil, err
+	}
+	if columns.tags, err = find("tags_column"); err != nil {
+		return nil, err
+	}
+	if columns.price, err = find("price_column"); err != nil {
+		return nil, err
+	}
+	return &columns, nil
+}
+
+// CSV 행 하나를 문서로 변환하는 함수
+func (c *csvColumns) document(record []string) (*documentBody, error) {
+	req := &documentBody{Content: strings.TrimSpace(record[c.content])}
+	if req.Content == "" {
+		return nil, errors.New("content is empty")
+	}
+	if c.title >= 0 {
+		req.Title = strings.TrimSpace(record[c.title])
+	}
+	if c.tags >= 0 {
+		req.Tags = splitParamList(record[c.tags])
+	}
+	if c.price >= 0 && strings.TrimSpace(record[c.price]) != "" {
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[c.price]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price '%s'", record[c.price])
+		}
+		req.Price = &price
+	}
+	return req, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 검색 조건으로 문서 삭제 핸들러 (POST /documents/delete_by_query?dry_run=true&hard=true&batch_size=500)
+// 본문은 POST /search와 같은 형식이며, 일치하는 문서를 스크롤 커서로 batch_size개씩 읽어
+// 배치마다 Postgres와 인덱스에서 함께 지운다. 배치 단위로 커밋하므로 중간에 중단되어도
+// 이미 지운 문서는 더 이상 검색되지 않고, 같은 요청을 다시 보내면 남은 문서만 지운다.
+// 기본은 DELETE /documents/{id}와 같이 삭제 표시만 하며 hard=true이면 행을 완전히 지운다.
+func deleteByQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	values := r.URL.Query()
+	batchSize, err := parseIntParam(values, "batch_size", defaultScrollBatchSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if batchSize == 0 || batchSize > maxScrollBatchSize {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'batch_size': must be between 1 and %d", maxScrollBatchSize), http.StatusBadRequest)
+		return
+	}
+	dryRun := values.Get("dry_run") == "true"
+	statement := "UPDATE documents SET deleted_at = now() WHERE id = ANY($1) AND deleted_at IS NULL"
+	if values.Get("hard") == "true" {
+		statement = "DELETE FROM documents WHERE id = ANY($1)"
+	}
+
+	spec, err := newSearchSpecFromBody(r.Body, index.Mapping())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 삭제에는 문서 ID만 필요
+	spec.request.Fields = nil
+	spec.request.Highlight = nil
+	cursor := newScrollCursor(spec, batchSize)
+
+	start := time.Now()
+	matched, deleted, batches := 0, 0, 0
+	for {
+		// 삭제한 문서는 마지막 ID보다 앞에 있으므로 search-after로 이어 읽어도 건너뛰는 문서가 없음
+		hits, _, done, err := cursor.next(r.Context())
+		if err != nil {
+			writeDeleteByQueryError(w, err, spec.timeout, deleted)
+			return
+		}
+		if len(hits) > 0 {
+			ids := make([]string, len(hits))
+			for i, hit := range hits {
+				ids[i] = hit.ID
+			}
+			if !dryRun {
+				if err := deleteDocumentBatch(r.Context(), ids, statement); err != nil {
+					writeDeleteByQueryError(w, err, spec.timeout, deleted)
+					return
+				}
+				deleted += len(ids)
+				batches++
+			}
+			matched += len(ids)
+		}
+		if done {
+			break
+		}
+	}
+
+	response := map[string]interface{}{"took_ms": time.Since(start).Milliseconds()}
+	if dryRun {
+		response["matched"] = matched
+		response["dry_run"] = true
+	} else {
+		response["deleted"] = deleted
+		response["batches"] = batches
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// 문서 ID 배치를 한 트랜잭션에서 DB에서 지우고 bleve Batch로 인덱스에서 지우는 함수
+// 인덱스 삭제가 실패하면 DB 삭제를 롤백한다.
+func deleteDocumentBatch(ctx context.Context, ids []string, statement string) error {
+	rowIDs := make([]int64, 0, len(ids))
+	batch := index.NewBatch()
+	for _, id := range ids {
+		if rowID, err := strconv.ParseInt(id, 10, 64); err == nil {
+			rowIDs = append(rowIDs, rowID)
+		}
+		batch.Delete(id)
+	}
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement, pq.Array(rowIDs)); err != nil {
+		return fmt.Errorf("Failed to delete documents: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := applyBatch(batch, nil, ids); err != nil {
+		return fmt.Errorf("Failed to delete documents from index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("%d documents were removed from the index but the database delete failed: %v", len(ids), err)
+		return fmt.Errorf("Failed to commit delete: %w", err)
+	}
+	suggester.markDirty()
+	return nil
+}
+
+// 삭제 도중 실패를 응답하는 함수 (이미 지운 문서 수를 함께 알려 다시 시도할 수 있게 함)
+func writeDeleteByQueryError(w http.ResponseWriter, err error, timeout time.Duration, deleted int) {
+	if errors.Is(err, errSearchTimeout) && deleted == 0 {
+		writeSearchError(w, err, timeout)
+		return
+	}
+	http.Error(w, fmt.Sprintf("%v (%d documents were deleted before the failure, retry to delete the rest)", err, deleted), http.StatusInternalServerError)
+}
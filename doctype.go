@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// 문서 종류를 색인하는 키워드 필드 이름 (bleve가 문서 매핑을 고르는 TypeField이기도 함)
+const documentTypeField = "type"
+
+// 문서 종류 (type 필드 값), 종류를 지정하지 않았거나 아래에 없는 종류면 defaultDocumentType 매핑으로 색인
+const (
+	defaultDocumentType = "document"
+	documentTypeArticle = "article"
+	documentTypeProduct = "product"
+	documentTypeFAQ     = "faq"
+)
+
+// 전용 문서 매핑이 있는 문서 종류
+var documentTypes = []string{documentTypeArticle, documentTypeProduct, documentTypeFAQ}
+
+// 문서 종류 이름의 최대 길이
+const maxDocumentTypeLength = 64
+
+// 상품명 전체를 토큰 하나로 색인하는 필드 이름 (정확히 일치하는 상품명 검색용, 상품 매핑에만 있음)
+const productTitleExactField = "title_exact"
+
+// bleve가 문서 매핑을 선택할 때 사용하는 타입 이름 (전용 매핑이 없는 종류는 기본 매핑 사용)
+func (doc indexDocument) Type() string {
+	if slices.Contains(documentTypes, doc.DocType) {
+		return doc.DocType
+	}
+	return defaultDocumentType
+}
+
+// 문서 종류별 매핑을 인덱스 매핑에 추가하는 함수
+// 모든 종류가 기본 문서 필드를 공유하고, 상품은 정확히 일치하는 상품명 검색용 필드를 더하며,
+// FAQ는 가격과 위치를 색인하지 않는다. 전용 매핑이 없는 종류는 기본 매핑(DefaultMapping)으로 색인한다.
+func addDocumentTypeMappings(indexMapping *mapping.IndexMappingImpl, textAnalyzer string) error {
+	indexMapping.TypeField = documentTypeField
+	indexMapping.DefaultType = defaultDocumentType
+
+	indexMapping.DefaultMapping = newDocumentMapping(textAnalyzer)
+	mappings := map[string]*mapping.DocumentMapping{defaultDocumentType: newDocumentMapping(textAnalyzer)}
+	for _, docType := range documentTypes {
+		mappings[docType] = newDocumentMapping(textAnalyzer)
+	}
+
+	titleExactFieldMapping := newExactFieldMapping()
+	titleExactFieldMapping.Name = productTitleExactField
+	mappings[documentTypeProduct].AddFieldMappingsAt("title", titleExactFieldMapping)
+
+	faqMapping := mappings[documentTypeFAQ]
+	delete(faqMapping.Properties, "price")
+	delete(faqMapping.Properties, locationField)
+	// 동적 매핑이 지운 필드를 다시 색인하지 않도록 함
+	faqMapping.Dynamic = false
+
+	// FIELD_ANALYZERS로 지정한 필드는 모든 종류에서 기본 분석기 대신 지정한 분석기 사용
+	for _, docMapping := range append([]*mapping.DocumentMapping{indexMapping.DefaultMapping}, mappingsInOrder(mappings)...) {
+		if err := applyFieldAnalyzers(indexMapping, docMapping, fieldAnalyzers); err != nil {
+			return fmt.Errorf("Invalid FIELD_ANALYZERS: %w", err)
+		}
+	}
+	for docType, docMapping := range mappings {
+		indexMapping.AddDocumentMapping(docType, docMapping)
+	}
+	return nil
+}
+
+// 종류 이름 순서로 문서 매핑을 나열하는 함수 (오류 메시지가 항상 같은 매핑에서 나오도록)
+func mappingsInOrder(mappings map[string]*mapping.DocumentMapping) []*mapping.DocumentMapping {
+	ordered := make([]*mapping.DocumentMapping, 0, len(mappings))
+	for _, docType := range sortedKeys(mappings) {
+		ordered = append(ordered, mappings[docType])
+	}
+	return ordered
+}
+
+// 인덱스 매핑에 문서 종류별 매핑이 모두 있는지 확인하는 함수 (종류별 매핑 이전에 만든 인덱스면 false)
+func hasDocumentTypeMappings(m mapping.IndexMapping) bool {
+	impl, ok := m.(*mapping.IndexMappingImpl)
+	if !ok || impl.TypeField != documentTypeField {
+		return false
+	}
+	for _, docType := range documentTypes {
+		if _, ok := impl.TypeMapping[docType]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// 문서 종류 제한 필터 (종류 중 하나와 일치하는 문서만, 목록이 비어 있으면 아무 문서도 일치하지 않음)
+// 종류를 지정하지 않고 저장한 문서는 기본 종류(document)로 색인되어 있다.
+func documentTypeFilter(types []string) query.Query {
+	if len(types) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	disjuncts := make([]query.Query, 0, len(types))
+	for _, docType := range types {
+		termQuery := bleve.NewTermQuery(docType)
+		termQuery.SetField(documentTypeField)
+		disjuncts = append(disjuncts, termQuery)
+	}
+	return bleve.NewDisjunctionQuery(disjuncts...)
+}
@@ -0,0 +1,1065 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	indexapi "github.com/blevesearch/bleve_index_api"
+	"github.com/lib/pq"
+)
+
+// 유사 문서 검색 기본값과 최대값
+const (
+	defaultSimilarSize   = 5
+	defaultMaxQueryTerms = 25
+	maxMaxQueryTerms     = 100
+	defaultMinTermFreq   = 1
+)
+
+// 유사 문서 검색에 사용할 대표 텀 (TF-IDF 점수 포함)
+type weightedTerm struct {
+	term  string
+	score float64
+}
+
+// 유사 문서 핸들러 (GET /documents/{id}/similar)
+// 원본 문서 본문에서 TF-IDF가 높은 텀을 골라 OR 검색하고 원본 문서는 결과에서 제외한다.
+func similarDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	values := r.URL.Query()
+	size, err := parseIntParam(values, "size", defaultSimilarSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxQueryTerms, err := parseIntParam(values, "max_query_terms", defaultMaxQueryTerms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if maxQueryTerms == 0 {
+		http.Error(w, "Invalid query parameter 'max_query_terms': must be at least 1", http.StatusBadRequest)
+		return
+	}
+	minTermFreq, err := parseIntParam(values, "min_term_freq", defaultMinTermFreq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	content, found, err := indexedContent(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("Document not found: %s", id), http.StatusNotFound)
+		return
+	}
+
+	terms, err := topTerms(index, defaultSearchField, content, min(maxQueryTerms, maxMaxQueryTerms), minTermFreq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 대표 텀이 없으면 빈 결과를 반환하기 위해 아무것도 일치하지 않는 쿼리 사용
+	var similarQuery query.Query = bleve.NewMatchNoneQuery()
+	if len(terms) > 0 {
+		termQueries := make([]query.Query, len(terms))
+		for i, term := range terms {
+			termQuery := bleve.NewTermQuery(term.term)
+			termQuery.SetField(defaultSearchField)
+			termQuery.SetBoost(term.score)
+			termQueries[i] = termQuery
+		}
+		booleanQuery := bleve.NewBooleanQuery()
+		booleanQuery.AddShould(termQueries...)
+		booleanQuery.AddMustNot(bleve.NewDocIDQuery([]string{id}))
+		similarQuery = booleanQuery
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(similarQuery, min(size, maxSearchSize), 0, false)
+	searchRequest.Fields = []string{"*"}
+	searchResult, err := searchWithTimeout(r.Context(), searchRequest, defaultSearchTimeout)
+	if err != nil {
+		writeSearchError(w, err, defaultSearchTimeout)
+		return
+	}
+
+	response := searchResponse{
+		SearchResult: searchResult,
+		Size:         searchRequest.Size,
+		QueryType:    "more_like_this",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// 본문을 필드 분석기로 토큰화하고 TF-IDF 점수가 높은 순으로 텀을 고르는 함수
+// 문서 빈도는 인덱스 리더에서 텀별로 조회하며, 문서 안에서 minTermFreq번 미만 나온 텀은 제외한다.
+func topTerms(idx bleve.Index, field, text string, limit, minTermFreq int) ([]weightedTerm, error) {
+	m := idx.Mapping()
+	analyzer := m.AnalyzerNamed(m.AnalyzerNameForPath(field))
+	if analyzer == nil {
+		return nil, fmt.Errorf("Failed to find analyzer for field '%s'", field)
+	}
+
+	termFreqs := make(map[string]int)
+	for _, token := range analyzer.Analyze([]byte(text)) {
+		termFreqs[string(token.Term)]++
+	}
+
+	advanced, err := idx.Advanced()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to access index: %w", err)
+	}
+	reader, err := advanced.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open index reader: %w", err)
+	}
+	defer reader.Close()
+
+	docCount, err := reader.DocCount()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to count documents: %w", err)
+	}
+
+	var terms []weightedTerm
+	for term, freq := range termFreqs {
+		if freq < minTermFreq {
+			continue
+		}
+		termReader, err := reader.TermFieldReader(context.Background(), []byte(term), field, false, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read term '%s': %w", term, err)
+		}
+		docFreq := termReader.Count()
+		termReader.Close()
+		if docFreq <= 1 {
+			// 원본 문서에만 있는 텀은 다른 문서와 일치할 수 없음
+			continue
+		}
+
+		idf := 1 + math.Log(float64(docCount)/float64(docFreq+1))
+		terms = append(terms, weightedTerm{term: term, score: float64(freq) * max(idf, 0.1)})
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].score != terms[j].score {
+			return terms[i].score > terms[j].score
+		}
+		return terms[i].term < terms[j].term
+	})
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms, nil
+}
+
+// 인덱스에 저장된 문서 본문을 읽는 함수 (두 번째 반환값은 문서 존재 여부)
+func indexedContent(id string) (string, bool, error) {
+	doc, err := index.Document(id)
+	if err != nil {
+		return "", false, fmt.Errorf("Failed to load document: %w", err)
+	}
+	if doc == nil {
+		return "", false, nil
+	}
+
+	var content string
+	doc.VisitFields(func(field indexapi.Field) {
+		if field.Name() == defaultSearchField {
+			content = string(field.Value())
+		}
+	})
+	return content, true, nil
+}
+
+// 문서 추가/수정 요청 본문
+type documentBody struct {
+	// 추가할 때 지정할 문서 ID (없으면 자동으로 부여하며, 이미 있는 ID면 409)
+	ID *int `json:"id"`
+	// 외부 시스템의 문서 ID (같은 값으로 다시 보내면 기존 문서를 갱신)
+	ExternalID *string   `json:"external_id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Tags       []string  `json:"tags"`
+	Price      *float64  `json:"price"`
+	Location   *geoPoint `json:"location"`
+	// 없으면 저장 시각을 사용
+	CreatedAt *time.Time             `json:"created_at"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	// 이 시각이 지나면 검색에서 제외되고 주기적으로 삭제됨
+	ExpiresAt *time.Time `json:"expires_at"`
+	// 문서 종류 (article, product, faq, 없거나 다른 값이면 기본 매핑으로 색인)
+	Type string `json:"type,omitempty"`
+	// 점수순 검색에서 점수에 곱하는 문서 가중치 (없으면 1)
+	Boost *float64 `json:"boost,omitempty"`
+	// PUT에서 If-Match 헤더 대신 사용할 수 있는 기대 버전
+	ExpectedVersion *int `json:"expected_version,omitempty"`
+}
+
+// 문서 요청 본문을 읽고 좌표 범위와 메타데이터 형태를 검증하는 함수
+func decodeDocumentBody(body io.Reader) (*documentBody, error) {
+	var req documentBody
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, errors.New("Invalid request body")
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (req *documentBody) validate() error {
+	if req.ID != nil && *req.ID <= 0 {
+		return errors.New("Invalid request body: id must be a positive integer")
+	}
+	if req.Location != nil && (req.Location.Lat < -90 || req.Location.Lat > 90 || req.Location.Lon < -180 || req.Location.Lon > 180) {
+		return errors.New("Invalid request body: location must have lat between -90 and 90 and lon between -180 and 180")
+	}
+	if err := validateDocumentBoost(req.Boost); err != nil {
+		return err
+	}
+	if len(req.Type) > maxDocumentTypeLength {
+		return fmt.Errorf("Invalid request body: type must be at most %d characters", maxDocumentTypeLength)
+	}
+	return validateMetadata(req.Metadata)
+}
+
+// 위치를 Postgres의 latitude, longitude 컬럼 값으로 나누는 함수 (위치가 없으면 NULL)
+func (req *documentBody) coordinates() (*float64, *float64) {
+	if req.Location == nil {
+		return nil, nil
+	}
+	return &req.Location.Lat, &req.Location.Lon
+}
+
+// 문서를 추가하고, external_id가 같은 행이 이미 있으면 그 행을 갱신하는 쿼리 (삭제 표시된 행이면 복구됨)
+// 분석 결과가 빈 문자열이면(ANALYSIS_MODE=none) analysis를 NULL로 저장해, 분석을 켠 뒤 재색인하면 분석되게 한다.
+// 유니크 제약에 대한 ON CONFLICT로 처리하므로 같은 external_id의 동시 요청도 행을 두 개 만들지 않는다.
+// 마지막 반환 컬럼은 새로 추가된 행인지 여부 (갱신된 행은 xmax가 0이 아님)
+const upsertDocumentSQL = `INSERT INTO documents(external_id, title, content, analysis, tags, price, latitude, longitude, created_at, metadata, expires_at, content_hash, doc_type, boost, analysis_source)
+VALUES($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, COALESCE($9, now()), $10, $11, $12, NULLIF($13, ''), COALESCE($14, 1), $15)
+ON CONFLICT (external_id) DO UPDATE SET title = EXCLUDED.title, content = EXCLUDED.content, analysis = EXCLUDED.analysis, analysis_source = EXCLUDED.analysis_source,
+	tags = EXCLUDED.tags, price = EXCLUDED.price, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude,
+	created_at = COALESCE($9, documents.created_at), metadata = EXCLUDED.metadata, expires_at = EXCLUDED.expires_at,
+	content_hash = EXCLUDED.content_hash, doc_type = EXCLUDED.doc_type, boost = EXCLUDED.boost, version = documents.version + 1, deleted_at = NULL
+RETURNING id, created_at, version, xmax = 0`
+
+// 클라이언트가 지정한 ID로 문서를 추가하는 쿼리 (인자는 upsertDocumentSQL 뒤에 ID를 붙인 것)
+// 지정한 ID는 다른 문서를 가리키지 않아야 하므로 external_id가 같은 문서가 있어도 갱신하지 않고 충돌로 처리한다.
+const insertDocumentWithIDSQL = `INSERT INTO documents(id, external_id, title, content, analysis, tags, price, latitude, longitude, created_at, metadata, expires_at, content_hash, doc_type, boost, analysis_source)
+VALUES($16, $1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, COALESCE($9, now()), $10, $11, $12, NULLIF($13, ''), COALESCE($14, 1), $15)
+RETURNING id, created_at, version, true`
+
+// 요청에 ID가 있는지에 따라 문서를 추가하는 쿼리와 인자 목록을 고르는 함수
+func (req *documentBody) insertStatement(analysis, source string) (string, []interface{}) {
+	if req.ID != nil {
+		return insertDocumentWithIDSQL, append(req.upsertArgs(analysis, source), *req.ID)
+	}
+	return upsertDocumentSQL, req.upsertArgs(analysis, source)
+}
+
+// 지정한 ID가 문서 ID 시퀀스보다 크면 시퀀스를 그 ID까지 앞당기는 함수
+// 이후 자동으로 부여하는 ID가 지정한 ID와 겹치지 않게 하며, 시퀀스를 되돌리지는 않는다.
+func advanceDocumentSequence(ctx context.Context, tx *sql.Tx, id int) error {
+	if _, err := tx.ExecContext(ctx, "SELECT setval('documents_id_seq', $1) WHERE $1 > (SELECT last_value FROM documents_id_seq)", id); err != nil {
+		return fmt.Errorf("Failed to advance document ID sequence: %w", err)
+	}
+	return nil
+}
+
+// 트랜잭션 안에서 지정한 ID로 문서를 추가하고 시퀀스를 맞추는 함수 (커밋은 색인한 뒤 호출 측에서 함)
+func insertDocumentWithID(ctx context.Context, tx *sql.Tx, req *documentBody, analysis, source string) (time.Time, int, error) {
+	var createdAt time.Time
+	var version int
+	query, args := req.insertStatement(analysis, source)
+	var id int
+	var created bool
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&id, &createdAt, &version, &created)
+	if exists := asDocumentExistsError(req, err); exists != nil {
+		return createdAt, version, exists
+	}
+	if err != nil {
+		return createdAt, version, err
+	}
+	if err := advanceDocumentSequence(ctx, tx, id); err != nil {
+		return createdAt, version, err
+	}
+	return createdAt, version, nil
+}
+
+// 요청의 ctx로 문서를 저장할 트랜잭션을 시작하는 함수
+// 트랜잭션의 쿼리는 ctx로 실행해 클라이언트가 연결을 끊으면 멈추지만, 트랜잭션 자체는 ctx가 끝나도 롤백되지 않는다.
+// 색인까지 마친 뒤 연결이 끊겨도 커밋되므로, 인덱스에만 있고 DB에는 없는 문서가 생기지 않는다.
+func beginTx(ctx context.Context) (*sql.Tx, error) {
+	return db.BeginTx(context.WithoutCancel(ctx), nil)
+}
+
+// 지정한 ID나 external_id의 문서가 이미 있음을 나타내는 오류 (409로 응답)
+type documentExistsError struct {
+	id         int
+	externalID *string
+}
+
+func (e *documentExistsError) Error() string {
+	if e.externalID != nil {
+		return fmt.Sprintf("Document already exists with external_id: %s", *e.externalID)
+	}
+	return fmt.Sprintf("Document already exists: %d", e.id)
+}
+
+// 지정한 ID로 추가할 때의 기본 키나 external_id 유니크 제약 위반을 documentExistsError로 바꾸는 함수 (해당하지 않으면 nil)
+func asDocumentExistsError(req *documentBody, err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return nil
+	}
+	switch pqErr.Constraint {
+	case "documents_pkey":
+		return &documentExistsError{id: *req.ID}
+	case "documents_external_id_key":
+		return &documentExistsError{externalID: req.ExternalID}
+	}
+	return nil
+}
+
+// 지정한 ID나 external_id의 문서가 이미 있으면 documentExistsError를 반환하는 함수
+// 일괄 추가에서 제약 위반으로 트랜잭션 전체가 실패하지 않도록 저장 전에 확인한다.
+func checkDocumentID(ctx context.Context, req *documentBody) error {
+	var id int
+	var externalID *string
+	err := db.QueryRowContext(ctx, "SELECT id, external_id FROM documents WHERE id = $1 OR external_id = $2 LIMIT 1", *req.ID, req.ExternalID).Scan(&id, &externalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to check document ID: %w", err)
+	}
+	if id == *req.ID {
+		return &documentExistsError{id: id}
+	}
+	return &documentExistsError{externalID: externalID}
+}
+
+// upsertDocumentSQL의 인자 목록을 만드는 함수
+func (req *documentBody) upsertArgs(analysis, source string) []interface{} {
+	latitude, longitude := req.coordinates()
+	return []interface{}{req.ExternalID, req.Title, req.Content, analysis, pq.Array(req.Tags), req.Price, latitude, longitude, req.CreatedAt, metadataColumn(req.Metadata), req.ExpiresAt, contentHash(req.Content), req.Type, req.Boost, source}
+}
+
+// 형태소 분석 결과와 저장 시각, 버전으로 색인할 문서를 만드는 함수 (본문의 언어도 감지)
+func (req *documentBody) indexDocument(analysis string, createdAt time.Time, version int) indexDocument {
+	doc := indexDocument{Title: req.Title, Content: req.Content, Analysis: analysis, Tags: req.Tags, Price: req.Price, CreatedAt: createdAt, Location: req.Location, Metadata: indexableMetadata(req.Metadata), Version: version, ExpiresAt: req.ExpiresAt, ExternalID: req.ExternalID, DocType: req.Type, Boost: indexedBoost(req.Boost)}
+	if doc.DocType == "" {
+		doc.DocType = defaultDocumentType
+	}
+	doc.setLanguage()
+	return doc
+}
+
+// 단일 문서 조회 응답
+type storedDocument struct {
+	ID         int        `json:"id"`
+	ExternalID *string    `json:"external_id,omitempty"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// 갱신할 때마다 1씩 증가
+	Version int `json:"version"`
+	// 점수순 검색에서 점수에 곱하는 문서 가중치
+	Boost float64 `json:"boost"`
+	// 검색 가능 여부와 관계없이 저장된 메타데이터 전체
+	Metadata map[string]interface{} `json:"metadata"`
+	// include_analysis=true일 때 저장된 형태소 분석 결과와 인덱스에 색인된 본문 텀 목록
+	Analysis *string   `json:"analysis,omitempty"`
+	Tokens   *[]string `json:"tokens,omitempty"`
+}
+
+// 문서 핸들러 (GET, HEAD, PUT, PATCH, DELETE /documents/{id})
+func documentHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid document ID: %s", r.PathValue("id")), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		// 본문 없이 인덱스 포함 여부만 상태 코드로 알리고, DB 행 존재 여부는 헤더로 알려 두 저장소의 불일치를 확인할 수 있게 함
+		_, indexed, err := indexedContent(strconv.Itoa(id))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var inDatabase bool
+		if err := db.QueryRowContext(r.Context(), "SELECT EXISTS(SELECT 1 FROM documents WHERE id = $1 AND deleted_at IS NULL AND tenant IS NULL)", id).Scan(&inDatabase); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-In-Database", strconv.FormatBool(inDatabase))
+		if !indexed {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		doc, err := loadDocument(r.Context(), id)
+		if err != nil {
+			writeDocumentError(w, r, id, err)
+			return
+		}
+		if r.URL.Query().Get("include_analysis") == "true" {
+			tokens, err := indexedTokens(strconv.Itoa(id))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			doc.Tokens = &tokens
+		} else {
+			doc.Analysis = nil
+		}
+		setVersionHeader(w, doc.Version)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	case http.MethodPut:
+		req, err := decodeDocumentBody(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ID != nil && *req.ID != id {
+			http.Error(w, fmt.Sprintf("Invalid request body: id %d does not match document %d", *req.ID, id), http.StatusBadRequest)
+			return
+		}
+		version, err := expectedVersion(r, req.ExpectedVersion)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc, err := updateDocument(r.Context(), id, req, version)
+		if err != nil {
+			writeDocumentError(w, r, id, err)
+			return
+		}
+		setVersionHeader(w, doc.Version)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	case http.MethodPatch:
+		version, err := expectedVersion(r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc, err := patchDocument(r.Context(), id, r.Body, version)
+		if err != nil {
+			writeDocumentError(w, r, id, err)
+			return
+		}
+		setVersionHeader(w, doc.Version)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	case http.MethodDelete:
+		// 기본은 삭제 표시만 하고 복구할 수 있게 두며, hard=true이면 행을 완전히 지움
+		remove := softDeleteDocument
+		if r.URL.Query().Get("hard") == "true" {
+			remove = deleteDocument
+		}
+		if err := remove(r.Context(), id); err != nil {
+			writeDocumentError(w, r, id, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"deleted": id})
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// Postgres에서 문서 행을 읽는 함수 (없으면 sql.ErrNoRows)
+func loadDocument(ctx context.Context, id int) (*storedDocument, error) {
+	doc := &storedDocument{}
+	var metadataJSON []byte
+	err := db.QueryRowContext(ctx, "SELECT id, external_id, COALESCE(title, ''), content, analysis, created_at, expires_at, version, boost, metadata FROM documents WHERE id = $1 AND deleted_at IS NULL AND tenant IS NULL", id).Scan(&doc.ID, &doc.ExternalID, &doc.Title, &doc.Content, &doc.Analysis, &doc.CreatedAt, &doc.ExpiresAt, &doc.Version, &doc.Boost, &metadataJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load document: %w", err)
+	}
+	if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
+		return nil, fmt.Errorf("Failed to decode metadata: %w", err)
+	}
+	return doc, nil
+}
+
+// 인덱스에 저장된 본문을 필드 분석기로 다시 분석해 색인된 텀을 구하는 함수
+// 인덱스에 없는 문서는 빈 목록을 반환해 DB와 인덱스가 어긋난 것을 알 수 있게 한다.
+func indexedTokens(id string) ([]string, error) {
+	content, found, err := indexedContent(id)
+	if err != nil || !found {
+		return []string{}, err
+	}
+	tokens := []string{}
+	if analyzer := queryAnalyzer(index.Mapping(), defaultSearchField, ""); analyzer != nil {
+		for _, token := range analyzer.Analyze([]byte(content)) {
+			tokens = append(tokens, string(token.Term))
+		}
+	}
+	return tokens, nil
+}
+
+// 문서 내용을 새로 분석해 Postgres 행을 갱신하고 같은 ID로 다시 색인하는 함수
+// 같은 ID로 Index를 호출하면 기존 문서가 교체되므로 이전 본문의 텀은 더 이상 일치하지 않는다.
+// 색인이 실패하면 DB 갱신을 롤백한다.
+func updateDocument(ctx context.Context, id int, req *documentBody, expectedVersion *int) (*storedDocument, error) {
+	// OpenAI API를 사용하여 형태소 분석 수행
+	tokens, source, err := getMorphologicalAnalysis(ctx, req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to analyze text: %w", err)
+	}
+	analysis := analysisText(tokens)
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	return saveDocument(ctx, tx, id, req, analysis, source, expectedVersion)
+}
+
+// 트랜잭션 안에서 문서 행을 갱신하고 다시 색인한 뒤 커밋하는 함수 (색인이 실패하면 커밋하지 않음)
+// expectedVersion이 있으면 저장된 버전과 다를 때 versionConflictError를 반환한다.
+// source는 분석 결과를 만든 곳이며, 저장된 분석 결과를 다시 쓰는 경우에는 빈 문자열을 넘겨 기록된 값을 유지한다.
+func saveDocument(ctx context.Context, tx *sql.Tx, id int, req *documentBody, analysis, source string, expectedVersion *int) (*storedDocument, error) {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM documents WHERE id = $1 AND deleted_at IS NULL AND tenant IS NULL FOR UPDATE", id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load document: %w", err)
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return nil, &versionConflictError{id: id, version: version}
+	}
+	hash := contentHash(req.Content)
+	duplicateID, err := findDuplicateDocument(ctx, tx, nil, hash, nil, id)
+	if err != nil {
+		return nil, err
+	}
+	if duplicateID != 0 {
+		return nil, &duplicateDocumentError{id: duplicateID}
+	}
+
+	doc := &storedDocument{ID: id}
+	latitude, longitude := req.coordinates()
+	err = tx.QueryRowContext(ctx, "UPDATE documents SET title = $2, content = $3, analysis = NULLIF($4, ''), tags = $5, price = $6, latitude = $7, longitude = $8, created_at = COALESCE($9, created_at), metadata = $10, expires_at = $11, content_hash = $12, doc_type = NULLIF($13, ''), boost = COALESCE($14, 1), analysis_source = COALESCE(NULLIF($15, ''), analysis_source), version = version + 1 WHERE id = $1 RETURNING external_id, COALESCE(title, ''), content, created_at, expires_at, version, boost", id, req.Title, req.Content, analysis, pq.Array(req.Tags), req.Price, latitude, longitude, req.CreatedAt, metadataColumn(req.Metadata), req.ExpiresAt, hash, req.Type, req.Boost, source).Scan(&doc.ExternalID, &doc.Title, &doc.Content, &doc.CreatedAt, &doc.ExpiresAt, &doc.Version, &doc.Boost)
+	if isDuplicateContentError(err) {
+		return nil, &duplicateDocumentError{}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to update document: %w", err)
+	}
+	doc.Metadata = req.Metadata
+	// PUT 본문에 external_id가 없어도 저장된 외부 ID를 색인
+	req.ExternalID = doc.ExternalID
+
+	// 색인하기 전에 요청이 취소됐으면 롤백 (색인한 뒤에는 취소돼도 커밋함)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := index.Index(strconv.Itoa(id), req.indexDocument(analysis, doc.CreatedAt, doc.Version)); err != nil {
+		return nil, fmt.Errorf("Failed to index data: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Document %d was re-indexed but the database update failed: %v", id, err)
+		return nil, fmt.Errorf("Failed to commit update: %w", err)
+	}
+	suggester.markDirty()
+	return doc, nil
+}
+
+// PATCH로 바꿀 수 있는 문서 필드
+var patchableFields = map[string]bool{"title": true, "content": true, "tags": true, "price": true, "location": true, "created_at": true, "metadata": true, "expires_at": true, "type": true, "boost": true}
+
+// PATCH 도중 다른 요청이 먼저 문서를 바꿨을 때 다시 시도하는 최대 횟수 (기대 버전을 지정하지 않은 경우)
+const patchAttempts = 3
+
+// 문서의 일부 필드만 바꾸는 함수
+// 현재 행을 읽어 본문에 있는 필드만 덮어쓴 뒤 (metadata는 객체 전체를 교체) 트랜잭션을 열고 읽은 버전 그대로인지 확인해 저장한다.
+// 형태소 분석은 content가 바뀐 경우에만 트랜잭션 밖에서 다시 수행하고 그 외에는 저장된 분석 결과를 재사용하므로,
+// 분석을 기다리는 동안 행을 잠그지 않는다. 그 사이에 문서가 바뀌었으면 기대 버전이 없을 때만 처음부터 다시 시도한다.
+func patchDocument(ctx context.Context, id int, body io.Reader, expectedVersion *int) (*storedDocument, error) {
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&patch); err != nil {
+		return nil, &badRequestError{"Invalid request body"}
+	}
+	if raw, ok := patch["expected_version"]; ok {
+		delete(patch, "expected_version")
+		if expectedVersion == nil {
+			expectedVersion = new(int)
+			if err := json.Unmarshal(raw, expectedVersion); err != nil {
+				return nil, &badRequestError{"Invalid request body: expected_version must be an integer"}
+			}
+		}
+	}
+	for _, field := range sortedKeys(patch) {
+		if !patchableFields[field] {
+			return nil, &badRequestError{fmt.Sprintf("Invalid request body: field '%s' cannot be patched (allowed: %s)", field, strings.Join(sortedKeys(patchableFields), ", "))}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		doc, err := applyPatch(ctx, id, patch, expectedVersion)
+		var conflict *versionConflictError
+		if expectedVersion == nil && attempt < patchAttempts && errors.As(err, &conflict) {
+			continue
+		}
+		return doc, err
+	}
+}
+
+// 현재 문서에 PATCH 본문을 합쳐 한 번 저장을 시도하는 함수
+// 읽은 뒤 저장하기 전에 문서가 바뀌었으면 versionConflictError를 반환한다.
+func applyPatch(ctx context.Context, id int, patch map[string]json.RawMessage, expectedVersion *int) (*storedDocument, error) {
+	current, analysis, version, err := readDocumentBody(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		return nil, &versionConflictError{id: id, version: version}
+	}
+
+	// 현재 문서를 JSON 객체로 바꾼 뒤 요청한 필드만 덮어써서 다시 읽음
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode document: %w", err)
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &merged); err != nil {
+		return nil, fmt.Errorf("Failed to encode document: %w", err)
+	}
+	for field, value := range patch {
+		merged[field] = value
+	}
+	encoded, _ = json.Marshal(merged)
+	var req documentBody
+	if err := json.Unmarshal(encoded, &req); err != nil {
+		return nil, &badRequestError{"Invalid request body"}
+	}
+	if err := req.validate(); err != nil {
+		return nil, &badRequestError{err.Error()}
+	}
+
+	source := ""
+	if req.Content != current.Content || analysis == nil {
+		// OpenAI API를 사용하여 형태소 분석 수행
+		tokens, analyzedBy, err := getMorphologicalAnalysis(ctx, req.Content)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to analyze text: %w", err)
+		}
+		analyzed := analysisText(tokens)
+		analysis, source = &analyzed, analyzedBy
+	}
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// 읽은 버전을 기대 버전으로 넘겨, 분석하는 동안 바뀐 문서를 덮어쓰지 않게 함
+	return saveDocument(ctx, tx, id, &req, *analysis, source, &version)
+}
+
+// documentBody로 읽는 문서 행의 열 (scanDocumentBody와 순서가 같아야 함)
+const documentBodyColumns = "external_id, COALESCE(title, ''), content, analysis, tags, price, latitude, longitude, created_at, metadata, expires_at, COALESCE(doc_type, ''), boost, version"
+
+// 삭제되지 않은 문서 행을 잠그지 않고 읽는 함수 (반환값은 본문, 저장된 형태소 분석 결과, 버전)
+func readDocumentBody(ctx context.Context, id int) (*documentBody, *string, int, error) {
+	return scanDocumentBody(db.QueryRowContext(ctx, "SELECT "+documentBodyColumns+" FROM documents WHERE id = $1 AND deleted_at IS NULL AND tenant IS NULL", id))
+}
+
+// 트랜잭션이 끝날 때까지 다른 쓰기를 막도록 문서 행을 잠그고 읽는 함수 (두 번째 반환값은 저장된 형태소 분석 결과)
+// deleted가 true이면 삭제 표시된 행만, false이면 삭제되지 않은 행만 읽는다.
+func lockDocumentBody(ctx context.Context, tx *sql.Tx, id int, deleted bool) (*documentBody, *string, error) {
+	req, analysis, _, err := scanDocumentBody(tx.QueryRowContext(ctx, "SELECT "+documentBodyColumns+" FROM documents WHERE id = $1 AND (deleted_at IS NOT NULL) = $2 AND tenant IS NULL FOR UPDATE", id, deleted))
+	return req, analysis, err
+}
+
+// documentBodyColumns로 읽은 행을 documentBody로 바꾸는 함수
+func scanDocumentBody(row *sql.Row) (*documentBody, *string, int, error) {
+	req := &documentBody{}
+	var analysis *string
+	var createdAt time.Time
+	var latitude, longitude *float64
+	var metadataJSON []byte
+	var version int
+	err := row.Scan(&req.ExternalID, &req.Title, &req.Content, &analysis, pq.Array(&req.Tags), &req.Price, &latitude, &longitude, &createdAt, &metadataJSON, &req.ExpiresAt, &req.Type, &req.Boost, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, 0, err
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("Failed to load document: %w", err)
+	}
+	if latitude != nil && longitude != nil {
+		req.Location = &geoPoint{Lat: *latitude, Lon: *longitude}
+	}
+	req.CreatedAt = &createdAt
+	if err := json.Unmarshal(metadataJSON, &req.Metadata); err != nil {
+		return nil, nil, 0, fmt.Errorf("Failed to decode metadata: %w", err)
+	}
+	return req, analysis, version, nil
+}
+
+// 삭제 표시된 문서 복구 핸들러 (POST /documents/{id}/restore)
+func restoreDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid document ID: %s", r.PathValue("id")), http.StatusBadRequest)
+		return
+	}
+	if err := restoreDocument(r.Context(), id); err != nil {
+		if requestCancelled(r) {
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("Deleted document not found: %d", id), http.StatusNotFound)
+			return
+		}
+		var duplicate *duplicateDocumentError
+		if errors.As(err, &duplicate) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"restored": id})
+}
+
+// 삭제 표시를 지우고 문서를 다시 색인하는 함수 (저장된 분석 결과가 없을 때만 다시 분석)
+func restoreDocument(ctx context.Context, id int) error {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	req, analysis, err := lockDocumentBody(ctx, tx, id, true)
+	if err != nil {
+		return err
+	}
+	// 삭제 표시된 동안 같은 내용의 문서가 추가됐으면 복구하지 않음
+	duplicateID, err := findDuplicateDocument(ctx, tx, nil, contentHash(req.Content), nil, id)
+	if err != nil {
+		return err
+	}
+	if duplicateID != 0 {
+		return &duplicateDocumentError{id: duplicateID}
+	}
+	source := ""
+	if analysis == nil {
+		// OpenAI API를 사용하여 형태소 분석 수행
+		tokens, analyzedBy, err := getMorphologicalAnalysis(ctx, req.Content)
+		if err != nil {
+			return fmt.Errorf("Failed to analyze text: %w", err)
+		}
+		analyzed := analysisText(tokens)
+		analysis, source = &analyzed, analyzedBy
+	}
+
+	var createdAt time.Time
+	var version int
+	err = tx.QueryRowContext(ctx, "UPDATE documents SET deleted_at = NULL, analysis = NULLIF($2, ''), content_hash = $3, analysis_source = COALESCE(NULLIF($4, ''), analysis_source) WHERE id = $1 RETURNING created_at, version", id, *analysis, contentHash(req.Content), source).Scan(&createdAt, &version)
+	if isDuplicateContentError(err) {
+		return &duplicateDocumentError{}
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to restore document: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := index.Index(strconv.Itoa(id), req.indexDocument(*analysis, createdAt, version)); err != nil {
+		return fmt.Errorf("Failed to index data: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Document %d was re-indexed but the database restore failed: %v", id, err)
+		return fmt.Errorf("Failed to commit restore: %w", err)
+	}
+	suggester.markDirty()
+	return nil
+}
+
+// 저장된 버전이 요청한 버전과 다름을 나타내는 오류 (409로 응답)
+type versionConflictError struct {
+	id      int
+	version int
+}
+
+func (e *versionConflictError) Error() string {
+	return fmt.Sprintf("Version conflict: document %d is at version %d", e.id, e.version)
+}
+
+// If-Match 헤더에서 기대하는 문서 버전을 읽는 함수 (헤더가 없으면 fallback 사용)
+// ETag 형식("3")과 따옴표 없는 숫자를 모두 받는다.
+func expectedVersion(r *http.Request, fallback *int) (*int, error) {
+	raw := r.Header.Get("If-Match")
+	if raw == "" {
+		return fallback, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(raw, "W/"), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid If-Match header: must be a document version")
+	}
+	return &version, nil
+}
+
+// 문서 버전을 ETag 헤더로 알리는 함수
+func setVersionHeader(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// 요청 본문이 잘못되었음을 나타내는 오류 (400으로 응답)
+type badRequestError struct {
+	message string
+}
+
+func (e *badRequestError) Error() string {
+	return e.message
+}
+
+// Postgres 행과 인덱스 문서를 함께 삭제하는 함수 (삭제 표시된 행도 지움)
+// 인덱스 삭제가 실패하면 DB 삭제를 롤백해 두 저장소가 어긋나지 않게 한다.
+func deleteDocument(ctx context.Context, id int) error {
+	return removeDocument(ctx, id, "DELETE FROM documents WHERE id = $1 AND tenant IS NULL")
+}
+
+// Postgres 행에 삭제 시각을 표시하고 인덱스에서 문서를 지우는 함수 (이미 삭제 표시된 문서는 없는 것으로 처리)
+func softDeleteDocument(ctx context.Context, id int) error {
+	return removeDocument(ctx, id, "UPDATE documents SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL AND tenant IS NULL")
+}
+
+// 한 트랜잭션에서 행을 지우거나 삭제 표시하고 인덱스에서 문서를 지우는 함수
+func removeDocument(ctx context.Context, id int, statement string) error {
+	tx, err := beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, statement, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete document: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	// 인덱스에서 지우기 전에 요청이 취소됐으면 롤백
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := index.Delete(strconv.Itoa(id)); err != nil {
+		return fmt.Errorf("Failed to delete document from index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		// 인덱스에서는 이미 지워졌으므로 재시작 시 DB로부터 인덱스를 다시 만들어야 일치함
+		log.Printf("Document %d was removed from the index but the database delete failed: %v", id, err)
+		return fmt.Errorf("Failed to commit delete: %w", err)
+	}
+	suggester.markDirty()
+	return nil
+}
+
+// 문서 처리 오류를 응답하는 함수 (없는 문서는 404, 잘못된 본문은 400, 버전 충돌과 내용 중복은 409)
+// 클라이언트가 연결을 끊어 실패했으면 응답하지 않는다.
+func writeDocumentError(w http.ResponseWriter, r *http.Request, id int, err error) {
+	if requestCancelled(r) {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, fmt.Sprintf("Document not found: %d", id), http.StatusNotFound)
+		return
+	}
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var conflict *versionConflictError
+	if errors.As(err, &conflict) {
+		setVersionHeader(w, conflict.version)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	var duplicate *duplicateDocumentError
+	if errors.As(err, &duplicate) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// 문서 목록 조회의 기본 개수와 본문 미리보기 길이 (글자 수)
+const (
+	defaultListLimit  = 50
+	listPreviewLength = 200
+)
+
+// 문서 목록 조회의 최대 개수 (DOCUMENTS_MAX_LIMIT로 변경 가능)
+var maxListLimit = 500
+
+// 문서 목록에서 정렬에 사용할 수 있는 컬럼
+var listOrderColumns = map[string]bool{"id": true, "created_at": true}
+
+// 문서 목록의 항목 (본문은 앞부분만 포함)
+type documentSummary struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Preview   string    `json:"preview"`
+	CreatedAt time.Time `json:"created_at"`
+	// include_deleted=true일 때 삭제 표시된 문서의 삭제 시각
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// 문서 목록 핸들러 (GET /documents?limit=50&offset=0&order=created_at.desc&since=2024-01-01T00:00:00Z&include_deleted=true)
+// DB에 저장된 문서를 인덱스와 대조할 수 있도록 Postgres 기준으로 나열한다.
+// 정렬 값이 같은 행의 순서가 페이지마다 바뀌지 않도록 id를 보조 정렬 기준으로 사용한다.
+func listDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	limit, err := parseIntParam(values, "limit", defaultListLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit == 0 || limit > maxListLimit {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'limit': must be between 1 and %d", maxListLimit), http.StatusBadRequest)
+		return
+	}
+	offset, err := parseIntParam(values, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	orderBy, err := parseListOrder(values.Get("order"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var since *time.Time
+	if raw := values.Get("since"); raw != "" {
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "Invalid query parameter 'since': must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = &value
+	}
+
+	includeDeleted := values.Get("include_deleted") == "true"
+
+	var total int
+	if err := db.QueryRowContext(r.Context(), "SELECT count(*) FROM documents WHERE ($1::timestamptz IS NULL OR created_at >= $1) AND ($2 OR deleted_at IS NULL) AND tenant IS NULL", since, includeDeleted).Scan(&total); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// 정렬 표현식은 허용된 컬럼과 방향으로만 만들어지므로 쿼리 문자열에 직접 넣어도 안전함
+	rows, err := db.QueryContext(r.Context(), "SELECT id, COALESCE(title, ''), content, created_at, deleted_at FROM documents WHERE ($1::timestamptz IS NULL OR created_at >= $1) AND ($2 OR deleted_at IS NULL) AND tenant IS NULL ORDER BY "+orderBy+" LIMIT $3 OFFSET $4", since, includeDeleted, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	documents := []documentSummary{}
+	for rows.Next() {
+		var doc documentSummary
+		var content string
+		if err := rows.Scan(&doc.ID, &doc.Title, &content, &doc.CreatedAt, &doc.DeletedAt); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scan row: %v", err), http.StatusInternalServerError)
+			return
+		}
+		doc.Preview = previewText(content, listPreviewLength)
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Error iterating over rows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"documents": documents,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// order 파라미터(컬럼.방향)를 ORDER BY 절로 바꾸는 함수 (기본값은 id.asc)
+func parseListOrder(raw string) (string, error) {
+	if raw == "" {
+		return "id ASC", nil
+	}
+	column, direction, _ := strings.Cut(raw, ".")
+	if !listOrderColumns[column] {
+		return "", fmt.Errorf("Invalid query parameter 'order': unknown column '%s' (allowed: %s)", column, strings.Join(sortedKeys(listOrderColumns), ", "))
+	}
+	switch direction {
+	case "", "asc":
+		direction = "ASC"
+	case "desc":
+		direction = "DESC"
+	default:
+		return "", fmt.Errorf("Invalid query parameter 'order': direction must be 'asc' or 'desc'")
+	}
+	if column == "id" {
+		return "id " + direction, nil
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+// 본문의 앞부분을 바이트가 아닌 글자 단위로 잘라 한글이 깨지지 않게 하는 함수
+func previewText(content string, length int) string {
+	runes := []rune(content)
+	if len(runes) <= length {
+		return content
+	}
+	return string(runes[:length])
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 테스트 DB에 문서 행을 추가하고 메모리 인덱스에 색인하는 함수 (반환값은 문서 ID)
+func insertTestDocument(t *testing.T, content string) int {
+	t.Helper()
+	req := documentBody{Title: "제목", Content: content}
+	var id int
+	if err := db.QueryRow("INSERT INTO documents (title, content, analysis, content_hash) VALUES ($1, $2, $2, $3) RETURNING id", req.Title, req.Content, contentHash(req.Content)).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	indexTestDocument(t, id, &req)
+	return id
+}
+
+func TestRemoveDocumentStopsWhenCancelled(t *testing.T) {
+	useTestDB(t)
+	useMemoryIndex(t)
+	id := insertTestDocument(t, "서울 시청 호텔")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, remove := range map[string]func(context.Context, int) error{"soft": softDeleteDocument, "hard": deleteDocument} {
+		if err := remove(ctx, id); !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s delete error = %v, want %v", name, err, context.Canceled)
+		}
+		if _, err := loadDocument(context.Background(), id); err != nil {
+			t.Errorf("%s delete of a cancelled request removed the row: %v", name, err)
+		}
+		if !documentIndexed(t, id) {
+			t.Errorf("%s delete of a cancelled request removed the document from the index", name)
+		}
+	}
+}
+
+// 문서 삭제가 항상 실패하는 인덱스
+type failingDeleteIndex struct {
+	bleveIndex
+}
+
+func (failingDeleteIndex) Delete(string) error {
+	return errors.New("index is read-only")
+}
+
+// 문서 핸들러에 요청을 보내고 응답을 반환하는 함수
+func serveDocument(method, target, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/documents/{id}", documentHandler)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestDeleteDocument(t *testing.T) {
+	testDB := useTestDB(t)
+	useMemoryIndex(t)
+
+	for _, target := range []string{"/documents/%d", "/documents/%d?hard=true"} {
+		id := insertTestDocument(t, "서울 시청 호텔 "+target)
+		path := strings.Replace(target, "%d", strconv.Itoa(id), 1)
+		if rec := serveDocument(http.MethodDelete, path, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":`+strconv.Itoa(id)) {
+			t.Fatalf("DELETE %s = %d %s, want 200 with the deleted ID", path, rec.Code, rec.Body)
+		}
+		if _, err := loadDocument(context.Background(), id); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("DELETE %s left the row: %v", path, err)
+		}
+		if documentIndexed(t, id) {
+			t.Errorf("DELETE %s left the document in the index", path)
+		}
+		// 이미 지운 문서는 404
+		if rec := serveDocument(http.MethodDelete, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("second DELETE %s = %d, want 404", path, rec.Code)
+		}
+	}
+
+	if rec := serveDocument(http.MethodDelete, "/documents/999999", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE of an unknown document = %d, want 404", rec.Code)
+	}
+
+	var remaining int
+	if err := testDB.QueryRow("SELECT count(*) FROM documents WHERE deleted_at IS NULL").Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Errorf("%d documents were not deleted", remaining)
+	}
+}
+
+func TestDeleteDocumentWhenIndexDeleteFails(t *testing.T) {
+	testDB := useTestDB(t)
+	memIndex := useMemoryIndex(t)
+	id := insertTestDocument(t, "서울 시청 호텔")
+	index = failingDeleteIndex{memIndex}
+
+	for name, remove := range map[string]func(context.Context, int) error{"soft": softDeleteDocument, "hard": deleteDocument} {
+		if err := remove(context.Background(), id); err == nil {
+			t.Fatalf("%s delete succeeded although the index delete failed", name)
+		}
+		// 인덱스 삭제가 실패하면 DB 삭제를 롤백해 행과 인덱스 문서가 함께 남음
+		var deleted bool
+		if err := testDB.QueryRow("SELECT deleted_at IS NOT NULL FROM documents WHERE id = $1", id).Scan(&deleted); err != nil {
+			t.Fatalf("%s delete removed the row: %v", name, err)
+		}
+		if deleted {
+			t.Errorf("%s delete marked the row deleted", name)
+		}
+		if !documentIndexed(t, id) {
+			t.Errorf("%s delete removed the document from the index", name)
+		}
+	}
+
+	if rec := serveDocument(http.MethodDelete, "/documents/"+strconv.Itoa(id), ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("DELETE = %d, want 500", rec.Code)
+	}
+	if _, err := loadDocument(context.Background(), id); err != nil {
+		t.Errorf("failed DELETE removed the row: %v", err)
+	}
+}
+
+func TestPatchDocumentAnalyzesOnlyChangedContent(t *testing.T) {
+	useTestDB(t)
+	useMemoryIndex(t)
+	calls := useFakeOpenAI(t)
+	id := insertTestDocument(t, "서울 시청 호텔")
+	path := "/documents/" + strconv.Itoa(id)
+
+	for _, tt := range []struct {
+		name  string
+		body  string
+		code  int
+		calls int32
+	}{
+		{"tags only", `{"tags": ["숙소"]}`, http.StatusOK, 0},
+		{"title and metadata", `{"title": "새 제목", "metadata": {"city": "서울"}}`, http.StatusOK, 0},
+		{"stale expected version", `{"tags": ["호텔"], "expected_version": 1}`, http.StatusConflict, 0},
+		{"content", `{"content": "부산 해운대 호텔"}`, http.StatusOK, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			rec := serveDocument(http.MethodPatch, path, tt.body)
+			if rec.Code != tt.code {
+				t.Fatalf("PATCH %s = %d %s, want %d", tt.body, rec.Code, rec.Body, tt.code)
+			}
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("PATCH %s made %d OpenAI calls, want %d", tt.body, got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestPutDocumentReplacesIndexedContent(t *testing.T) {
+	testDB := useTestDB(t)
+	useMemoryIndex(t)
+	useFakeOpenAI(t)
+	id := insertTestDocument(t, "서울 시청 호텔")
+	path := "/documents/" + strconv.Itoa(id)
+	search := func(q string) []string {
+		_, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {q}}.Encode(), nil))
+		return ids
+	}
+	if ids := search("시청"); len(ids) != 1 {
+		t.Fatalf("search 시청 before the update = %v, want the document", ids)
+	}
+
+	rec := serveDocument(http.MethodPut, path, `{"title": "새 제목", "content": "부산 해운대 바다"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT = %d %s (ETag %s), want 200 with version 2", rec.Code, rec.Body, rec.Header().Get("ETag"))
+	}
+	// 같은 ID로 다시 색인되어 이전 본문의 텀은 더 이상 일치하지 않음
+	if ids := search("시청"); len(ids) != 0 {
+		t.Errorf("search 시청 after the update = %v, want no hits", ids)
+	}
+	if ids := search("해운대"); !reflect.DeepEqual(ids, []string{strconv.Itoa(id)}) {
+		t.Errorf("search 해운대 after the update = %v, want [%d]", ids, id)
+	}
+	var content string
+	if err := testDB.QueryRow("SELECT content FROM documents WHERE id = $1", id).Scan(&content); err != nil || content != "부산 해운대 바다" {
+		t.Errorf("stored content = %q (%v), want the new content", content, err)
+	}
+
+	if rec := serveDocument(http.MethodPut, "/documents/999999", `{"content": "대구"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("PUT of an unknown document = %d, want 404", rec.Code)
+	}
+	// 이전 버전을 기대한 갱신은 DB와 인덱스를 바꾸지 않음
+	if rec := serveDocument(http.MethodPut, path, `{"content": "대구 동성로", "expected_version": 1}`); rec.Code != http.StatusConflict {
+		t.Errorf("PUT with a stale version = %d, want 409", rec.Code)
+	}
+	if ids := search("동성로"); len(ids) != 0 {
+		t.Errorf("search 동성로 after a rejected update = %v, want no hits", ids)
+	}
+}
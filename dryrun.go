@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// 형태소 분석 요청 하나의 토큰 수 추정에 쓰는 값
+// 시스템 메시지와 프롬프트 문구는 본문과 관계없이 요청마다 붙고, 응답은 형태소마다 따옴표와 쉼표가 붙어 본문보다 길어진다.
+const (
+	analysisPromptOverheadTokens = 40
+	analysisCompletionRatio      = 2.0
+	// 라틴 문자 등은 토큰 하나가 평균 4글자, 한글/한자/가나는 한 글자가 토큰 하나 정도
+	latinCharsPerToken = 4
+)
+
+// 형태소 분석 모델별 100만 토큰당 가격 (USD, 프롬프트와 응답), 표에 없는 모델은 비용을 추정하지 않음
+var analysisPricesPerMillion = map[string][2]float64{
+	openai.GPT4:          {30, 60},
+	openai.GPT4Turbo:     {10, 30},
+	openai.GPT4o:         {2.5, 10},
+	openai.GPT4oMini:     {0.15, 0.6},
+	openai.GPT3Dot5Turbo: {0.5, 1.5},
+}
+
+// 재색인 모의 실행 결과 (인덱스와 OpenAI를 건드리지 않고 예상만 계산)
+type reindexPlan struct {
+	DryRun bool   `json:"dry_run"`
+	Mode   string `json:"mode"`
+	// 색인할 행 수와 그중 저장된 분석 결과를 다시 쓰는 행 수, OpenAI로 새로 분석해야 하는 행 수
+	Documents int `json:"documents"`
+	Cached    int `json:"cached"`
+	Analyze   int `json:"to_analyze"`
+	analysisEstimate
+}
+
+// 새로 분석해야 하는 본문들의 토큰 사용량과 비용 추정
+type analysisEstimate struct {
+	Model                     string `json:"model"`
+	ContentChars              int    `json:"content_chars"`
+	EstimatedPromptTokens     int    `json:"estimated_prompt_tokens"`
+	EstimatedCompletionTokens int    `json:"estimated_completion_tokens"`
+	// 모델의 가격을 모르면 null
+	EstimatedCostUSD *float64 `json:"estimated_cost_usd"`
+}
+
+// 현재 형태소 분석 모델로 분석할 때의 빈 추정을 만드는 함수
+func newAnalysisEstimate() analysisEstimate {
+	estimate := analysisEstimate{Model: analysisModel}
+	if _, ok := analysisPricesPerMillion[analysisModel]; ok {
+		estimate.EstimatedCostUSD = new(float64)
+	}
+	return estimate
+}
+
+// 본문 하나를 분석하는 요청의 토큰 수를 추정에 더하는 함수
+func (e *analysisEstimate) add(content string) {
+	tokens := estimateTokens(content)
+	e.ContentChars += utf8.RuneCountInString(content)
+	e.EstimatedPromptTokens += analysisPromptOverheadTokens + tokens
+	e.EstimatedCompletionTokens += int(math.Ceil(float64(tokens) * analysisCompletionRatio))
+	price, ok := analysisPricesPerMillion[e.Model]
+	if !ok {
+		return
+	}
+	cost := float64(e.EstimatedPromptTokens)*price[0] + float64(e.EstimatedCompletionTokens)*price[1]
+	// 센트 이하 네 자리까지 반올림해 같은 입력에 항상 같은 값을 응답
+	cost = math.Round(cost/1e6*1e4) / 1e4
+	e.EstimatedCostUSD = &cost
+}
+
+// 텍스트의 토큰 수를 추정하는 함수 (한글/한자/가나는 글자마다, 나머지 공백이 아닌 글자는 latinCharsPerToken개마다 토큰 하나)
+func estimateTokens(text string) int {
+	var cjkChars, otherChars int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hangul, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			cjkChars++
+		case !unicode.IsSpace(r):
+			otherChars++
+		}
+	}
+	return cjkChars + (otherChars+latinCharsPerToken-1)/latinCharsPerToken
+}
+
+// 재색인이 처리할 행을 훑어 분석할 행 수와 예상 비용을 계산하는 함수
+// 전체 재색인은 색인할 모든 행을, 증분 재색인은 동기화 기준 시각 이후에 바뀐 행을 훑는다.
+func planReindex(ctx context.Context, mode string) (reindexPlan, error) {
+	plan := reindexPlan{DryRun: true, Mode: mode, analysisEstimate: newAnalysisEstimate()}
+	filter, args := indexableDocumentsFilter, []interface{}{}
+	if mode == reindexIncremental {
+		if err := indexUnavailable(); err != nil {
+			return plan, err
+		}
+		since, err := readSyncWatermark()
+		if err != nil {
+			return plan, err
+		}
+		filter, args = filter+" AND updated_at >= $1", append(args, since)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT content, analysis IS NOT NULL FROM documents WHERE "+filter, args...)
+	if err != nil {
+		return plan, fmt.Errorf("Failed to query documents: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var content string
+		var cached bool
+		if err := rows.Scan(&content, &cached); err != nil {
+			return plan, fmt.Errorf("Failed to scan document: %w", err)
+		}
+		plan.Documents++
+		if cached {
+			plan.Cached++
+			continue
+		}
+		// 분석하지 않거나 로컬 분석기로만 분석하면 OpenAI를 거치지 않음
+		if !usesOpenAI() {
+			continue
+		}
+		plan.Analyze++
+		plan.add(content)
+	}
+	if err := rows.Err(); err != nil {
+		return plan, fmt.Errorf("Error iterating over rows: %w", err)
+	}
+	return plan, nil
+}
+
+// 재색인 모의 실행 결과를 응답하는 함수 (POST /admin/reindex?dry_run=true)
+func writeReindexPlan(w http.ResponseWriter, r *http.Request, mode string) {
+	plan, err := planReindex(r.Context(), mode)
+	if errors.Is(err, errIndexBuilding) {
+		writeIndexBuilding(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   ", 0},
+		{"서울 시청 호텔", 6},      // 한글은 글자마다 토큰 하나 (공백 제외)
+		{"Hello, world!", 3}, // 공백이 아닌 나머지 글자는 4글자마다 하나 (올림)
+		{"東京 タワ v1.2", 5},
+		{"a", 1},
+	} {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnalysisEstimate(t *testing.T) {
+	defer func(model string) { analysisModel = model }(analysisModel)
+	corpus := []string{"서울 시청 호텔", "Hello, world!", "東京 タワ v1.2"}
+
+	for _, tt := range []struct {
+		model   string
+		priced  bool
+		costUSD float64
+	}{
+		// (134 * 2.5 + 28 * 10) / 1e6 = 0.000615
+		{openai.GPT4o, true, 0.0006},
+		// (134 * 0.5 + 28 * 1.5) / 1e6 = 0.000109
+		{openai.GPT3Dot5Turbo, true, 0.0001},
+		{"local-model", false, 0},
+	} {
+		t.Run(tt.model, func(t *testing.T) {
+			analysisModel = tt.model
+			estimate := newAnalysisEstimate()
+			for _, content := range corpus {
+				estimate.add(content)
+			}
+			// 요청마다 프롬프트 40토큰 + 본문 토큰 (6 + 3 + 5), 응답은 본문 토큰의 두 배
+			if estimate.ContentChars != 31 || estimate.EstimatedPromptTokens != 134 || estimate.EstimatedCompletionTokens != 28 {
+				t.Errorf("estimate = %d chars, %d prompt and %d completion tokens, want 31, 134 and 28", estimate.ContentChars, estimate.EstimatedPromptTokens, estimate.EstimatedCompletionTokens)
+			}
+			switch {
+			case !tt.priced && estimate.EstimatedCostUSD != nil:
+				t.Errorf("cost = %v for a model without a price, want null", *estimate.EstimatedCostUSD)
+			case tt.priced && (estimate.EstimatedCostUSD == nil || *estimate.EstimatedCostUSD != tt.costUSD):
+				t.Errorf("cost = %v, want %v", estimate.EstimatedCostUSD, tt.costUSD)
+			}
+		})
+	}
+}
+
+func TestPlanReindex(t *testing.T) {
+	testDB := useTestDB(t)
+	useMemoryIndex(t)
+	defer func(model string) { analysisModel = model }(analysisModel)
+	analysisModel = openai.GPT4o
+	calls := useFakeOpenAI(t)
+
+	// 분석 결과가 저장된 행 하나와 분석이 필요한 행 둘, 색인하지 않는 삭제된 행 하나
+	if _, err := testDB.Exec(`INSERT INTO documents (content, analysis, deleted_at) VALUES
+		('서울 시청 호텔', '서울 시청 호텔', NULL),
+		('Hello, world!', NULL, NULL),
+		('東京 タワ v1.2', NULL, NULL),
+		('부산 해운대', NULL, now())`); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planReindex(context.Background(), reindexFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Documents != 3 || plan.Cached != 1 || plan.Analyze != 2 {
+		t.Errorf("plan = %d documents, %d cached, %d to analyze, want 3, 1 and 2", plan.Documents, plan.Cached, plan.Analyze)
+	}
+	if plan.EstimatedPromptTokens != 2*analysisPromptOverheadTokens+3+5 || plan.EstimatedCompletionTokens != 16 {
+		t.Errorf("plan = %d prompt and %d completion tokens, want 88 and 16", plan.EstimatedPromptTokens, plan.EstimatedCompletionTokens)
+	}
+	if calls.Load() != 0 || documentIndexed(t, 1) {
+		t.Error("dry run called OpenAI or touched the index")
+	}
+}
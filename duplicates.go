@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 본문 해시의 유니크 인덱스 이름 (이름 붙은 인덱스별로 구분하며 삭제 표시된 행은 제외)
+const contentHashIndex = "documents_tenant_content_hash_idx"
+
+// 내용이 같은 문서가 이미 있음을 나타내는 오류 (409로 응답, id가 0이면 기존 문서를 알 수 없음)
+type duplicateDocumentError struct {
+	id int
+}
+
+func (e *duplicateDocumentError) Error() string {
+	if e.id == 0 {
+		return "Duplicate document: a document with the same content already exists"
+	}
+	return fmt.Sprintf("Duplicate document: same content as document %d", e.id)
+}
+
+// 중복 판정에 사용할 본문 해시를 계산하는 함수
+// NFC로 정규화하고 공백 차이(연속된 공백, 줄바꿈, 앞뒤 공백)를 없앤 뒤 SHA-256을 구하므로 사소하게 다른 사본도 같은 해시가 된다.
+func contentHash(content string) string {
+	normalized := strings.Join(strings.Fields(norm.NFC.String(content)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// 트랜잭션 안팎에서 같은 쿼리를 실행하기 위한 인터페이스 (*sql.DB, *sql.Tx)
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// 같은 인덱스(tenant가 nil이면 기본 인덱스)에서 내용이 같은 기존 문서의 ID를 찾는 함수 (없으면 0)
+// externalID가 같은 문서는 갱신할 대상이고 excludeID는 저장하려는 문서 자신이므로 중복으로 보지 않는다.
+func findDuplicateDocument(ctx context.Context, q rowQuerier, tenant *string, hash string, externalID *string, excludeID int) (int, error) {
+	var id int
+	err := q.QueryRowContext(ctx, "SELECT id FROM documents WHERE content_hash = $1 AND deleted_at IS NULL AND ($2::text IS NULL OR external_id IS DISTINCT FROM $2) AND id <> $3 AND tenant IS NOT DISTINCT FROM $4 LIMIT 1", hash, externalID, excludeID, tenant).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed to check duplicate documents: %w", err)
+	}
+	return id, nil
+}
+
+// 본문 해시의 유니크 인덱스 위반인지 확인하는 함수 (중복 확인 후 저장 전에 같은 내용이 추가된 경우)
+func isDuplicateContentError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == contentHashIndex
+}
+
+// 추가하려는 문서와 내용이 같은 문서가 있으면 응답하고 true를 반환하는 함수
+// 기본은 기존 문서의 ID를 200으로 돌려주고, reject가 true이면 409로 거부한다.
+func respondIfDuplicate(w http.ResponseWriter, r *http.Request, tenant *string, req *documentBody, reject bool) bool {
+	// 데이터베이스 없이 실행하면 본문 해시를 저장하지 않으므로 중복을 확인하지 않음
+	if db == nil {
+		return false
+	}
+	id, err := findDuplicateDocument(r.Context(), db, tenant, contentHash(req.Content), req.ExternalID, 0)
+	if requestCancelled(r) {
+		return true
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	if id == 0 {
+		return false
+	}
+	if reject {
+		http.Error(w, (&duplicateDocumentError{id: id}).Error(), http.StatusConflict)
+		return true
+	}
+	writeInsertResponse(w, r, http.StatusOK, insertResponse{ID: id, Result: "duplicate"})
+	return true
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// 값 전체를 토큰 하나로 색인하되 대소문자를 구분하지 않는 분석기 이름
+// 태그, 외부 ID, 키워드 메타데이터를 이 분석기로 색인하며, 대소문자를 구분하려면 FIELD_ANALYZERS=external_id:keyword처럼 keyword 분석기를 지정한다.
+const exactAnalyzerName = "keyword_lowercase"
+
+// 외부 시스템의 문서 ID를 색인하는 키워드 필드 이름
+const externalIDField = "external_id"
+
+// 대소문자를 구분하지 않는 키워드 분석기를 매핑에 등록하는 함수
+func addExactAnalyzer(indexMapping *mapping.IndexMappingImpl) error {
+	err := indexMapping.AddCustomAnalyzer(exactAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []interface{}{lowercase.Name},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register keyword analyzer: %w", err)
+	}
+	return nil
+}
+
+// 정확히 일치하는 값으로 검색하는 필드의 매핑 (분석하지 않고 값 전체를 소문자로 색인)
+func newExactFieldMapping() *mapping.FieldMapping {
+	fieldMapping := bleve.NewKeywordFieldMapping()
+	fieldMapping.Analyzer = exactAnalyzerName
+	return fieldMapping
+}
+
+// 인덱스 매핑이 대소문자를 구분하지 않는 키워드 분석기를 쓰는지 확인하는 함수 (이전에 만든 인덱스면 false)
+func hasExactAnalyzer(m mapping.IndexMapping) bool {
+	impl, ok := m.(*mapping.IndexMappingImpl)
+	if !ok || impl.CustomAnalysis == nil {
+		return false
+	}
+	_, ok = impl.CustomAnalysis.Analyzers[exactAnalyzerName]
+	return ok
+}
+
+// filter_term 값(field:value)을 키워드 필드의 TermQuery로 바꾸는 함수
+// 필드가 매핑에 없으면 같은 이름의 메타데이터 키로 찾으며(status:published는 metadata.status), 값은 필드의 분석기로 정규화한다.
+func buildTermFilter(raw string, m mapping.IndexMapping) (query.Query, error) {
+	field, value, ok := strings.Cut(raw, ":")
+	if !ok || field == "" || value == "" {
+		return nil, fmt.Errorf("expected field:value, got '%s'", raw)
+	}
+	fields := mappedFields(m)
+	fieldMapping, ok := fields[field]
+	if !ok {
+		if fieldMapping, ok = fields[metadataField+"."+field]; ok {
+			field = metadataField + "." + field
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown field '%s'", field)
+	}
+	if fieldMapping.Type != "text" || (fieldMapping.Analyzer != keyword.Name && fieldMapping.Analyzer != exactAnalyzerName) {
+		return nil, fmt.Errorf("field '%s' is not a keyword field", field)
+	}
+
+	term := value
+	if analyzer := m.AnalyzerNamed(fieldMapping.Analyzer); analyzer != nil {
+		if tokens := analyzer.Analyze([]byte(value)); len(tokens) == 1 {
+			term = string(tokens[0].Term)
+		}
+	}
+	termQuery := bleve.NewTermQuery(term)
+	termQuery.SetField(field)
+	return termQuery, nil
+}
+
+// filter_term 파라미터들을 필터 목록으로 바꾸는 함수 (여러 번 지정하면 모두 일치해야 함)
+func buildTermFilters(values url.Values, m mapping.IndexMapping) ([]query.Query, error) {
+	var filters []query.Query
+	for _, raw := range values["filter_term"] {
+		filter, err := buildTermFilter(raw, m)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid query parameter 'filter_term': %v", err)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 만료 시각이 색인되는 필드
+const expiresAtField = "expires_at"
+
+// 종료 시 진행 중인 요청을 기다리는 최대 시간
+const shutdownTimeout = 10 * time.Second
+
+// 만료 문서 정리 주기 (EXPIRY_SWEEP_INTERVAL로 변경 가능)
+var expirySweepInterval = time.Minute
+
+// 검색 요청에 만료되지 않은 문서만 남기는 조건을 더한 복사본을 만드는 함수
+// 정리 작업이 돌기 전이라도 만료된 문서가 검색되지 않도록 모든 검색에 적용한다.
+// 만료 시각이 없는 문서는 범위 쿼리에 일치하지 않으므로 그대로 남는다.
+func excludeExpired(req *bleve.SearchRequest) *bleve.SearchRequest {
+	expired := bleve.NewDateRangeInclusiveQuery(time.Time{}, time.Now(), nil, &[]bool{true}[0])
+	expired.SetField(expiresAtField)
+
+	filtered := bleve.NewBooleanQuery()
+	filtered.AddMust(req.Query)
+	filtered.AddMustNot(expired)
+
+	copied := *req
+	copied.Query = filtered
+	return &copied
+}
+
+// 만료 문서를 주기적으로 정리하는 고루틴을 시작하는 함수
+// ctx가 취소되면 진행 중인 정리를 마치고 멈추며, 반환한 채널은 고루틴이 끝나면 닫힌다.
+func startExpirySweeper(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	// 읽기 전용 모드에서는 정리하지 않음 (쓰기 서버가 정리하고, 만료된 문서는 검색에서 제외됨)
+	// 데이터베이스 없이 실행해도 정리하지 않음 (만료된 문서는 검색에서 제외됨)
+	if readOnly || db == nil {
+		close(done)
+		return done
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// 처음 인덱스를 만드는 중이면 다음 주기에 정리 (만드는 인덱스는 만료 문서를 읽지 않음)
+				if indexUnavailable() != nil {
+					continue
+				}
+				purged, err := purgeExpiredDocuments()
+				if err != nil {
+					log.Printf("Failed to purge expired documents: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d expired documents", purged)
+				}
+			}
+		}
+	}()
+	return done
+}
+
+// 만료된 행을 Postgres에서 지우고 같은 문서를 인덱스(이름 붙은 인덱스의 문서는 그 인덱스)에서 지우는 함수
+// 인덱스 삭제가 실패하면 DB 삭제를 롤백해 다음 주기에 다시 시도한다.
+func purgeExpiredDocuments() (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("DELETE FROM documents WHERE expires_at <= now() RETURNING id, tenant")
+	if err != nil {
+		return 0, fmt.Errorf("Failed to delete expired documents: %w", err)
+	}
+	batch := index.NewBatch()
+	var ids []string
+	named := make(map[string][]string)
+	for rows.Next() {
+		var id int
+		var tenant *string
+		if err := rows.Scan(&id, &tenant); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("Failed to scan row: %w", err)
+		}
+		if tenant != nil {
+			named[*tenant] = append(named[*tenant], strconv.Itoa(id))
+			continue
+		}
+		ids = append(ids, strconv.Itoa(id))
+		batch.Delete(strconv.Itoa(id))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("Error iterating over rows: %w", err)
+	}
+	purged := len(ids)
+	for name, namedIDs := range named {
+		if err := namedIndexes.deleteDocuments(name, namedIDs); err != nil {
+			return 0, fmt.Errorf("Failed to delete expired documents from index '%s': %w", name, err)
+		}
+		purged += len(namedIDs)
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+
+	if batch.Size() > 0 {
+		if err := applyBatch(batch, nil, ids); err != nil {
+			return 0, fmt.Errorf("Failed to delete expired documents from index: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("Failed to commit delete: %w", err)
+	}
+	suggester.markDirty()
+	return purged, nil
+}
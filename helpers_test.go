@@ -0,0 +1,178 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// 메모리 인덱스를 전역 인덱스로 설정하는 함수 (INDEX_PATH=:memory:와 같이 디스크에 쓰지 않음)
+// 테스트가 끝나면 이전 인덱스로 되돌린다.
+func useMemoryIndex(t testing.TB) bleve.Index {
+	t.Helper()
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memIndex, err := bleve.NewMemOnly(indexMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := index
+	index = memIndex
+	t.Cleanup(func() {
+		index = previous
+		memIndex.Close()
+	})
+	return memIndex
+}
+
+// TEST_POSTGRES_CONN의 데이터베이스에 스키마를 적용하고 전역 DB로 설정하는 함수
+// 설정하지 않으면 테스트를 건너뛰며, 테스트마다 문서 관련 테이블을 비운다.
+func useTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	connStr := os.Getenv("TEST_POSTGRES_CONN")
+	if connStr == "" {
+		t.Skip("TEST_POSTGRES_CONN is not set")
+	}
+	testDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := os.ReadFile("postgres.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.Exec(string(schema)); err != nil {
+		t.Fatalf("Failed to apply schema: %v", err)
+	}
+	if _, err := testDB.Exec("TRUNCATE documents, document_tombstones, schema_migrations, analysis_cache RESTART IDENTITY"); err != nil {
+		t.Fatalf("Failed to reset tables: %v", err)
+	}
+	previous := db
+	db = testDB
+	t.Cleanup(func() {
+		db = previous
+		testDB.Close()
+	})
+	return testDB
+}
+
+// 문서를 ID 순서대로 1부터 ID를 붙여 색인하는 함수 (형태소 분석 결과는 원문으로 대신함)
+func indexTestDocuments(t testing.TB, target bleve.Index, docs ...documentBody) {
+	t.Helper()
+	for i, doc := range docs {
+		if err := target.Index(strconv.Itoa(i+1), doc.indexDocument(doc.Content, time.Now(), 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 핸들러에 요청을 보내고 상태 코드와 검색 결과의 문서 ID를 정렬해 반환하는 함수
+func searchIDs(t testing.TB, handler http.HandlerFunc, req *http.Request) (int, []string) {
+	t.Helper()
+	code, ids := rankedIDs(t, handler, req)
+	sort.Strings(ids)
+	return code, ids
+}
+
+// 핸들러에 요청을 보내고 상태 코드와 검색 결과의 문서 ID를 응답 순서대로 반환하는 함수
+func rankedIDs(t testing.TB, handler http.HandlerFunc, req *http.Request) (int, []string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	var response struct {
+		Hits []struct {
+			ID string `json:"id"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", rec.Body, err)
+	}
+	ids := []string{}
+	for _, hit := range response.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return rec.Code, ids
+}
+
+// 문서 하나를 지정한 ID로 전역 인덱스에 색인하는 함수
+func indexTestDocument(t testing.TB, id int, req *documentBody) {
+	t.Helper()
+	if err := index.Index(strconv.Itoa(id), req.indexDocument(req.Content, time.Now(), 1)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 전역 인덱스에 문서가 있는지 확인하는 함수
+func documentIndexed(t testing.TB, id int) bool {
+	t.Helper()
+	_, found, err := indexedContent(strconv.Itoa(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+// 항상 같은 형태소 분석 결과를 돌려주는 가짜 OpenAI API를 설정하는 함수 (반환값은 받은 요청 수)
+func useFakeOpenAI(t testing.TB) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: `{"tokens": ["분석", "결과"]}`},
+			}},
+		})
+	}))
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	previous := openaiClient
+	openaiClient = openai.NewClientWithConfig(config)
+	t.Cleanup(func() {
+		openaiClient = previous
+		server.Close()
+	})
+	return &calls
+}
+
+// 인덱스의 필드 텀 사전에 있는 모든 텀을 반환하는 함수
+func fieldTerms(t testing.TB, idx bleve.Index, field string) []string {
+	t.Helper()
+	dict, err := idx.FieldDict(field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dict.Close()
+	var terms []string
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil {
+			return terms
+		}
+		terms = append(terms, entry.Term)
+	}
+}
+
+// 값의 포인터를 반환하는 함수 (선택 필드가 있는 요청 본문을 만들 때 사용)
+func ptrTo[T any](value T) *T {
+	return &value
+}
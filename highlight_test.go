@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHighlightFragments(t *testing.T) {
+	korean := strings.Repeat("서울 시청 앞 광장에서 열린 행사에 많은 시민이 모였다. ", 20)
+	japanese := strings.Repeat("東京タワーの近くで開かれた祭りに多くの人が集まった。", 20)
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "ko", Content: korean},
+		documentBody{Title: "ja", Content: japanese},
+	)
+
+	for _, tt := range []struct {
+		name         string
+		q            string
+		fragmentSize int
+		numFragments int
+	}{
+		{"korean", "시민", 40, 2},
+		{"japanese", "東京", 25, 3},
+		{"korean default size", "광장", defaultFragmentSize, defaultNumFragments},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{"q": {tt.q}, "highlight": {"true"}}
+			if tt.fragmentSize != defaultFragmentSize {
+				params.Set("fragment_size", strconv.Itoa(tt.fragmentSize))
+				params.Set("num_fragments", strconv.Itoa(tt.numFragments))
+			}
+			rec := httptest.NewRecorder()
+			searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+params.Encode(), nil))
+			var response struct {
+				Hits []struct {
+					Fragments map[string][]string `json:"fragments"`
+				} `json:"hits"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Hits) != 1 {
+				t.Fatalf("search = %d %s, want one hit", rec.Code, rec.Body)
+			}
+
+			fragments := response.Hits[0].Fragments[defaultSearchField]
+			if len(fragments) == 0 || len(fragments) > tt.numFragments {
+				t.Fatalf("got %d fragments, want 1 to %d", len(fragments), tt.numFragments)
+			}
+			for _, fragment := range fragments {
+				if !utf8.ValidString(fragment) || strings.ContainsRune(fragment, utf8.RuneError) {
+					t.Errorf("fragment %q is not valid UTF-8", fragment)
+				}
+				if !strings.Contains(fragment, "<mark>") {
+					t.Errorf("fragment %q does not mark %q", fragment, tt.q)
+				}
+				// 조각 크기는 글자 수 기준이며, 하이라이트한 텀을 자르지 않으려고 경계가 조금 늘어날 수 있음
+				text := strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(fragment)
+				if n := utf8.RuneCountInString(text); n > tt.fragmentSize+utf8.RuneCountInString(tt.q) {
+					t.Errorf("fragment %q has %d characters, want about %d", fragment, n, tt.fragmentSize)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHighlightOptions(t *testing.T) {
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		query        string
+		fragmentSize int
+		numFragments int
+		wantErr      bool
+	}{
+		{"highlight=true", defaultFragmentSize, defaultNumFragments, false},
+		{"highlight=true&fragment_size=40&num_fragments=1", 40, 1, false},
+		{"highlight=true&fragment_size=0", 0, 0, true},
+		{"highlight=true&fragment_size=1001", 0, 0, true},
+		{"highlight=true&num_fragments=11", 0, 0, true},
+	} {
+		values, _ := url.ParseQuery(tt.query)
+		opts, err := parseHighlightOptions(values, indexMapping)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHighlightOptions(%s) error = %v, wantErr %t", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (opts.fragmentSize != tt.fragmentSize || opts.numFragments != tt.numFragments) {
+			t.Errorf("parseHighlightOptions(%s) = %d, %d, want %d, %d", tt.query, opts.fragmentSize, opts.numFragments, tt.fragmentSize, tt.numFragments)
+		}
+	}
+	if opts, err := parseHighlightOptions(url.Values{}, indexMapping); opts != nil || err != nil {
+		t.Errorf("parseHighlightOptions without highlight=true = %v, %v, want nil", opts, err)
+	}
+}
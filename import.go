@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 가져오기 배치 크기의 기본값과 최대값, 한 줄의 최대 길이
+const (
+	defaultImportBatchSize = 100
+	maxImportBatchSize     = maxBulkDocuments
+	maxImportLineSize      = 1 << 20
+)
+
+// 가져오기 중 실패한 줄
+type importLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// 배치마다 스트리밍하는 진행 결과 (마지막 줄은 done=true인 전체 요약)
+type importProgress struct {
+	Batch    int `json:"batch,omitempty"`
+	Inserted int `json:"inserted"`
+	// 내용이 같은 문서가 이미 있어 추가하지 않은 문서 수
+	Duplicates int               `json:"duplicates,omitempty"`
+	Failed     int               `json:"failed"`
+	Errors     []importLineError `json:"errors,omitempty"`
+	Done       bool              `json:"done,omitempty"`
+	Aborted    bool              `json:"aborted,omitempty"`
+	TookMS     int64             `json:"took_ms,omitempty"`
+}
+
+// NDJSON 가져오기 핸들러 (POST /import/ndjson?batch_size=100&strict=true)
+// 본문을 한 줄씩 읽어 batch_size개마다 분석, 저장, 색인하고 배치 결과를 한 줄씩 응답한다.
+// 잘못된 줄은 줄 번호와 함께 보고하고 건너뛰며, strict=true이면 그 줄에서 가져오기를 중단한다.
+// 본문 전체를 메모리에 올리지 않도록 한 번에 한 배치만 보관한다.
+func importNDJSONHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	values := r.URL.Query()
+	batchSize, err := parseIntParam(values, "batch_size", defaultImportBatchSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if batchSize == 0 || batchSize > maxImportBatchSize {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'batch_size': must be between 1 and %d", maxImportBatchSize), http.StatusBadRequest)
+		return
+	}
+	strict := values.Get("strict") == "true"
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	importer := newBatchImporter(r.Context(), w, batchSize)
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	line := 0
+	for scanner.Scan() {
+		if requestCancelled(r) {
+			return
+		}
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		req, err := decodeDocumentBody(bytes.NewReader(text))
+		if err == nil && strings.TrimSpace(req.Content) == "" {
+			err = fmt.Errorf("Invalid request body: content is required")
+		}
+		if err != nil {
+			if strict {
+				importer.reject(line, err.Error())
+				importer.abort()
+				return
+			}
+			importer.skip(line, err.Error())
+			continue
+		}
+		importer.add(line, req)
+	}
+	if requestCancelled(r) {
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		importer.reject(line+1, fmt.Sprintf("Failed to read request body: %v", err))
+		importer.abort()
+		return
+	}
+	importer.finish()
+}
+
+// 문서를 배치 단위로 모아 저장하고 진행 결과를 스트리밍하는 가져오기 도구
+type batchImporter struct {
+	ctx       context.Context
+	encoder   *json.Encoder
+	flusher   http.Flusher
+	batchSize int
+	start     time.Time
+
+	lines   []int
+	reqs    []*documentBody
+	pending importProgress
+	total   importProgress
+}
+
+func newBatchImporter(ctx context.Context, w http.ResponseWriter, batchSize int) *batchImporter {
+	flusher, _ := w.(http.Flusher)
+	return &batchImporter{ctx: ctx, encoder: json.NewEncoder(w), flusher: flusher, batchSize: batchSize, start: time.Now()}
+}
+
+// 검증된 문서를 현재 배치에 추가하고 배치가 차면 저장하는 함수
+func (b *batchImporter) add(line int, req *documentBody) {
+	b.lines = append(b.lines, line)
+	b.reqs = append(b.reqs, req)
+	b.flushIfFull()
+}
+
+// 잘못된 줄을 건너뛰는 함수 (잘못된 줄도 배치 크기에 포함해 오류 목록이 계속 커지지 않게 함)
+func (b *batchImporter) skip(line int, message string) {
+	b.reject(line, message)
+	b.flushIfFull()
+}
+
+func (b *batchImporter) flushIfFull() {
+	if len(b.reqs)+len(b.pending.Errors) >= b.batchSize {
+		b.flush()
+	}
+}
+
+// 검증에 실패한 줄을 현재 배치 결과에 기록하는 함수
+func (b *batchImporter) reject(line int, message string) {
+	b.pending.Failed++
+	b.pending.Errors = append(b.pending.Errors, importLineError{Line: line, Error: message})
+}
+
+// 현재 배치를 저장하고 결과 한 줄을 응답하는 함수
+func (b *batchImporter) flush() {
+	if len(b.reqs) > 0 {
+		items := make([]bulkItem, len(b.reqs))
+		insertDocuments(b.ctx, b.reqs, items, false)
+		for i, item := range items {
+			switch {
+			case item.Result == "duplicate":
+				b.pending.Duplicates++
+			case item.ID != nil:
+				b.pending.Inserted++
+			default:
+				b.reject(b.lines[i], item.Error)
+			}
+		}
+	}
+	if b.pending.Inserted == 0 && b.pending.Duplicates == 0 && b.pending.Failed == 0 {
+		return
+	}
+
+	b.total.Batch++
+	b.pending.Batch = b.total.Batch
+	b.total.Inserted += b.pending.Inserted
+	b.total.Duplicates += b.pending.Duplicates
+	b.total.Failed += b.pending.Failed
+	b.write(b.pending)
+	b.lines, b.reqs, b.pending = b.lines[:0], b.reqs[:0], importProgress{}
+}
+
+// 남은 배치를 저장하고 전체 요약을 응답하는 함수
+func (b *batchImporter) finish() {
+	b.flush()
+	b.total.Done = true
+	b.total.TookMS = time.Since(b.start).Milliseconds()
+	b.total.Batch = 0
+	b.write(b.total)
+}
+
+// strict 모드에서 잘못된 줄을 만났을 때 저장하지 않은 배치를 버리고 중단하는 함수
+// 이전 배치까지 저장된 문서는 그대로 남는다.
+func (b *batchImporter) abort() {
+	b.total.Failed += b.pending.Failed
+	b.total.Errors = b.pending.Errors
+	b.total.Done = true
+	b.total.Aborted = true
+	b.total.TookMS = time.Since(b.start).Milliseconds()
+	b.total.Batch = 0
+	b.write(b.total)
+}
+
+func (b *batchImporter) write(progress importProgress) {
+	b.encoder.Encode(progress)
+	if b.flusher != nil {
+		b.flusher.Flush()
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 손상된 인덱스 디렉터리를 옮겨 두는 이름의 접두사 (뒤에 옮긴 시각이 붙음, 자동으로 지우지 않음)
+const corruptIndexDirPrefix = legacyIndexDir + ".corrupt-"
+
+// 인덱스 검증에서 확인하는 문서 수의 기본값과 최대값
+const (
+	defaultVerifySample = 100
+	maxVerifySample     = 1000
+)
+
+// 손상된 인덱스를 데이터베이스에서 자동으로 다시 만들지 (AUTO_REBUILD=false로 끔)
+var autoRebuild = true
+
+// 현재 인덱스 디렉터리를 열 수 없음을 나타내는 오류 (비정상 종료 등으로 손상된 인덱스)
+type corruptIndexError struct {
+	path string
+	err  error
+}
+
+func (e *corruptIndexError) Error() string {
+	return fmt.Sprintf("Failed to open index %s: %v", e.path, e.err)
+}
+
+func (e *corruptIndexError) Unwrap() error {
+	return e.err
+}
+
+// 시작할 때 열 수 없는 인덱스를 복구하는 함수 (빈 별칭을 반환하면 호출 측에서 데이터베이스로 다시 만듦)
+// PostgreSQL에 연결할 수 있을 때만 손상된 디렉터리를 옆으로 옮기므로, 다시 만들 수 없으면 인덱스를 그대로 두고 오류를 반환한다.
+func recoverCorruptIndex(corrupt *corruptIndexError) (*swappableIndex, error) {
+	if !autoRebuild {
+		return nil, fmt.Errorf("%v. AUTO_REBUILD=false, so it was left in place: restore a backup, or move %s aside and restart to rebuild it from the database", corrupt, corrupt.path)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("%v. It cannot be rebuilt because PostgreSQL is unreachable: %v", corrupt, err)
+	}
+
+	moved, err := quarantineIndex(corrupt.path)
+	if err != nil {
+		return nil, err
+	}
+	if moved != "" {
+		log.Printf("WARNING: %v. Moved it to %s and rebuilding the index from the database.", corrupt, moved)
+	} else {
+		log.Printf("WARNING: %v. The directory is missing, rebuilding the index from the database.", corrupt)
+	}
+	return openSwappableIndex()
+}
+
+// 손상된 인덱스 디렉터리를 .index.corrupt-{시각}으로 옮기고 현재 인덱스 기록을 지우는 함수 (옮긴 경로를 반환, 디렉터리가 없으면 빈 문자열)
+func quarantineIndex(path string) (string, error) {
+	moved := filepath.Join(indexDir, corruptIndexDirPrefix+time.Now().UTC().Format(indexDirTimeFormat))
+	if err := os.Rename(path, moved); errors.Is(err, os.ErrNotExist) {
+		moved = ""
+	} else if err != nil {
+		return "", fmt.Errorf("Failed to move corrupt index aside: %w", err)
+	}
+	if err := os.Remove(filepath.Join(indexDir, currentIndexFile)); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("Failed to reset current index: %w", err)
+	}
+	return moved, nil
+}
+
+// 인덱스 검증 응답
+type verifyResult struct {
+	// 인덱스와 Postgres의 문서 수가 같고 확인한 문서가 모두 인덱스에 있는지
+	OK                bool   `json:"ok"`
+	IndexDocuments    uint64 `json:"index_documents"`
+	PostgresDocuments uint64 `json:"postgres_documents"`
+	Divergence        int64  `json:"divergence"`
+	// 무작위로 골라 확인한 문서 수와 그중 인덱스에 없는 문서 ID
+	Sampled int      `json:"sampled"`
+	Missing []string `json:"missing"`
+}
+
+// 인덱스 검증 핸들러 (GET /admin/verify?sample=100)
+// 인덱스의 문서 수를 색인해야 할 Postgres 행 수와 비교하고, 행을 무작위로 sample개 골라 인덱스에 있는지 확인한다.
+func verifyHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	sample, err := parseIntParam(r.URL.Query(), "sample", defaultVerifySample)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sample > maxVerifySample {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'sample': must be at most %d", maxVerifySample), http.StatusBadRequest)
+		return
+	}
+
+	result, err := verifyIndex(r.Context(), sample)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// 인덱스와 Postgres의 문서 수를 비교하고 무작위로 고른 문서가 인덱스에 있는지 확인하는 함수
+func verifyIndex(ctx context.Context, sample int) (verifyResult, error) {
+	result := verifyResult{Missing: []string{}}
+	var err error
+	if result.IndexDocuments, err = index.DocCount(); err != nil {
+		return result, fmt.Errorf("Failed to count indexed documents: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents WHERE "+indexableDocumentsFilter).Scan(&result.PostgresDocuments); err != nil {
+		return result, fmt.Errorf("Failed to count documents: %w", err)
+	}
+	result.Divergence = int64(result.PostgresDocuments) - int64(result.IndexDocuments)
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM documents WHERE "+indexableDocumentsFilter+" ORDER BY random() LIMIT $1", sample)
+	if err != nil {
+		return result, fmt.Errorf("Failed to sample documents: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return result, fmt.Errorf("Failed to scan document: %w", err)
+		}
+		doc, err := index.Document(strconv.Itoa(id))
+		if err != nil {
+			return result, fmt.Errorf("Failed to read indexed document %d: %w", id, err)
+		}
+		result.Sampled++
+		if doc == nil {
+			result.Missing = append(result.Missing, strconv.Itoa(id))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("Failed to sample documents: %w", err)
+	}
+	result.OK = result.Divergence == 0 && len(result.Missing) == 0
+	return result, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// TEXT_ANALYZER에 쓰는 본문 분석기 이름
+const (
+	textAnalyzerKorean = "korean"
+	textAnalyzerCJK    = "cjk"
+)
+
+// 한국어 분석기 구성 요소 이름 (인덱스 매핑에 저장됨)
+const (
+	koreanAnalyzerName       = "korean_morpheme"
+	koreanParticleFilterName = "korean_particle_filter"
+	koreanParticleFilterType = "korean_particles"
+)
+
+// 제목과 본문에 사용할 분석기 (TEXT_ANALYZER로 변경, 변경 시 인덱스를 다시 만들어야 적용됨)
+var textAnalyzerSetting = textAnalyzerKorean
+
+// 한국어 분석기가 토큰 끝에서 떼어 내는 조사 목록 (KOREAN_PARTICLES로 변경, 비워 두면 떼어 내지 않음)
+var koreanParticles = []string{
+	"가", "과", "까지", "께서", "나", "는", "도", "랑", "로", "를", "마다", "만", "보다", "부터",
+	"에", "에게", "에서", "와", "으로", "은", "을", "의", "이", "이나", "이랑", "처럼", "하고", "한테",
+}
+
+func init() {
+	registry.RegisterTokenFilter(koreanParticleFilterType, koreanParticleFilterConstructor)
+}
+
+// 토큰 끝의 조사를 떼어 내는 필터
+// 가장 긴 조사부터 비교하며, 조사를 뗀 뒤 한 글자 이상 남는 한글 토큰만 바꾼다.
+// 색인과 검색어에 같은 필터가 적용되므로 "김치를"로 검색해도 "김치가"가 들어 있는 문서와 매칭된다.
+type koreanParticleFilter struct {
+	particles []string
+}
+
+func koreanParticleFilterConstructor(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+	raw, _ := config["particles"].([]interface{})
+	particles := make([]string, 0, len(raw))
+	for _, value := range raw {
+		particle, ok := value.(string)
+		if !ok || particle == "" {
+			return nil, errors.New("particles must be a list of non-empty strings")
+		}
+		particles = append(particles, particle)
+	}
+	sort.SliceStable(particles, func(i, j int) bool {
+		return utf8.RuneCountInString(particles[i]) > utf8.RuneCountInString(particles[j])
+	})
+	return &koreanParticleFilter{particles: particles}, nil
+}
+
+func (f *koreanParticleFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	for _, token := range input {
+		term := string(token.Term)
+		for _, particle := range f.particles {
+			stem, ok := strings.CutSuffix(term, particle)
+			if ok && stem != "" && isHangul(stem) {
+				token.Term = []byte(stem)
+				break
+			}
+		}
+	}
+	return input
+}
+
+// 한글 음절로만 이루어진 문자열인지 확인하는 함수
+func isHangul(s string) bool {
+	for _, r := range s {
+		if r < 0xAC00 || r > 0xD7A3 {
+			return false
+		}
+	}
+	return true
+}
+
+// TEXT_ANALYZER 값을 확인하는 함수
+func parseTextAnalyzer(value string) (string, error) {
+	if value != textAnalyzerKorean && value != textAnalyzerCJK {
+		return "", errors.New("must be korean or cjk")
+	}
+	return value, nil
+}
+
+// 제목과 본문에 사용할 분석기를 매핑에 등록하고 그 이름을 반환하는 함수
+// korean은 형태소 분석 결과와 원문을 공백과 문장 부호에서 나누고(바이그램 없음) 전각/반각과 소문자를 정규화한 뒤 조사를 떼어 낸다.
+// cjk는 bleve의 CJK 분석기(한글 어절, 한자/가나 바이그램)를 사용한다. 두 분석기 모두 STOPWORDS_PATH의 불용어를 제거한다.
+func addTextAnalyzer(indexMapping *mapping.IndexMappingImpl) (string, error) {
+	if textAnalyzerSetting == textAnalyzerCJK {
+		if len(stopWords) == 0 {
+			return cjk.AnalyzerName, nil
+		}
+		if err := addStopWordAnalyzer(indexMapping, stopWords); err != nil {
+			return "", err
+		}
+		return stopWordAnalyzerName, nil
+	}
+
+	filters := []interface{}{cjk.WidthName, lowercase.Name}
+	if len(koreanParticles) > 0 {
+		particles := make([]interface{}, len(koreanParticles))
+		for i, particle := range koreanParticles {
+			particles[i] = particle
+		}
+		err := indexMapping.AddCustomTokenFilter(koreanParticleFilterName, map[string]interface{}{
+			"type":      koreanParticleFilterType,
+			"particles": particles,
+		})
+		if err != nil {
+			return "", fmt.Errorf("Failed to register particle filter: %w", err)
+		}
+		filters = append(filters, koreanParticleFilterName)
+	}
+	if len(stopWords) > 0 {
+		if err := addStopWordFilter(indexMapping, stopWords); err != nil {
+			return "", err
+		}
+		filters = append(filters, stopWordsFilterName)
+	}
+	err := indexMapping.AddCustomAnalyzer(koreanAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": filters,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to register Korean analyzer: %w", err)
+	}
+	return koreanAnalyzerName, nil
+}
+
+// 인덱스 매핑에 저장된 조사 목록을 반환하는 함수
+func indexedParticles(m mapping.IndexMapping) []string {
+	impl, ok := m.(*mapping.IndexMappingImpl)
+	if !ok || impl.CustomAnalysis == nil {
+		return nil
+	}
+	values, _ := impl.CustomAnalysis.TokenFilters[koreanParticleFilterName]["particles"].([]interface{})
+
+	var particles []string
+	for _, value := range values {
+		if particle, ok := value.(string); ok {
+			particles = append(particles, particle)
+		}
+	}
+	slices.Sort(particles)
+	return particles
+}
+
+// 인덱스의 본문 분석기나 조사 목록이 현재 설정으로 만드는 매핑과 다른지 확인하는 함수
+func textAnalyzerChanged(m mapping.IndexMapping) bool {
+	expected, err := buildIndexMapping()
+	if err != nil {
+		return false
+	}
+	content, ok := mappedFields(m)["content"]
+	if !ok {
+		return false
+	}
+	if content.Analyzer != mappedFields(expected)["content"].Analyzer {
+		return true
+	}
+	return !slices.Equal(indexedParticles(m), indexedParticles(expected))
+}
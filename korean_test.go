@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKoreanAnalyzer(t *testing.T) {
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := indexMapping.AnalyzerNamed(koreanAnalyzerName)
+	if analyzer == nil {
+		t.Fatalf("analyzer %s is not registered", koreanAnalyzerName)
+	}
+
+	for _, tt := range []struct {
+		text string
+		want []string
+	}{
+		// 형태소 분석 결과는 공백과 문장 부호에서 나누고 바이그램으로 자르지 않음
+		{"형태소 분석기, 검색엔진.", []string{"형태소", "분석기", "검색엔진"}},
+		{"김치를 담그는 방법", []string{"김치", "담그", "방법"}},
+		// 가장 긴 조사부터 떼어 냄
+		{"서울에서 부산으로", []string{"서울", "부산"}},
+		// 조사만 있거나 한글이 아닌 어간은 그대로
+		{"에서 ABC는 이", []string{"에서", "abc는", "이"}},
+		// 전각/반각과 대소문자 정규화
+		{"Ｓｅｏｕｌ TOWER", []string{"seoul", "tower"}},
+	} {
+		var terms []string
+		for _, token := range analyzer.Analyze([]byte(tt.text)) {
+			terms = append(terms, string(token.Term))
+		}
+		if !reflect.DeepEqual(terms, tt.want) {
+			t.Errorf("Analyze(%q) = %q, want %q", tt.text, terms, tt.want)
+		}
+	}
+}
+
+func TestKoreanAnalyzerRelevance(t *testing.T) {
+	defer func(setting string) { textAnalyzerSetting = setting }(textAnalyzerSetting)
+	corpus := []documentBody{
+		{Title: "1", Content: "김치를 담그는 방법"},
+		{Title: "2", Content: "김치찌개 끓이는 법"},
+		{Title: "3", Content: "서울에서 부산으로 가는 기차"},
+		{Title: "4", Content: "부산 해운대 바다"},
+		{Title: "5", Content: "치킨과 맥주"},
+		{Title: "6", Content: "국어 사전"},
+	}
+
+	for _, tt := range []struct {
+		q      string
+		korean []string
+		cjk    []string
+	}{
+		// 조사가 붙은 어절도 조사를 뗀 검색어와 일치 (cjk는 어절 전체가 같아야 함)
+		{"김치", []string{"1"}, []string{}},
+		{"김치가", []string{"1"}, []string{}},
+		{"부산", []string{"3", "4"}, []string{"4"}},
+		{"서울로", []string{"3"}, []string{}},
+		{"치킨", []string{"5"}, []string{}},
+		// 조사를 떼도 다른 어절의 일부와는 일치하지 않음
+		{"국어", []string{"6"}, []string{"6"}},
+		{"찌개", []string{}, []string{}},
+	} {
+		for setting, want := range map[string][]string{textAnalyzerKorean: tt.korean, textAnalyzerCJK: tt.cjk} {
+			t.Run(setting+" "+tt.q, func(t *testing.T) {
+				textAnalyzerSetting = setting
+				memIndex := useMemoryIndex(t)
+				for i, doc := range corpus {
+					// 형태소 분석 결과 없이 색인해 본문 분석기만 비교
+					if err := memIndex.Index(strconv.Itoa(i+1), doc.indexDocument("", time.Now(), 1)); err != nil {
+						t.Fatal(err)
+					}
+				}
+				_, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {tt.q}}.Encode(), nil))
+				if !reflect.DeepEqual(ids, want) {
+					t.Errorf("search %s = %v, want %v", tt.q, ids, want)
+				}
+			})
+		}
+	}
+
+	// 기본 필드(_all)도 본문 분석기로 검색어를 분석하므로 조사가 붙은 검색어는 뗀 검색어와 점수와 하이라이트가 같음
+	textAnalyzerSetting = textAnalyzerKorean
+	indexTestDocuments(t, useMemoryIndex(t), corpus...)
+	search := func(q string) string {
+		rec := httptest.NewRecorder()
+		searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {q}, "highlight": {"true"}}.Encode(), nil))
+		var response struct {
+			Hits []json.RawMessage `json:"hits"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Hits) != 1 {
+			t.Fatalf("search %s = %d %s, want one hit", q, rec.Code, rec.Body)
+		}
+		return string(response.Hits[0])
+	}
+	if stripped, withParticle := search("김치"), search("김치가"); stripped != withParticle {
+		t.Errorf("search 김치가 hit = %s, want the same as 김치 %s", withParticle, stripped)
+	}
+}
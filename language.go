@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// 감지한 언어를 색인하는 키워드 필드 이름
+const languageField = "lang"
+
+// 감지하는 언어 (lang 필드와 lang= 파라미터 값)
+const (
+	languageKorean   = "ko"
+	languageEnglish  = "en"
+	languageJapanese = "ja"
+)
+
+// 언어별 본문 필드를 만드는 언어 목록
+var languages = []string{languageKorean, languageEnglish, languageJapanese}
+
+// 언어별 본문이 색인되는 필드 이름 (예: content_en)
+func languageContentField(lang string) string {
+	return defaultSearchField + "_" + lang
+}
+
+// 본문의 언어를 문자 종류로 감지하는 함수 (글자가 없으면 빈 문자열)
+// 한글 음절과 한자/가나 한 글자는 라틴 문자 세 글자 정도의 정보량으로 보고 가장 많은 쪽을 고르며,
+// 가나 없이 한자만 있는 본문은 바이그램으로 색인하도록 일본어로 분류한다.
+func detectLanguage(text string) string {
+	var hangul, japanese, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			japanese++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	switch {
+	case hangul == 0 && japanese == 0 && latin == 0:
+		return ""
+	case hangul*3 >= japanese*3 && hangul*3 >= latin:
+		return languageKorean
+	case japanese*3 >= latin:
+		return languageJapanese
+	default:
+		return languageEnglish
+	}
+}
+
+// 색인할 문서에 감지한 언어와 그 언어의 본문 필드를 채우는 함수
+func (doc *indexDocument) setLanguage() {
+	doc.Language = detectLanguage(doc.Content)
+	switch doc.Language {
+	case languageKorean:
+		doc.ContentKo = doc.Content
+	case languageEnglish:
+		doc.ContentEn = doc.Content
+	case languageJapanese:
+		doc.ContentJa = doc.Content
+	}
+}
+
+// 언어 필드와 언어별 본문 필드를 문서 매핑에 추가하는 함수
+// 한국어는 본문 분석기, 영어는 어간 추출을 하는 영어 분석기, 일본어는 CJK 바이그램 분석기로 색인한다.
+// 언어별 본문은 content와 같은 내용이므로 저장하지 않고 _all 필드에도 넣지 않는다.
+func addLanguageMapping(docMapping *mapping.DocumentMapping, textAnalyzer string) {
+	languageFieldMapping := bleve.NewKeywordFieldMapping()
+	languageFieldMapping.Store = true
+	docMapping.AddFieldMappingsAt(languageField, languageFieldMapping)
+
+	analyzers := map[string]string{
+		languageKorean:   textAnalyzer,
+		languageEnglish:  en.AnalyzerName,
+		languageJapanese: cjk.AnalyzerName,
+	}
+	for _, lang := range languages {
+		contentFieldMapping := bleve.NewTextFieldMapping()
+		contentFieldMapping.Analyzer = analyzers[lang]
+		contentFieldMapping.Store = false
+		contentFieldMapping.IncludeInAll = false
+		contentFieldMapping.IncludeTermVectors = termVectors
+		docMapping.AddFieldMappingsAt(languageContentField(lang), contentFieldMapping)
+	}
+}
+
+// lang 파라미터를 확인하는 함수 (없으면 빈 문자열)
+func parseLanguageParam(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, lang := range languages {
+		if value == lang {
+			return lang, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid query parameter 'lang': must be ko, en or ja")
+}
+
+// 인덱스 매핑에 있는 언어별 본문 필드 목록 (언어 감지 이전에 만든 인덱스면 빈 목록)
+func languageContentFields(m mapping.IndexMapping) []string {
+	known := mappedFields(m)
+	var fields []string
+	for _, lang := range languages {
+		if _, ok := known[languageContentField(lang)]; ok {
+			fields = append(fields, languageContentField(lang))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// 형태소 분석을 수행할 곳 (ANALYSIS_PROVIDER로 변경)
+// openai는 OpenAI API로 분석하고 재시도 후에도 실패하면 로컬 분석기로 대신 분석하며, local은 처음부터 로컬 분석기로만 분석한다.
+const (
+	analysisProviderOpenAI = "openai"
+	analysisProviderLocal  = "local"
+)
+
+var analysisProvider = analysisProviderOpenAI
+
+// 형태소 분석 모드 (ANALYSIS_MODE로 변경)
+// llm은 analysisProvider로 분석하고, none은 분석하지 않아 bleve의 본문 분석기만으로 검색하며 OpenAI API 키가 필요 없다.
+const (
+	analysisModeLLM  = "llm"
+	analysisModeNone = "none"
+)
+
+var analysisMode = analysisModeLLM
+
+// OpenAI API로 형태소 분석을 요청하는 설정인지
+func usesOpenAI() bool {
+	return analysisMode == analysisModeLLM && analysisProvider == analysisProviderOpenAI
+}
+
+// 로컬 형태소 분석에 쓰는 bleve의 CJK 분석기 (한글 어절, 한자/가나 바이그램)
+var localAnalyzer = sync.OnceValues(func() (analysis.Analyzer, error) {
+	return registry.NewCache().AnalyzerNamed(cjk.AnalyzerName)
+})
+
+// bleve의 CJK 분석기로 형태소 분석을 대신하는 함수 (토큰을 나온 순서대로 반환)
+func localMorphologicalAnalysis(text string) ([]string, error) {
+	analyzer, err := localAnalyzer()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load local analyzer: %w", err)
+	}
+	stream := analyzer.Analyze([]byte(text))
+	tokens := make([]string, len(stream))
+	for i, token := range stream {
+		tokens[i] = string(token.Term)
+	}
+	return tokens, nil
+}
+
+// 설정한 곳에서 형태소 분석을 수행하는 함수 (분석한 곳과 캐시에서 찾았는지를 함께 반환)
+// OpenAI API가 실패하거나 analysisTimeout 안에 끝나지 않으면 로컬 분석기로 분석하지만, ctx가 끝나서 실패했으면 대신 분석하지 않고 오류를 반환한다.
+// ANALYSIS_MODE=none이면 분석하지 않고 토큰 없이 none을 반환한다.
+func analyzeWithFallback(ctx context.Context, text string) ([]string, string, bool, error) {
+	if analysisMode == analysisModeNone {
+		return nil, analysisSourceNone, false, nil
+	}
+	if analysisProvider == analysisProviderLocal {
+		tokens, err := localMorphologicalAnalysis(text)
+		return tokens, analysisSourceLocal, false, err
+	}
+	analysisCtx := ctx
+	if analysisTimeout > 0 {
+		var cancel context.CancelFunc
+		analysisCtx, cancel = context.WithTimeout(ctx, analysisTimeout)
+		defer cancel()
+	}
+	tokens, cacheHit, err := analyzeContent(analysisCtx, text)
+	if err == nil {
+		return tokens, analysisSourceOpenAI, cacheHit, nil
+	}
+	if ctx.Err() != nil {
+		return nil, "", false, err
+	}
+	log.Printf("OpenAI analysis failed, using the local analyzer instead: %v", err)
+	tokens, err = localMorphologicalAnalysis(text)
+	return tokens, analysisSourceLocal, false, err
+}
+
+// 로컬 분석기로 분석한 문서 목록의 항목
+type localAnalysisDocument struct {
+	ID int `json:"id"`
+	// 이름 붙은 인덱스의 문서면 인덱스 이름
+	Index   *string `json:"index,omitempty"`
+	Title   string  `json:"title"`
+	Preview string  `json:"preview"`
+}
+
+// 로컬 분석기로 분석한 문서 목록 핸들러 (GET /admin/local-analysis?limit=50&offset=0)
+// OpenAI 장애로 로컬 분석 결과가 저장된 문서를 나열해, API가 복구된 뒤 다시 분석할 문서를 찾을 수 있게 한다.
+func localAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	limit, err := parseIntParam(values, "limit", defaultListLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit == 0 || limit > maxListLimit {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'limit': must be between 1 and %d", maxListLimit), http.StatusBadRequest)
+		return
+	}
+	offset, err := parseIntParam(values, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var total int
+	if err := db.QueryRowContext(r.Context(), "SELECT count(*) FROM documents WHERE analysis_source = $1 AND analysis IS NOT NULL AND deleted_at IS NULL", analysisSourceLocal).Scan(&total); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	rows, err := db.QueryContext(r.Context(), "SELECT id, tenant, COALESCE(title, ''), content FROM documents WHERE analysis_source = $1 AND analysis IS NOT NULL AND deleted_at IS NULL ORDER BY id LIMIT $2 OFFSET $3", analysisSourceLocal, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	documents := []localAnalysisDocument{}
+	for rows.Next() {
+		var doc localAnalysisDocument
+		var content string
+		if err := rows.Scan(&doc.ID, &doc.Index, &doc.Title, &content); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scan row: %v", err), http.StatusInternalServerError)
+			return
+		}
+		doc.Preview = previewText(content, listPreviewLength)
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Error iterating over rows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"documents": documents,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalysisModeNone(t *testing.T) {
+	defer func(mode string) { analysisMode = mode }(analysisMode)
+	analysisMode = analysisModeNone
+	calls := useFakeOpenAI(t)
+	useNoDatabase(t)
+	useMemoryIndex(t)
+
+	tokens, source, cacheHit, err := analyzeWithFallback(context.Background(), "서울 시청 호텔")
+	if err != nil || tokens != nil || source != analysisSourceNone || cacheHit {
+		t.Errorf("analyzeWithFallback = %v, %s, %t, %v, want no tokens from %s", tokens, source, cacheHit, err, analysisSourceNone)
+	}
+
+	// 분석 없이 원문만 색인해도 본문 분석기로 검색됨
+	rec := httptest.NewRecorder()
+	insertHandler(rec, httptest.NewRequest(http.MethodPost, "/insert", strings.NewReader(`{"title": "호텔", "content": "서울 시청 호텔"}`)))
+	var inserted insertResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &inserted); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("POST /insert = %d %s, want 201", rec.Code, rec.Body)
+	}
+	if inserted.AnalysisSource != analysisSourceNone || inserted.AnalysisModel != "" || len(inserted.Tokens) != 0 {
+		t.Errorf("insert response = %+v, want analysis source %s without a model or tokens", inserted, analysisSourceNone)
+	}
+	if _, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {"시청"}}.Encode(), nil)); !reflect.DeepEqual(ids, []string{"1"}) {
+		t.Errorf("search = %v, want the inserted document", ids)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Errorf("made %d OpenAI calls with ANALYSIS_MODE=none, want none", got)
+	}
+
+	// 상태 확인과 통계가 분석 모드를 알림
+	for _, handler := range []http.HandlerFunc{heartbeatHandler, statsHandler} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var response struct {
+			AnalysisMode string `json:"analysis_mode"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.AnalysisMode != analysisModeNone {
+			t.Errorf("response %s has analysis_mode %q, want %s", rec.Body, response.AnalysisMode, analysisModeNone)
+		}
+	}
+}
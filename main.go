@@ -4,16 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/blevesearch/bleve/v2"
-	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -21,6 +33,87 @@ var index bleve.Index
 var db *sql.DB
 var openaiClient *openai.Client
 
+// 검색 결과 페이지 크기 기본값과 최대값
+const (
+	defaultSearchSize = 10
+	maxSearchSize     = 100
+)
+
+// min_score 적용 시 한 번에 가져와 걸러낼 최대 히트 수
+const minScoreWindow = 1000
+
+// dedupe_field 적용 시 요청한 범위보다 더 가져올 배수
+const dedupeOverfetch = 3
+
+// 검색 시간 제한의 최대값 (기본값은 SEARCH_TIMEOUT_MS로 변경 가능)
+const maxSearchTimeout = 30 * time.Second
+
+var defaultSearchTimeout = 2 * time.Second
+
+// 검색이 시간 제한 안에 끝나지 않았음을 나타내는 오류
+var errSearchTimeout = errors.New("query timed out")
+
+// 패싯 버킷 수 기본값과 최대값
+const (
+	defaultFacetSize = 10
+	maxFacetSize     = 100
+)
+
+// 필드를 지정하지 않은 검색과 하이라이트의 기본 대상 필드
+const defaultSearchField = "content"
+
+// 인덱스에 저장되는 문서 구조체
+type indexDocument struct {
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Analysis  string    `json:"analysis"`
+	Tags      []string  `json:"tags"`
+	Price     *float64  `json:"price"`
+	CreatedAt time.Time `json:"created_at"`
+	Location  *geoPoint `json:"location"`
+	// METADATA_FIELDS로 지정한 메타데이터 키만 포함
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Version  int                    `json:"version"`
+	// 만료 시각 (없으면 만료되지 않음)
+	ExpiresAt *time.Time `json:"expires_at"`
+	// 본문에서 감지한 언어와 그 언어의 분석기로 색인하는 본문 (감지한 언어의 필드만 채워짐)
+	Language  string `json:"lang,omitempty"`
+	ContentKo string `json:"content_ko,omitempty"`
+	ContentEn string `json:"content_en,omitempty"`
+	ContentJa string `json:"content_ja,omitempty"`
+	// 외부 시스템의 문서 ID (없으면 색인하지 않음)
+	ExternalID *string `json:"external_id,omitempty"`
+	// 문서 종류 (bleve의 Classifier가 쓰는 Type 메서드와 이름이 겹치지 않도록 DocType)
+	DocType string `json:"type"`
+	// 문서 가중치 (1이면 색인하지 않음)
+	Boost *float64 `json:"boost,omitempty"`
+}
+
+// 위치 좌표 (좌표가 없는 문서는 nil)
+type geoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// 위치 좌표를 색인하는 geopoint 필드 이름
+const locationField = "location"
+
+// 검색 응답 구조체 (페이지 정보 포함)
+type searchResponse struct {
+	*bleve.SearchResult
+	From        int      `json:"from"`
+	Size        int      `json:"size"`
+	QueryType   string   `json:"query_type"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	// min_score로 걸러내기 전의 전체 히트 수
+	UnfilteredTotal *uint64 `json:"unfiltered_total_hits,omitempty"`
+	// dedupe_field로 합쳐져 제외된 히트 수
+	CollapsedHits *int `json:"collapsed_hits,omitempty"`
+	// collapse 요청 시 현재 페이지의 그룹과 가져온 범위 안의 전체 그룹 수
+	Groups      []*hitGroup `json:"groups,omitempty"`
+	TotalGroups *int        `json:"total_groups,omitempty"`
+}
+
 func main() {
 	var err error
 
@@ -30,205 +123,1762 @@ func main() {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
-	// OpenAI API 클라이언트 초기화
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("OPENAI_API_KEY environment variable is not set")
+	// 형태소 분석 모드와 분석을 수행할 곳 (예: ANALYSIS_MODE=none, ANALYSIS_PROVIDER=local)
+	// 기본은 OpenAI로 분석하고 실패하면 로컬 분석기로 대신 분석하며, none이면 분석하지 않고 원문만 색인한다.
+	if mode := os.Getenv("ANALYSIS_MODE"); mode != "" {
+		if mode != analysisModeLLM && mode != analysisModeNone {
+			log.Fatalf("Invalid ANALYSIS_MODE: must be llm or none")
+		}
+		analysisMode = mode
+	}
+	if provider := os.Getenv("ANALYSIS_PROVIDER"); provider != "" {
+		if provider != analysisProviderOpenAI && provider != analysisProviderLocal {
+			log.Fatalf("Invalid ANALYSIS_PROVIDER: must be openai or local")
+		}
+		analysisProvider = provider
 	}
-	openaiClient = openai.NewClient(apiKey)
 
-	// PostgreSQL 연결 설정
-	connStr := os.Getenv("POSTGRES_CONN")
-	db, err = sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	// OpenAI API 클라이언트 초기화 (OpenAI로 분석하지 않으면 API 키가 없어도 됨)
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		openaiClient = newOpenAIClient(apiKey)
+	} else if usesOpenAI() {
+		log.Fatal("OPENAI_API_KEY environment variable is not set (set ANALYSIS_MODE=none to run without OpenAI)")
 	}
-	defer db.Close()
 
-	// Bleve 인덱스 설정
-	indexPath := ".index"
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		// 인덱스 파일이 없을 때 PostgreSQL에서 데이터를 가져와 인덱스를 생성
+	// OpenAI API 요청의 최대 시도 횟수와 재시도를 포함한 전체 제한 시간 (예: OPENAI_MAX_ATTEMPTS=3 OPENAI_RETRY_DEADLINE=1m)
+	if attempts := os.Getenv("OPENAI_MAX_ATTEMPTS"); attempts != "" {
+		value, err := strconv.Atoi(attempts)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid OPENAI_MAX_ATTEMPTS: must be a positive integer")
+		}
+		openaiMaxAttempts = value
+	}
+	if deadline := os.Getenv("OPENAI_RETRY_DEADLINE"); deadline != "" {
+		value, err := time.ParseDuration(deadline)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid OPENAI_RETRY_DEADLINE: must be a positive duration such as 2m")
+		}
+		openaiRetryDeadline = value
+	}
+
+	// 형태소 분석 요청 하나에 보낼 본문의 추정 토큰 수 (예: ANALYSIS_CHUNK_TOKENS=3000, 더 긴 문서는 나눠서 분석)
+	if chunkTokens := os.Getenv("ANALYSIS_CHUNK_TOKENS"); chunkTokens != "" {
+		value, err := strconv.Atoi(chunkTokens)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid ANALYSIS_CHUNK_TOKENS: must be a positive integer")
+		}
+		analysisChunkTokens = value
+	}
+
+	// 문서 하나의 형태소 분석에 쓸 수 있는 시간 (예: ANALYSIS_TIMEOUT=10s, 0이면 제한하지 않음)
+	if timeout := os.Getenv("ANALYSIS_TIMEOUT"); timeout != "" {
+		value, err := time.ParseDuration(timeout)
+		if err != nil || value < 0 {
+			log.Fatalf("Invalid ANALYSIS_TIMEOUT: must be a duration such as 30s")
+		}
+		analysisTimeout = value
+	}
+
+	// OpenAI API 요청 속도 제한 (예: OPENAI_REQUESTS_PER_MINUTE=500 OPENAI_TOKENS_PER_MINUTE=30000, 설정하지 않으면 제한하지 않음)
+	requestsPerMinute, tokensPerMinute := 0, 0
+	for name, limit := range map[string]*int{"OPENAI_REQUESTS_PER_MINUTE": &requestsPerMinute, "OPENAI_TOKENS_PER_MINUTE": &tokensPerMinute} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				log.Fatalf("Invalid %s: must be a non-negative integer", name)
+			}
+			*limit = parsed
+		}
+	}
+	openaiLimiter = newOpenAIRateLimiter(requestsPerMinute, tokensPerMinute)
+
+	// 형태소 분석 모델과 요청 파라미터 (예: OPENAI_MODEL=gpt-4o OPENAI_TEMPERATURE=0.2 OPENAI_MAX_TOKENS=2048)
+	if model := os.Getenv("OPENAI_MODEL"); model != "" {
+		if strings.TrimSpace(model) != model {
+			log.Fatalf("Invalid OPENAI_MODEL: must not contain surrounding whitespace")
+		}
+		analysisModel = model
+	}
+	if temperature := os.Getenv("OPENAI_TEMPERATURE"); temperature != "" {
+		value, err := strconv.ParseFloat(temperature, 32)
+		if err != nil || value < 0 || value > 2 {
+			log.Fatalf("Invalid OPENAI_TEMPERATURE: must be a number between 0 and 2")
+		}
+		analysisTemperature = float32(value)
+	}
+	if maxTokens := os.Getenv("OPENAI_MAX_TOKENS"); maxTokens != "" {
+		value, err := strconv.Atoi(maxTokens)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid OPENAI_MAX_TOKENS: must be a positive integer")
+		}
+		analysisMaxTokens = value
+	}
+	switch {
+	case analysisMode == analysisModeNone:
+		log.Printf("Morphological analysis is disabled (ANALYSIS_MODE=none), indexing raw content only")
+	case analysisProvider == analysisProviderLocal:
+		log.Printf("Using the local analyzer for morphological analysis")
+	default:
+		log.Printf("Using %s for morphological analysis (temperature %g, max tokens %d)", analysisModel, analysisTemperature, analysisMaxTokens)
+	}
+
+	// 짧은 문서 여러 개를 형태소 분석 요청 하나로 묶는 배치 (예: ANALYSIS_BATCH_SIZE=10 ANALYSIS_BATCH_TOKENS=1500 ANALYSIS_BATCH_LINGER=50ms)
+	if batchSize := os.Getenv("ANALYSIS_BATCH_SIZE"); batchSize != "" {
+		value, err := strconv.Atoi(batchSize)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid ANALYSIS_BATCH_SIZE: must be a positive integer")
+		}
+		analysisBatchSize = value
+	}
+	if batchTokens := os.Getenv("ANALYSIS_BATCH_TOKENS"); batchTokens != "" {
+		value, err := strconv.Atoi(batchTokens)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid ANALYSIS_BATCH_TOKENS: must be a positive integer")
+		}
+		analysisBatchTokens = value
+	}
+	if linger := os.Getenv("ANALYSIS_BATCH_LINGER"); linger != "" {
+		value, err := time.ParseDuration(linger)
+		if err != nil || value < 0 {
+			log.Fatalf("Invalid ANALYSIS_BATCH_LINGER: must be a non-negative duration such as 20ms")
+		}
+		analysisBatchLinger = value
+	}
+
+	// 동의어 사전 로드 (설정하지 않으면 동의어 확장 없이 동작)
+	if synonymsPath := os.Getenv("SYNONYMS_PATH"); synonymsPath != "" {
+		if err := synonyms.load(synonymsPath); err != nil {
+			log.Fatalf("Failed to load synonyms: %v", err)
+		}
+	}
+
+	// 검색 시간 제한 기본값 (예: SEARCH_TIMEOUT_MS=2000)
+	if timeoutMS := os.Getenv("SEARCH_TIMEOUT_MS"); timeoutMS != "" {
+		value, err := strconv.Atoi(timeoutMS)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid SEARCH_TIMEOUT_MS: must be a positive integer")
+		}
+		defaultSearchTimeout = time.Duration(value) * time.Millisecond
+	}
+
+	// multi_match 모드의 기본 필드 가중치 (예: SEARCH_FIELD_WEIGHTS=content:3,analysis:1)
+	if fieldWeights := os.Getenv("SEARCH_FIELD_WEIGHTS"); fieldWeights != "" {
+		defaultFieldWeights, defaultWeightedFields, err = parseBoostFields(fieldWeights)
+		if err != nil {
+			log.Fatalf("Invalid SEARCH_FIELD_WEIGHTS: %v", err)
+		}
+		if len(defaultWeightedFields) == 0 {
+			log.Fatal("Invalid SEARCH_FIELD_WEIGHTS: at least one field is required")
+		}
+	}
+
+	// 문서 목록 조회의 최대 개수 (예: DOCUMENTS_MAX_LIMIT=1000)
+	if maxLimit := os.Getenv("DOCUMENTS_MAX_LIMIT"); maxLimit != "" {
+		value, err := strconv.Atoi(maxLimit)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid DOCUMENTS_MAX_LIMIT: must be a positive integer")
+		}
+		maxListLimit = value
+	}
+
+	// 인덱스를 만들 때 Batch 하나에 모을 문서 수 (예: INDEX_BATCH_SIZE=1000)
+	if batchSize := os.Getenv("INDEX_BATCH_SIZE"); batchSize != "" {
+		value, err := strconv.Atoi(batchSize)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid INDEX_BATCH_SIZE: must be a positive integer")
+		}
+		indexBatchSize = value
+	}
+
+	// 인덱스를 만들 때 형태소 분석을 동시에 요청하는 수와 분석에 실패한 행이 있으면 중단할지 (예: ANALYSIS_WORKERS=8 INDEX_BUILD_STRICT=true)
+	if workers := os.Getenv("ANALYSIS_WORKERS"); workers != "" {
+		value, err := strconv.Atoi(workers)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid ANALYSIS_WORKERS: must be a positive integer")
+		}
+		analysisWorkers = value
+	}
+	strictIndexBuild = os.Getenv("INDEX_BUILD_STRICT") == "true"
+
+	// 인덱스를 만들 때 Postgres에서 한 번에 읽을 행 수 (예: INDEX_READ_BATCH_SIZE=5000)
+	if readBatchSize := os.Getenv("INDEX_READ_BATCH_SIZE"); readBatchSize != "" {
+		value, err := strconv.Atoi(readBatchSize)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid INDEX_READ_BATCH_SIZE: must be a positive integer")
+		}
+		indexReadBatchSize = value
+	}
+
+	// 메모리에 보관할 형태소 분석 결과 수 (예: ANALYSIS_CACHE_SIZE=50000, 0이면 Postgres의 analysis_cache만 사용)
+	if cacheSize := os.Getenv("ANALYSIS_CACHE_SIZE"); cacheSize != "" {
+		value, err := strconv.Atoi(cacheSize)
+		if err != nil || value < 0 {
+			log.Fatalf("Invalid ANALYSIS_CACHE_SIZE: must be a non-negative integer")
+		}
+		analysisCacheSize = value
+	}
+
+	// 도메인 불용어 로드 (변경 시 인덱스를 다시 만들어야 적용됨)
+	if stopWordsPath := os.Getenv("STOPWORDS_PATH"); stopWordsPath != "" {
+		stopWords, err = loadStopWords(stopWordsPath)
+		if err != nil {
+			log.Fatalf("Failed to load stop words: %v", err)
+		}
+	}
 
-		// CJK 분석기를 사용하는 인덱스 매핑 생성
-		indexMapping := bleve.NewIndexMapping()
-		docMapping := bleve.NewDocumentMapping()
+	// 만료 문서 정리 주기 (예: EXPIRY_SWEEP_INTERVAL=5m)
+	if interval := os.Getenv("EXPIRY_SWEEP_INTERVAL"); interval != "" {
+		value, err := time.ParseDuration(interval)
+		if err != nil || value <= 0 {
+			log.Fatalf("Invalid EXPIRY_SWEEP_INTERVAL: must be a positive duration such as 5m")
+		}
+		expirySweepInterval = value
+	}
 
-		textFieldMapping := bleve.NewTextFieldMapping()
-		textFieldMapping.Analyzer = cjk.AnalyzerName // CJK 언어에 대한 분석기 설정
+	// 검색 가능한 메타데이터 키와 숫자/날짜로 색인할 키 (예: METADATA_FIELDS=brand,view_count:number, 변경 시 인덱스를 다시 만들어야 적용됨)
+	metadataFields, metadataFieldTypes, err = parseMetadataFields(os.Getenv("METADATA_FIELDS"))
+	if err != nil {
+		log.Fatalf("Invalid METADATA_FIELDS: %v", err)
+	}
 
-		docMapping.AddFieldMappingsAt("content", textFieldMapping)
-		indexMapping.AddDocumentMapping("document", docMapping)
+	// 제목과 본문에 사용할 분석기와 한국어 분석기가 떼어 내는 조사 (예: TEXT_ANALYZER=cjk, KOREAN_PARTICLES=은,는,이,가, 변경 시 인덱스를 다시 만들어야 적용됨)
+	if value := os.Getenv("TEXT_ANALYZER"); value != "" {
+		textAnalyzerSetting, err = parseTextAnalyzer(value)
+		if err != nil {
+			log.Fatalf("Invalid TEXT_ANALYZER: %v", err)
+		}
+	}
+	if particles, ok := os.LookupEnv("KOREAN_PARTICLES"); ok {
+		koreanParticles = splitParamList(particles)
+	}
 
-		index, err = bleve.New(indexPath, indexMapping)
+	// 필드별 분석기 (예: FIELD_ANALYZERS=title:cjk,tags:keyword,metadata.abstract:en, 변경 시 인덱스를 다시 만들어야 적용됨)
+	// 분석기 이름은 인덱스를 만들 때가 아니라 시작할 때 확인한다.
+	if analyzers := os.Getenv("FIELD_ANALYZERS"); analyzers != "" {
+		fieldAnalyzers, err = parseFieldAnalyzers(analyzers)
 		if err != nil {
-			log.Fatalf("Failed to create index: %v", err)
+			log.Fatalf("Invalid FIELD_ANALYZERS: %v", err)
+		}
+		if os.Getenv("MAPPING_FILE") != "" {
+			log.Fatal("Invalid FIELD_ANALYZERS: cannot be combined with MAPPING_FILE, set the analyzers in the mapping file instead")
+		}
+		if _, err := buildIndexMapping(); err != nil {
+			log.Fatal(err)
 		}
-		fmt.Println("Index not found, creating new index from database...")
-		err = createIndexFromDatabase()
+	}
+
+	// 코드에 정의된 매핑 대신 사용할 인덱스 매핑 JSON (예: MAPPING_FILE=/etc/searchable/mapping.json, 변경 시 인덱스를 다시 만들어야 적용됨)
+	if mappingFile = os.Getenv("MAPPING_FILE"); mappingFile != "" {
+		mappingFileJSON, err = loadMappingFile(mappingFile)
 		if err != nil {
-			log.Fatalf("Failed to create index from database: %v", err)
+			log.Fatalf("Invalid MAPPING_FILE: %v", err)
 		}
+	}
+
+	// 인덱스 디렉터리를 만드는 위치와 새로 만드는 인덱스의 백엔드 (예: INDEX_PATH=/var/lib/searchable INDEX_TYPE=scorch)
+	// INDEX_PATH=:memory: 또는 INDEX_IN_MEMORY=true면 디렉터리 없이 메모리에 인덱스를 만듦 (테스트와 임시 배포용)
+	if dir := os.Getenv("INDEX_PATH"); dir == memoryIndexPath {
+		indexInMemory = true
+	} else if dir != "" {
+		indexDir = dir
+	}
+	if os.Getenv("INDEX_IN_MEMORY") == "true" {
+		indexInMemory = true
+	}
+	if value := os.Getenv("INDEX_TYPE"); value != "" {
+		indexType, err = parseIndexType(value)
+		if err != nil {
+			log.Fatalf("Invalid INDEX_TYPE: %v", err)
+		}
+	}
+	// 읽기 전용 모드 (READ_ONLY=true면 미리 만든 인덱스를 읽기 전용으로 열고 변경 요청을 거부, 인덱스가 없으면 종료)
+	readOnly = os.Getenv("READ_ONLY") == "true"
+	if readOnly && indexInMemory {
+		log.Fatal("Invalid READ_ONLY: cannot be combined with an in-memory index, which is always built from the database")
+	}
+	// 읽기 전용 모드에서는 인덱스 볼륨에 쓰지 않으므로 쓰기 확인도 하지 않음
+	if !indexInMemory && !readOnly {
+		if err := prepareIndexDir(indexDir); err != nil {
+			log.Fatalf("Invalid INDEX_PATH: %v", err)
+		}
+	}
+	// 인덱스 백업 파일을 저장하는 위치 (예: BACKUP_PATH=/mnt/backups)
+	backupDir = os.Getenv("BACKUP_PATH")
+
+	// Bleve 인덱스 설정
+	// POST /admin/reindex로 실행 중에 인덱스를 교체할 수 있도록 별칭으로 감쌈
+	// 손상된 인덱스는 옆으로 옮기고 데이터베이스에서 다시 만듦 (AUTO_REBUILD=false이면 그대로 두고 종료)
+	autoRebuild = os.Getenv("AUTO_REBUILD") != "false"
+
+	// 제목과 본문의 텀 벡터 (TERM_VECTORS=false면 인덱스가 작아지지만 하이라이트와 구문 검색을 쓸 수 없음, 변경 시 인덱스를 다시 만들어야 적용됨)
+	termVectors = os.Getenv("TERM_VECTORS") != "false"
+
+	// 시작할 때 캐시를 채우는 예열 검색 (예: WARMUP_QUERIES=/etc/searchable/warmup.txt 또는 WARMUP_QUERIES=200, 200이면 무작위 텀 200개)
+	if warmupQueries = os.Getenv("WARMUP_QUERIES"); warmupQueries != "" {
+		if err := validateWarmupQueries(warmupQueries); err != nil {
+			log.Fatalf("Invalid WARMUP_QUERIES: %v", err)
+		}
+	}
+
+	// PostgreSQL 연결 설정
+	// 메모리 인덱스 모드에서 POSTGRES_CONN이 없으면 데이터베이스 없이 문서를 인덱스에만 저장 (단위 테스트와 임시 배포용)
+	if connStr := os.Getenv("POSTGRES_CONN"); connStr == "" && indexInMemory {
+		log.Println("POSTGRES_CONN is not set: running without a database, documents are kept only in the in-memory index")
 	} else {
-		index, err = bleve.Open(indexPath)
+		db, err = sql.Open("postgres", connStr)
 		if err != nil {
-			log.Fatalf("Failed to open index: %v", err)
+			log.Fatalf("Failed to connect to PostgreSQL: %v", err)
 		}
+		defer db.Close()
+	}
+
+	live, err := openSwappableIndex()
+	var corrupt *corruptIndexError
+	if errors.As(err, &corrupt) && !readOnly {
+		live, err = recoverCorruptIndex(corrupt)
+	}
+	if err != nil {
+		log.Fatalf("Failed to open index: %v", err)
+	}
+	if !live.ready() && readOnly {
+		log.Fatalf("Index not found in %s: READ_ONLY=true, so it is not created from the database", indexDir)
+	}
+	if !live.ready() {
+		// 인덱스가 없을 때 PostgreSQL에서 데이터를 가져와 백그라운드에서 인덱스를 생성
+		// 다 만들 때까지 검색 요청에는 진행률과 함께 503을 응답한다.
+		indexMapping, err := buildIndexMapping()
+		if err != nil {
+			log.Fatalf("Failed to build index mapping: %v", err)
+		}
+		if err := validateFields("SEARCH_FIELD_WEIGHTS", defaultWeightedFields, indexMapping); err != nil {
+			log.Fatalf("Invalid SEARCH_FIELD_WEIGHTS: %v", err)
+		}
+		index = live
+		if db == nil {
+			if err := live.startEmpty(); err != nil {
+				log.Fatalf("Failed to create index: %v", err)
+			}
+		} else {
+			fmt.Println("Index not found, building new index from database in background...")
+			if _, _, err := startReindex(live, reindexFull); err != nil {
+				log.Fatalf("Failed to start index build: %v", err)
+			}
+		}
+	} else {
+		checkIndexMapping(live.path, live.Mapping())
+		if err := validateFields("SEARCH_FIELD_WEIGHTS", defaultWeightedFields, live.Mapping()); err != nil {
+			log.Fatalf("Invalid SEARCH_FIELD_WEIGHTS: %v", err)
+		}
+		index = live
 	}
 	defer index.Close()
+	if db != nil {
+		if err := loadNamedIndexes(context.Background()); err != nil {
+			log.Fatalf("Failed to load indexes: %v", err)
+		}
+	}
 
-	// HTTP 핸들러 설정
+	// HTTP 핸들러 설정 (읽기 전용 모드에서는 변경 요청을 403으로 거부하고, 데이터베이스 없이 실행하면 원본 행이 필요한 요청을 501로 거부)
 	http.HandleFunc("/", heartbeatHandler)
+	http.HandleFunc("GET /livez", heartbeatHandler)
+	http.HandleFunc("GET /readyz", readinessHandler)
 	http.HandleFunc("/search", searchHandler)
-	http.HandleFunc("/insert", insertHandler)
+	http.HandleFunc("/search/count", countHandler)
+	http.HandleFunc("/search/validate", validateSearchHandler)
+	http.HandleFunc("/msearch", multiSearchHandler)
+	http.HandleFunc("POST /search/scroll", scrollStartHandler)
+	http.HandleFunc("GET /search/scroll/{token}", scrollNextHandler)
+	http.HandleFunc("/insert", rejectInReadOnly(insertHandler))
+	http.HandleFunc("/suggest", suggestHandler)
+	http.HandleFunc("/terms", termsHandler)
+	http.HandleFunc("/browse", browseHandler)
+	http.HandleFunc("/templates", rejectWritesInReadOnly(requireDatabase(templatesHandler)))
+	http.HandleFunc("/templates/{name}", rejectWritesInReadOnly(requireDatabase(templateHandler)))
+	http.HandleFunc("GET /search/template/{name}", requireDatabase(templateSearchHandler))
+	http.HandleFunc("/synonyms/reload", synonymsReloadHandler)
+	http.HandleFunc("GET /documents", requireDatabase(listDocumentsHandler))
+	http.HandleFunc("POST /documents/bulk", rejectInReadOnly(requireDatabase(bulkInsertHandler)))
+	http.HandleFunc("POST /documents/delete_by_query", rejectInReadOnly(requireDatabase(deleteByQueryHandler)))
+	http.HandleFunc("POST /import/ndjson", rejectInReadOnly(requireDatabase(importNDJSONHandler)))
+	http.HandleFunc("POST /import/csv", rejectInReadOnly(requireDatabase(importCSVHandler)))
+	http.HandleFunc("/documents/{id}", rejectWritesInReadOnly(requireDatabase(documentHandler)))
+	http.HandleFunc("POST /documents/{id}/restore", rejectInReadOnly(requireDatabase(restoreDocumentHandler)))
+	http.HandleFunc("GET /documents/{id}/similar", similarDocumentsHandler)
+	http.HandleFunc("GET /indexes", requireDatabase(listNamedIndexesHandler))
+	http.HandleFunc("POST /indexes/{name}", rejectInReadOnly(requireDatabase(createNamedIndexHandler)))
+	http.HandleFunc("DELETE /indexes/{name}", rejectInReadOnly(requireDatabase(deleteNamedIndexHandler)))
+	http.HandleFunc("/indexes/{name}/search", requireDatabase(namedSearchHandler))
+	http.HandleFunc("POST /indexes/{name}/insert", rejectInReadOnly(requireDatabase(namedInsertHandler)))
+	http.HandleFunc("GET /admin/stats", statsHandler)
+	http.HandleFunc("POST /admin/backup", rejectInReadOnly(backupHandler))
+	http.HandleFunc("GET /admin/backups", listBackupsHandler)
+	http.HandleFunc("POST /admin/restore", rejectInReadOnly(restoreHandler))
+	http.HandleFunc("POST /admin/compact", rejectInReadOnly(compactHandler))
+	http.HandleFunc("GET /admin/verify", requireDatabase(verifyHandler))
+	http.HandleFunc("DELETE /admin/index", rejectInReadOnly(requireDatabase(recreateIndexHandler)))
+	http.HandleFunc("POST /admin/reindex", rejectInReadOnly(requireDatabase(reindexHandler)))
+	http.HandleFunc("GET /admin/reindex/status", reindexProgressHandler)
+	http.HandleFunc("GET /admin/reindex/{job}", reindexStatusHandler)
+	http.HandleFunc("DELETE /admin/analysis-cache", rejectInReadOnly(analysisCacheHandler))
+	http.HandleFunc("POST /admin/migrations/{name}", rejectInReadOnly(requireDatabase(migrationHandler)))
+	http.HandleFunc("GET /admin/local-analysis", requireDatabase(localAnalysisHandler))
+
+	// 종료 신호를 받으면 진행 중인 요청을 마치고 만료 문서 정리도 멈춘 뒤 종료
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sweeperDone := startExpirySweeper(ctx, expirySweepInterval)
+	startWarmup()
 
 	// 서버 시작
+	server := &http.Server{Addr: ":8080"}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down server: %v", err)
+		}
+	}()
 	fmt.Println("Starting server on :8080...")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+	<-sweeperDone
 }
 
-// Heartbeat 핸들러
+// Heartbeat 핸들러 (mode는 read_write 또는 read_only, analysis_mode는 llm 또는 none)
 func heartbeatHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "mode": serverMode(), "analysis_mode": analysisMode})
+}
+
+// 데이터 삽입 응답 (result는 created, updated, duplicate 중 하나)
+type insertResponse struct {
+	ID     int      `json:"id"`
+	Result string   `json:"result"`
+	Tokens []string `json:"tokens,omitempty"`
+	// 형태소 분석을 수행한 곳과 모델 (중복 문서라 분석하지 않았으면 생략)
+	AnalysisSource string `json:"analysis_source,omitempty"`
+	AnalysisModel  string `json:"analysis_model,omitempty"`
+}
+
+// 형태소 분석 결과를 만든 곳 (documents.analysis_source에 저장됨, local은 OpenAI 대신 로컬 분석기로 분석한 결과)
+// none은 ANALYSIS_MODE=none이라 분석하지 않았음을 나타내며, 이때 analysis는 NULL로 저장된다.
+const (
+	analysisSourceOpenAI = "openai"
+	analysisSourceLocal  = "local"
+	analysisSourceNone   = "none"
+)
+
+// 새로 분석해 추가한 문서의 응답을 만드는 함수 (OpenAI로 분석했으면 모델도 포함)
+func analyzedInsertResponse(id int, tokens []string, source string) insertResponse {
+	response := insertResponse{ID: id, Result: "created", Tokens: tokens, AnalysisSource: source}
+	if source == analysisSourceOpenAI {
+		response.AnalysisModel = analysisModel
+	}
+	return response
+}
+
+// 데이터 삽입 결과를 응답하는 함수
+// Accept 헤더로 text/plain을 요청하면 이전의 텍스트 응답을 반환한다. 텍스트 응답은 사용 중단 예정이므로 Deprecation 헤더로 알린다.
+func writeInsertResponse(w http.ResponseWriter, r *http.Request, status int, response insertResponse) {
+	if !acceptsPlainText(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Deprecation", "true")
+	w.WriteHeader(status)
+	switch response.Result {
+	case "created":
+		fmt.Fprintf(w, "Document inserted with ID: %d", response.ID)
+	case "updated":
+		fmt.Fprintf(w, "Document updated with ID: %d", response.ID)
+	default:
+		fmt.Fprintf(w, "Document already exists with ID: %d", response.ID)
+	}
+}
+
+// Accept 헤더에 text/plain이 명시되어 있는지 확인하는 함수 (*/*나 헤더가 없으면 JSON)
+func acceptsPlainText(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.TrimSpace(mediaType) == "text/plain" {
+			return true
+		}
+	}
+	return false
+}
+
+// nginx가 쓰는 비표준 상태 코드 (클라이언트가 응답을 받기 전에 연결을 닫음)
+const statusClientClosedRequest = 499
+
+// 클라이언트가 연결을 끊어 요청이 취소됐으면 499로 기록하고 true를 반환하는 함수
+// 받을 쪽이 없으므로 호출한 핸들러는 응답을 쓰지 않고 바로 반환한다.
+func requestCancelled(r *http.Request) bool {
+	if !errors.Is(r.Context().Err(), context.Canceled) {
+		return false
+	}
+	log.Printf("%d %s %s: client closed request", statusClientClosedRequest, r.Method, r.URL.RequestURI())
+	return true
 }
 
-// 데이터 삽입 핸들러
+// 데이터 삽입 핸들러 (reject_duplicates=true이면 내용이 같은 문서가 있을 때 409로 거부)
+// 부여된 ID와 형태소 분석 토큰을 JSON으로 응답한다.
 func insertHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req struct {
-		Content string `json:"content"`
+	rejectDuplicates := r.URL.Query().Get("reject_duplicates") == "true"
+	req, err := decodeDocumentBody(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	// 내용이 같은 문서가 이미 있으면 분석하지 않고 기존 문서로 응답
+	if respondIfDuplicate(w, r, nil, req, rejectDuplicates) {
 		return
 	}
 
 	// OpenAI API를 사용하여 형태소 분석 수행
-	analysis, err := getMorphologicalAnalysis(req.Content)
+	ctx := r.Context()
+	tokens, source, err := getMorphologicalAnalysis(ctx, req.Content)
+	if requestCancelled(r) {
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to analyze text: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if db == nil {
+		insertWithoutDatabase(w, r, req, tokens, source)
+		return
+	}
+	analysis := analysisText(tokens)
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
 	var id int
-	err = db.QueryRow("INSERT INTO documents(content) VALUES($1) RETURNING id", analysis).Scan(&id)
+	var createdAt time.Time
+	var version int
+	created := true
+	if req.ID != nil {
+		// 지정한 ID로 추가 (이미 있는 ID면 409)
+		id = *req.ID
+		createdAt, version, err = insertDocumentWithID(ctx, tx, req, analysis, source)
+	} else {
+		err = tx.QueryRowContext(ctx, upsertDocumentSQL, req.upsertArgs(analysis, source)...).Scan(&id, &createdAt, &version, &created)
+	}
+	if requestCancelled(r) {
+		return
+	}
+	if err != nil && !isDuplicateContentError(err) {
+		err = fmt.Errorf("Failed to insert data: %w", err)
+	}
+	if isDuplicateContentError(err) && respondIfDuplicate(w, r, nil, req, rejectDuplicates) {
+		return
+	}
+	var exists *documentExistsError
+	if errors.As(err, &exists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to insert data: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	err = index.Index(strconv.Itoa(id), analysis)
+	// 색인이 실패하면 커밋하지 않아 DB와 인덱스가 어긋나지 않게 함
+	err = index.Index(strconv.Itoa(id), req.indexDocument(analysis, createdAt, version))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to index data: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Document %d was indexed but the database commit failed: %v", id, err)
+		http.Error(w, fmt.Sprintf("Failed to commit insert: %v", err), http.StatusInternalServerError)
+		return
+	}
+	suggester.markDirty()
 
-	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "Document inserted with ID: %d", id)
+	// 문서 버전은 ETag 헤더로 알리고, external_id가 같은 문서가 이미 있으면 그 문서를 갱신한 것으로 응답
+	setVersionHeader(w, version)
+	response := analyzedInsertResponse(id, tokens, source)
+	if !created {
+		response.Result = "updated"
+		writeInsertResponse(w, r, http.StatusOK, response)
+		return
+	}
+	writeInsertResponse(w, r, http.StatusCreated, response)
 }
 
-// 검색 핸들러
+// 검색 핸들러 (GET은 쿼리 파라미터, POST는 JSON 불리언 쿼리 본문 사용)
 func searchHandler(w http.ResponseWriter, r *http.Request) {
-	if index == nil {
-		http.Error(w, "Index is not initialized", http.StatusInternalServerError)
+	if !indexReady(w) {
 		return
 	}
 
-	queryParam := r.URL.Query().Get("q")
-	if queryParam == "" {
-		http.Error(w, "Missing query parameter 'q'", http.StatusBadRequest)
+	var spec *searchSpec
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		spec, err = newSearchSpecFromParams(r.URL.Query(), index.Mapping())
+	case http.MethodPost:
+		spec, err = newSearchSpecFromBody(r.Body, index.Mapping())
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	executeSearch(w, r, spec)
+}
 
-	query := bleve.NewMatchQuery(queryParam)
-	searchRequest := bleve.NewSearchRequest(query)
-	searchResult, err := index.Search(searchRequest)
+// 검색 요청을 실행하고 결과를 응답하는 함수
+func executeSearch(w http.ResponseWriter, r *http.Request, spec *searchSpec) {
+	response, err := runSearch(r.Context(), spec)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Search failed: %v", err), http.StatusInternalServerError)
+		writeSearchError(w, err, spec.timeout)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(searchResult); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
 
-// 데이터베이스에서 모든 문서를 읽어와 인덱스를 생성하는 함수
-func createIndexFromDatabase() error {
-	rows, err := db.Query("SELECT id, content FROM documents")
+// 검색을 실행하고 후처리(점수 하한, 하이라이트, 맞춤법 제안)를 거친 응답을 만드는 함수
+func runSearch(ctx context.Context, spec *searchSpec) (*searchResponse, error) {
+	// 점수 하한이나 중복 제거가 있으면 앞쪽 히트를 한 번에 가져와 걸러낸 뒤 요청한 페이지를 잘라냄
+	// 중복 제거는 걸러진 뒤에도 페이지가 채워지도록 요청한 범위의 dedupeOverfetch배를 가져옴
+	from, size := spec.request.From, spec.request.Size
+	window := 0
+	if spec.minScore != nil {
+		window = minScoreWindow
+	}
+	if spec.dedupeField != "" {
+		window = max(window, min((from+size)*dedupeOverfetch, minScoreWindow))
+	}
+	if spec.collapse != nil {
+		// 그룹 단위로 페이지를 나누므로 그룹마다 내부 히트만큼 더 가져옴
+		window = max(window, min((from+size)*(spec.collapse.innerHits+1)*dedupeOverfetch, minScoreWindow))
+	}
+
+	// 가중치가 있는 문서가 있으면 점수순 검색의 앞쪽 히트를 가중치를 반영해 다시 정렬
+	target := spec.searchIndex()
+	boosted := false
+	if sortedByScore(spec.request) && from+size <= boostRescoreWindow {
+		var err error
+		if boosted, err = hasBoostedDocuments(ctx, target); err != nil {
+			return nil, err
+		}
+	}
+	addedBoostField := false
+	if boosted {
+		window = max(window, boostRescoreWindow)
+		addedBoostField = requestBoostField(spec.request)
+	}
+	if window > 0 {
+		spec.request.From, spec.request.Size = 0, window
+	}
+
+	searchResult, err := searchIndexWithTimeout(ctx, target, spec.request, spec.timeout)
+	spec.request.From, spec.request.Size = from, size
+	if addedBoostField {
+		spec.request.Fields = spec.request.Fields[:len(spec.request.Fields)-1]
+	}
 	if err != nil {
-		return fmt.Errorf("Failed to query documents: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var id int
-		var content string
-		if err := rows.Scan(&id, &content); err != nil {
-			return fmt.Errorf("Failed to scan row: %w", err)
+	decodeDistanceSorts(searchResult, spec.request.Sort)
+	if boosted {
+		applyDocumentBoosts(searchResult)
+		if addedBoostField {
+			dropBoostField(searchResult)
+		}
+	}
+
+	var unfilteredTotal *uint64
+	if spec.minScore != nil {
+		total := searchResult.Total
+		unfilteredTotal = &total
+		applyMinScore(searchResult, *spec.minScore)
+	}
+	var collapsedHits *int
+	if spec.dedupeField != "" {
+		collapsed := dedupeHits(searchResult, spec.dedupeField)
+		collapsedHits = &collapsed
+	}
+	var groups []*hitGroup
+	var totalGroups *int
+	if spec.collapse != nil {
+		// 페이지는 히트가 아닌 그룹 단위로 나누고, 히트 목록에는 그룹 대표 히트만 남김
+		groups = collapseHits(searchResult.Hits, spec.collapse)
+		count := len(groups)
+		totalGroups = &count
+		groups = groups[min(from, count):min(from+size, count)]
+		searchResult.Hits = searchResult.Hits[:0]
+		for _, group := range groups {
+			searchResult.Hits = append(searchResult.Hits, group.Hit)
+		}
+	} else if window > 0 {
+		pageHits(searchResult, from, size)
+	}
+
+	if spec.highlight != nil {
+		if err := applyHighlight(target, searchResult, spec.highlight); err != nil {
+			return nil, err
+		}
+	}
+	markMissingFields(searchResult, spec.includeFields)
+
+	response := &searchResponse{
+		SearchResult:    searchResult,
+		From:            spec.request.From,
+		Size:            spec.request.Size,
+		QueryType:       spec.queryType,
+		UnfilteredTotal: unfilteredTotal,
+		CollapsedHits:   collapsedHits,
+		Groups:          groups,
+		TotalGroups:     totalGroups,
+	}
+
+	// 결과가 거의 없으면 철자를 교정한 대체 검색어를 제안
+	if spec.suggestText != "" && searchResult.Total < suggestHitThreshold {
+		response.Suggestions, err = spellSuggestions(target, defaultSearchField, spec.suggestText)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// 실행할 검색 요청과 응답 구성에 필요한 부가 정보
+type searchSpec struct {
+	request       *bleve.SearchRequest
+	queryType     string
+	includeFields []string
+	highlight     *highlightOptions
+	suggestText   string   // 맞춤법 제안을 요청한 경우의 원래 검색어
+	minScore      *float64 // 이 점수 미만의 히트는 응답에서 제외
+	timeout       time.Duration
+	dedupeField   string // 이 필드 값이 같은 히트는 최고 점수 하나만 남김
+	collapse      *collapseOptions
+	target        bleve.Index // 검색할 이름 붙은 인덱스 (nil이면 기본 인덱스)
+}
+
+// 검색할 인덱스를 반환하는 함수
+func (spec *searchSpec) searchIndex() bleve.Index {
+	if spec.target != nil {
+		return spec.target
+	}
+	return index
+}
+
+// 요청한 페이지가 점수 하한, 중복 제거, 그룹 묶기를 적용하는 앞쪽 minScoreWindow개 히트를 벗어나는지 확인하는 함수
+// 벗어난 페이지는 걸러낼 히트를 가져오지 못해 조용히 비거나 잘리므로 요청을 거부해야 한다.
+func (spec *searchSpec) exceedsResultWindow() bool {
+	if spec.minScore == nil && spec.dedupeField == "" && spec.collapse == nil {
+		return false
+	}
+	return spec.request.From+spec.request.Size > minScoreWindow
+}
+
+// 일치 문서 수 핸들러 (GET /search/count)
+// 쿼리는 /search와 같은 방식으로 만들고 히트는 불러오지 않는다.
+func countHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spec, err := newSearchSpecFromParams(r.URL.Query(), index.Mapping())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	countRequest := spec.request
+	countRequest.From, countRequest.Size = 0, 0
+	countRequest.Fields = nil
+	countRequest.Facets = nil
+	countRequest.Explain = false
+	countRequest.IncludeLocations = false
+	if spec.minScore != nil {
+		// 점수 하한은 히트 점수가 있어야 적용할 수 있음
+		countRequest.Size = minScoreWindow
+	}
+
+	start := time.Now()
+	searchResult, err := searchWithTimeout(r.Context(), countRequest, spec.timeout)
+	if err != nil {
+		writeSearchError(w, err, spec.timeout)
+		return
+	}
+	if spec.minScore != nil {
+		applyMinScore(searchResult, *spec.minScore)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]uint64{
+		"count":   searchResult.Total,
+		"took_ms": uint64(time.Since(start).Milliseconds()),
+	})
+}
+
+// 요청 컨텍스트에서 파생한 시간 제한 안에서 검색을 실행하는 함수
+// 시간 제한에 걸리면 수집 중이던 부분 결과는 버리고 errSearchTimeout을 반환한다.
+func searchWithTimeout(ctx context.Context, req *bleve.SearchRequest, timeout time.Duration) (*bleve.SearchResult, error) {
+	return searchIndexWithTimeout(ctx, index, req, timeout)
+}
+
+// 지정한 인덱스에서 시간 제한 안에 검색을 실행하는 함수 (searchWithTimeout 참고)
+func searchIndexWithTimeout(ctx context.Context, target bleve.Index, req *bleve.SearchRequest, timeout time.Duration) (*bleve.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	searchResult, err := target.SearchInContext(ctx, excludeExpired(req))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, errSearchTimeout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Search failed: %w", err)
+	}
+	return searchResult, nil
+}
+
+// 검색 오류를 응답하는 함수 (시간 초과는 504와 JSON 본문으로 구분)
+func writeSearchError(w http.ResponseWriter, err error, timeout time.Duration) {
+	if errors.Is(err, errIndexBuilding) {
+		writeIndexBuilding(w)
+		return
+	}
+	if !errors.Is(err, errSearchTimeout) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      errSearchTimeout.Error(),
+		"timeout_ms": timeout.Milliseconds(),
+	})
+}
+
+// 요청한 검색 시간 제한을 해석하는 함수 (0 또는 미지정이면 기본값)
+func searchTimeout(timeoutMS int) (time.Duration, error) {
+	if timeoutMS == 0 {
+		return defaultSearchTimeout, nil
+	}
+	timeout := time.Duration(timeoutMS) * time.Millisecond
+	if timeout > maxSearchTimeout {
+		return 0, fmt.Errorf("Invalid query parameter 'timeout_ms': must be at most %d", maxSearchTimeout.Milliseconds())
+	}
+	return timeout, nil
+}
+
+// 점수가 하한 미만인 히트를 제외하고 전체 히트 수를 다시 계산하는 함수
+// 검색은 From=0부터 창 크기만큼 실행되므로 걸러낸 목록에서 페이지를 잘라내면(pageHits)
+// 다음 페이지에 이미 제외된 히트가 다시 나타나지 않는다. 창 밖의 히트는 세지 않는다.
+func applyMinScore(result *bleve.SearchResult, minScore float64) {
+	kept := result.Hits[:0]
+	for _, hit := range result.Hits {
+		if hit.Score >= minScore {
+			kept = append(kept, hit)
+		}
+	}
+	result.Hits = kept
+	result.Total = uint64(len(kept))
+}
+
+// 같은 필드 값을 가진 히트 중 점수가 가장 높은 히트만 남기는 함수 (제외된 히트 수 반환)
+// 남은 히트는 원래 순서를 유지하며, 필드 값이 없는 히트는 모두 남긴다.
+func dedupeHits(result *bleve.SearchResult, field string) int {
+	kept := result.Hits[:0]
+	positions := make(map[string]int)
+	for _, hit := range result.Hits {
+		value, ok := hit.Fields[field]
+		if !ok || value == nil {
+			kept = append(kept, hit)
+			continue
+		}
+		key := fmt.Sprint(value)
+		if i, seen := positions[key]; seen {
+			if hit.Score > kept[i].Score {
+				kept[i] = hit
+			}
+			continue
 		}
+		positions[key] = len(kept)
+		kept = append(kept, hit)
+	}
+	collapsed := len(result.Hits) - len(kept)
+	result.Hits = kept
+	return collapsed
+}
+
+// 미리 넉넉히 가져온 히트 목록에서 요청한 페이지만 남기는 함수
+func pageHits(result *bleve.SearchResult, from, size int) {
+	start := min(from, len(result.Hits))
+	end := min(start+size, len(result.Hits))
+	result.Hits = result.Hits[start:end]
+}
+
+// GET 검색의 쿼리 파라미터로부터 검색 요청을 생성하는 함수
+func newSearchSpecFromParams(values url.Values, m mapping.IndexMapping) (*searchSpec, error) {
+	queryParam := values.Get("q")
+	if queryParam == "" {
+		return nil, fmt.Errorf("Missing query parameter 'q'")
+	}
+
+	// 페이지네이션 파라미터 파싱
+	from, err := parseIntParam(values, "from", 0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := parseIntParam(values, "size", defaultSearchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	searchQuery, queryType, err := buildTextQuery(values, queryParam, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// 필터 조건은 텍스트 쿼리와 AND로 결합
+	filters, err := buildParamFilters(values, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) > 0 {
+		searchQuery = bleve.NewConjunctionQuery(append([]query.Query{searchQuery}, filters...)...)
+	}
+
+	// explain=true일 때만 히트별 점수 계산 내역을 포함 (기본값은 응답에서 생략)
+	explain := values.Get("explain") == "true"
+
+	searchRequest := bleve.NewSearchRequestOptions(searchQuery, min(size, maxSearchSize), from, explain)
+
+	var includeFields []string
+	searchRequest.Fields, includeFields = selectStoredFields(values, m)
 
-		// OpenAI API를 사용하여 형태소 분석 수행
-		analysis, err := getMorphologicalAnalysis(content)
+	// 하이라이트 요청 시 <mark> 태그로 감싼 조각을 반환 (텀 위치가 필요)
+	highlight, err := parseHighlightOptions(values, m)
+	if err != nil {
+		return nil, err
+	}
+	searchRequest.IncludeLocations = highlight != nil
+
+	// 정렬 조건 (지정하지 않으면 점수 내림차순)
+	sortOrder, err := parseSortParam(values.Get("sort"), values, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(sortOrder) > 0 {
+		searchRequest.SortByCustom(sortOrder)
+	}
+
+	// 패싯 요청 시 필드별 TermsFacet 추가 (검색 결과에 대해서만 집계됨)
+	if facets := splitParamList(values.Get("facets")); len(facets) > 0 {
+		if err := validateFields("facets", facets, m); err != nil {
+			return nil, err
+		}
+		facetSize, err := parseIntParam(values, "facet_size", defaultFacetSize)
 		if err != nil {
-			return fmt.Errorf("Failed to analyze text: %w", err)
+			return nil, err
+		}
+		for _, field := range facets {
+			searchRequest.AddFacet(field, bleve.NewFacetRequest(field, min(facetSize, maxFacetSize)))
+		}
+	}
+
+	// 점수 하한 (검색 후 핸들러에서 적용)
+	var minScore *float64
+	if raw := values.Get("min_score"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("Invalid query parameter 'min_score': must be a non-negative number")
+		}
+		minScore = &value
+	}
+
+	// 필드 값 기준 중복 제거
+	dedupeField := values.Get("dedupe_field")
+	if err := prepareDedupeField(searchRequest, dedupeField, m); err != nil {
+		return nil, err
+	}
+
+	// 필드 값 기준 그룹 묶기 (collapse=field&inner_hits=3)
+	collapse, err := parseCollapseOptions(values, searchRequest, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// 검색 시간 제한
+	timeoutMS, err := parseIntParam(values, "timeout_ms", 0)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := searchTimeout(timeoutMS)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &searchSpec{request: searchRequest, queryType: queryType, includeFields: includeFields, highlight: highlight, minScore: minScore, timeout: timeout, dedupeField: dedupeField, collapse: collapse}
+	if spec.exceedsResultWindow() {
+		return nil, fmt.Errorf("Invalid query parameters: from+size must be at most %d when min_score, dedupe_field or collapse is set", minScoreWindow)
+	}
+	if values.Get("suggest") == "true" {
+		spec.suggestText = queryParam
+	}
+	return spec, nil
+}
+
+// 중복 제거 기준 필드를 검증하고 히트에 해당 필드 값이 포함되도록 하는 함수
+func prepareDedupeField(searchRequest *bleve.SearchRequest, field string, m mapping.IndexMapping) error {
+	if field == "" {
+		return nil
+	}
+	fieldMapping, ok := mappedFields(m)[field]
+	if !ok || !fieldMapping.Store {
+		return fmt.Errorf("Invalid query parameter 'dedupe_field': field '%s' is not a stored field", field)
+	}
+	if !slices.Contains(searchRequest.Fields, "*") && !slices.Contains(searchRequest.Fields, field) {
+		searchRequest.Fields = append(searchRequest.Fields, field)
+	}
+	return nil
+}
+
+// 히트에 포함할 저장 필드를 결정하는 함수 (기본값은 전체, exclude_fields는 저장 필드 중 제외할 목록)
+// 두 번째 반환값은 include_fields로 명시한 필드 목록으로, 값이 없는 필드를 null로 표시할 때 사용한다.
+func selectStoredFields(values url.Values, m mapping.IndexMapping) ([]string, []string) {
+	includeFields := splitParamList(values.Get("include_fields"))
+	if len(includeFields) > 0 {
+		return includeFields, includeFields
+	}
+	if excludeFields := splitParamList(values.Get("exclude_fields")); len(excludeFields) > 0 {
+		return storedFieldsExcept(m, excludeFields), nil
+	}
+	return []string{"*"}, nil
+}
+
+// 매핑의 저장 필드 중 제외 목록에 없는 필드만 반환하는 함수
+func storedFieldsExcept(m mapping.IndexMapping, exclude []string) []string {
+	fields := mappedFields(m)
+	stored := []string{}
+	for _, name := range sortedKeys(fields) {
+		if fields[name].Store && !slices.Contains(exclude, name) {
+			stored = append(stored, name)
+		}
+	}
+	return stored
+}
+
+// 요청한 필드가 저장되어 있지 않아 히트에 없으면 null 값으로 표시하는 함수
+func markMissingFields(result *bleve.SearchResult, requested []string) {
+	for _, hit := range result.Hits {
+		for _, field := range requested {
+			if _, ok := hit.Fields[field]; !ok {
+				if hit.Fields == nil {
+					hit.Fields = make(map[string]interface{})
+				}
+				hit.Fields[field] = nil
+			}
+		}
+	}
+}
+
+// 쉼표로 구분된 쿼리 파라미터 값을 목록으로 분리하는 함수 (빈 항목 제외)
+func splitParamList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
+	}
+	return items
+}
+
+// fuzziness 파라미터를 파싱하는 함수 (0, 1, 2 또는 "auto")
+func parseFuzziness(raw string, text string) (int, error) {
+	switch raw {
+	case "":
+		return 0, nil
+	case "auto":
+		return autoFuzziness(text), nil
+	case "0", "1", "2":
+		return strconv.Atoi(raw)
+	}
+	return 0, fmt.Errorf("Invalid query parameter 'fuzziness': must be 0, 1, 2 or auto")
+}
+
+// 가장 짧은 검색어의 글자 수에 따라 퍼지 거리를 결정하는 함수
+// (2글자 이하는 0, 5글자 이하는 1, 그 이상은 2)
+func autoFuzziness(text string) int {
+	terms := strings.Fields(text)
+	if len(terms) == 0 {
+		return 0
+	}
+
+	shortest := utf8.RuneCountInString(terms[0])
+	for _, term := range terms[1:] {
+		if length := utf8.RuneCountInString(term); length < shortest {
+			shortest = length
+		}
+	}
+
+	switch {
+	case shortest <= 2:
+		return 0
+	case shortest <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// 음이 아닌 정수형 쿼리 파라미터를 파싱하는 함수 (값이 없으면 기본값 반환)
+func parseIntParam(values url.Values, name string, defaultValue int) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
 
-		err = index.Index(strconv.Itoa(id), analysis)
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("Invalid query parameter '%s': must be a non-negative integer", name)
+	}
+	return value, nil
+}
+
+// 본문 분석기와 문서 종류별 매핑을 사용하는 인덱스 매핑 생성
+func buildIndexMapping() (*mapping.IndexMappingImpl, error) {
+	// MAPPING_FILE이 있으면 파일의 매핑을 그대로 사용 (STOPWORDS_PATH와 METADATA_FIELDS는 적용되지 않음)
+	if mappingFileJSON != nil {
+		return parseIndexMapping(mappingFileJSON)
+	}
+	indexMapping := bleve.NewIndexMapping()
+
+	// TEXT_ANALYZER로 고른 분석기 (도메인 불용어가 설정되어 있으면 불용어도 제거)
+	textAnalyzer, err := addTextAnalyzer(indexMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// 태그, 외부 ID, 키워드 메타데이터를 대소문자 구분 없이 색인하는 분석기
+	if err := addExactAnalyzer(indexMapping); err != nil {
+		return nil, err
+	}
+
+	// 문서 종류(type 필드)별 매핑
+	if err := addDocumentTypeMappings(indexMapping, textAnalyzer); err != nil {
+		return nil, err
+	}
+	return indexMapping, nil
+}
+
+// 모든 문서 종류가 공유하는 문서 매핑을 만드는 함수
+func newDocumentMapping(textAnalyzer string) *mapping.DocumentMapping {
+	docMapping := bleve.NewDocumentMapping()
+
+	// 원문은 검색 결과에 그대로 돌려주도록 저장하고, 하이라이트와 구문 검색을 할 수 있도록 텀 벡터도 저장 (TERM_VECTORS=false면 생략)
+	textFieldMapping := bleve.NewTextFieldMapping()
+	textFieldMapping.Analyzer = textAnalyzer // 한국어(또는 CJK) 분석기 설정
+	textFieldMapping.Store = true
+	textFieldMapping.IncludeTermVectors = termVectors
+
+	// 형태소 분석 결과는 검색에만 사용하고 저장하지 않음
+	analysisFieldMapping := bleve.NewTextFieldMapping()
+	analysisFieldMapping.Analyzer = textAnalyzer
+	analysisFieldMapping.Store = false
+
+	// 태그는 분석하지 않고 소문자로만 바꿔 색인 (패싯 집계와 filter_term 정확히 일치 검색용)
+	tagsFieldMapping := newExactFieldMapping()
+
+	// 외부 ID는 filter_term으로 찾을 수 있도록 정확히 일치하는 값으로 색인하고 검색 결과에 돌려주도록 저장
+	externalIDFieldMapping := newExactFieldMapping()
+	externalIDFieldMapping.Store = true
+
+	// 작성 시각은 범위 검색과 정렬이 가능하도록 datetime으로 색인
+	createdAtFieldMapping := bleve.NewDateTimeFieldMapping()
+
+	// 가격 등 숫자 필드는 범위 검색이 가능하도록 numeric으로 색인
+	priceFieldMapping := bleve.NewNumericFieldMapping()
+
+	// 위치 좌표는 반경 검색과 거리순 정렬이 가능하도록 geopoint로 색인
+	locationFieldMapping := bleve.NewGeoPointFieldMapping()
+
+	// 문서 버전은 검색 결과에 함께 돌려주도록 저장
+	versionFieldMapping := bleve.NewNumericFieldMapping()
+
+	// 만료 시각은 만료된 문서를 검색에서 제외하는 데만 사용
+	expiresAtFieldMapping := bleve.NewDateTimeFieldMapping()
+	expiresAtFieldMapping.Store = false
+
+	// 문서 가중치는 검색 후 점수에 곱하도록 저장 (_all 필드에 넣으면 본문 점수가 달라지므로 제외)
+	boostFieldMapping := bleve.NewNumericFieldMapping()
+	boostFieldMapping.IncludeInAll = false
+
+	// 문서 종류는 필터링에 쓰고 검색 결과에 함께 돌려주도록 저장
+	typeFieldMapping := bleve.NewKeywordFieldMapping()
+	typeFieldMapping.Store = true
+
+	docMapping.AddFieldMappingsAt("title", textFieldMapping)
+	docMapping.AddFieldMappingsAt("content", textFieldMapping)
+	docMapping.AddFieldMappingsAt("analysis", analysisFieldMapping)
+	docMapping.AddFieldMappingsAt("tags", tagsFieldMapping)
+	docMapping.AddFieldMappingsAt(externalIDField, externalIDFieldMapping)
+	docMapping.AddFieldMappingsAt("price", priceFieldMapping)
+	docMapping.AddFieldMappingsAt("created_at", createdAtFieldMapping)
+	docMapping.AddFieldMappingsAt(locationField, locationFieldMapping)
+	docMapping.AddFieldMappingsAt("version", versionFieldMapping)
+	docMapping.AddFieldMappingsAt(expiresAtField, expiresAtFieldMapping)
+	docMapping.AddFieldMappingsAt(documentTypeField, typeFieldMapping)
+	docMapping.AddFieldMappingsAt(boostField, boostFieldMapping)
+
+	// 메타데이터는 지정한 키만 키워드로 색인
+	addMetadataMapping(docMapping, metadataFields, metadataFieldTypes)
+
+	// 감지한 언어와 언어별 분석기로 색인하는 본문
+	addLanguageMapping(docMapping, textAnalyzer)
+
+	return docMapping
+}
+
+// 기존 인덱스가 현재 매핑 이전에 만들어졌는지 확인하고, 다른 점이 있으면 한 번에 기록해 재색인을 안내하는 함수
+func checkIndexMapping(path string, m mapping.IndexMapping) {
+	mismatches := indexMappingMismatches(m)
+	if len(mismatches) == 0 {
+		return
+	}
+	log.Printf("WARNING: index at %s does not match the current configuration:", path)
+	for _, mismatch := range mismatches {
+		log.Printf("WARNING:   - %s", mismatch)
+	}
+	log.Printf("WARNING: Run POST /admin/reindex to rebuild it from the database.")
+}
+
+// 인덱스 매핑이 현재 설정과 다른 점의 목록 (같으면 빈 목록)
+func indexMappingMismatches(m mapping.IndexMapping) []string {
+	var mismatches []string
+	if mappingFileJSON != nil {
+		if mappingDiffers(m) {
+			mismatches = append(mismatches, "built with a different mapping than MAPPING_FILE, the file's mapping only applies after the index is rebuilt")
+		}
+		return mismatches
+	}
+	fields := mappedFields(m)
+	content, ok := fields["content"]
+	if _, hasAnalysis := fields["analysis"]; !ok || !content.Store || !hasAnalysis {
+		mismatches = append(mismatches, "built without stored document fields, search hits will not include document content")
+	}
+	if ok && termVectors && !content.IncludeTermVectors {
+		mismatches = append(mismatches, "built without term vectors, highlight=true and phrase queries on title and content will fail")
+	}
+	if ok && !termVectors && content.IncludeTermVectors {
+		mismatches = append(mismatches, "built with term vectors but TERM_VECTORS=false, the smaller index only applies after it is rebuilt")
+	}
+	if _, ok := fields[languageField]; !ok {
+		mismatches = append(mismatches, "built without language fields, lang= searches will not work")
+	}
+	if !hasDocumentTypeMappings(m) {
+		mismatches = append(mismatches, "built without document type mappings, all documents use the default mapping")
+	}
+	if !hasExactAnalyzer(m) {
+		mismatches = append(mismatches, "built with case-sensitive tags and metadata keys and without external IDs, filter_term matches exact case only")
+	}
+	if _, ok := fields["title"]; !ok {
+		mismatches = append(mismatches, "built without the title field, titles will not be searchable")
+	}
+	if !slices.Equal(indexedMetadataFields(m), metadataFields) || !maps.Equal(indexedMetadataFieldTypes(m), metadataFieldTypes) {
+		mismatches = append(mismatches, "built with different searchable metadata keys or key types than METADATA_FIELDS, the new keys only apply after the index is rebuilt")
+	}
+	if !fieldAnalyzersMatch(m) {
+		mismatches = append(mismatches, "built with different field analyzers than FIELD_ANALYZERS, the new analyzers only apply after the index is rebuilt")
+	}
+	if textAnalyzerChanged(m) {
+		mismatches = append(mismatches, "built with a different text analyzer than TEXT_ANALYZER/KOREAN_PARTICLES, the new analyzer only applies after the index is rebuilt")
+	}
+	if !slices.Equal(indexedStopWords(m), stopWords) {
+		mismatches = append(mismatches, "built with a different stop word list than STOPWORDS_PATH, the new list only applies after the index is rebuilt")
+	}
+	return mismatches
+}
+
+// 기본 인덱스에 색인할 문서 행(이름 붙은 인덱스에 속하지 않고 삭제 표시되지 않았으며 만료되지 않은 문서)의 조건과 그 행을 읽는 쿼리
+// 이름 붙은 인덱스의 행은 namedDocumentsSQL로 읽는다.
+const (
+	indexableDocumentsFilter = "tenant IS NULL AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > now())"
+	indexableDocumentsSQL    = "SELECT id, COALESCE(title, ''), content, analysis, tags, price, created_at, latitude, longitude, metadata, version, expires_at, COALESCE(doc_type, ''), external_id, boost FROM documents WHERE " + indexableDocumentsFilter
+)
+
+// 데이터베이스에서 삭제 표시되지 않고 만료되지 않은 모든 문서를 읽어와 target 인덱스를 생성하는 함수
+// 읽기 시작한 시점을 동기화 기준 시각으로 기록해 이후 증분 재색인이 그 뒤에 바뀐 행만 처리하게 한다.
+// 진행 상황은 실행 중인 재색인 작업에 기록하며, 형태소 분석에 실패한 행은 오류로 세고 건너뛴다 (INDEX_BUILD_STRICT=true면 중단).
+// 색인한 행의 체크포인트를 target에 기록하므로, 중단된 인덱스를 다시 넘기면 체크포인트 다음 행부터 이어서 만든다.
+func createIndexFromDatabase(target bleve.Index) error {
+	progress, err := startBuild(target)
+	if err != nil {
+		return err
+	}
+	if progress.complete {
+		fmt.Println("Index was already created from database before the restart.")
+		return nil
+	}
+	if progress.checkpoint > 0 {
+		log.Printf("Resuming index build after document %d", progress.checkpoint)
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM documents WHERE "+indexableDocumentsFilter+" AND id > $1", progress.checkpoint).Scan(&total); err != nil {
+		return fmt.Errorf("Failed to count documents: %w", err)
+	}
+	reindexes.setTotal(total)
+
+	batcher := &documentBatcher{target: target, size: indexBatchSize}
+	pages := &indexRowPages{query: indexableDocumentsSQL, lastID: progress.checkpoint, checkpoint: batcher.setCheckpoint, cacheResult: reindexes.recordCacheResult}
+	_, err = indexRows(pages, batcher.add, func(err error) error {
+		reindexes.advance(err)
+		if err != nil && strictIndexBuild {
+			return err
+		}
 		if err != nil {
-			return fmt.Errorf("Failed to index data: %w", err)
+			log.Printf("Skipping document while building index: %v", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := batcher.flush(); err != nil {
+		return err
+	}
+	if err := target.SetInternal(syncWatermarkKey, progress.watermark); err != nil {
+		return fmt.Errorf("Failed to record sync watermark: %w", err)
+	}
+	if err := target.SetInternal(buildCompleteKey, []byte("true")); err != nil {
+		return fmt.Errorf("Failed to record index build completion: %w", err)
+	}
+
+	fmt.Println("Index successfully created from database.")
+	return nil
+}
+
+// 형태소 분석을 동시에 요청하는 작업자 수 (ANALYSIS_WORKERS로 변경 가능)
+var analysisWorkers = 4
+
+// 인덱스를 만들 때 형태소 분석에 실패한 행이 있으면 건너뛰지 않고 중단할지 (INDEX_BUILD_STRICT=true)
+var strictIndexBuild = false
+
+// 데이터베이스에서 읽은 색인할 행 하나 (analysis가 nil이면 작업자가 형태소 분석을 수행)
+type indexRow struct {
+	// 읽은 순서 (체크포인트를 구할 때 사용)
+	seq       int
+	id        int
+	req       documentBody
+	analysis  *string
+	createdAt time.Time
+	version   int
+}
+
+// 형태소 분석을 마친 행 (분석에 실패했으면 err만 채워짐)
+type analyzedRow struct {
+	seq   int
+	rowID int
+	id    string
+	doc   indexDocument
+	err   error
+	// 형태소 분석을 수행했는지와 그 결과를 캐시에서 찾았는지
+	analyzed bool
+	cacheHit bool
+}
+
+// 인덱스를 만들 때 Postgres에서 한 번에 읽을 행 수 (INDEX_READ_BATCH_SIZE로 변경 가능)
+var indexReadBatchSize = 1000
+
+// 색인할 행을 id 순서의 keyset 페이지로 나누어 읽는 도구
+// 결과 집합 하나를 형태소 분석이 끝날 때까지 열어 두면 연결 하나를 몇 시간씩 붙잡게 되므로,
+// 페이지마다 쿼리를 실행하고 행을 모두 읽어 결과 집합을 닫은 뒤에 분석한다.
+// 페이지마다 다른 스냅샷을 읽지만, 읽는 동안 바뀐 행은 읽기 전에 구한 동기화 기준 시각 이후에 바뀌었으므로 다음 증분 재색인이 반영한다.
+type indexRowPages struct {
+	// WHERE 조건까지 쓴 indexableDocumentsSQL 형태의 쿼리와 그 인자 (페이지 조건의 인자는 뒤에 붙음)
+	query  string
+	args   []interface{}
+	lastID int
+	// 이 ID 이하의 행을 모두 add나 report에 넘길 때마다 호출 (nil이면 호출하지 않음)
+	checkpoint func(lastID int)
+	// 저장된 분석 결과가 없어 분석한 행마다 캐시에서 찾았는지와 함께 호출 (nil이면 호출하지 않음)
+	cacheResult func(hit bool)
+	read        int
+}
+
+// 다음 페이지를 읽는 함수 (더 읽을 행이 없으면 빈 목록)
+func (p *indexRowPages) next() ([]indexRow, error) {
+	query := fmt.Sprintf("%s AND id > $%d ORDER BY id LIMIT $%d", p.query, len(p.args)+1, len(p.args)+2)
+	rows, err := db.Query(query, append(slices.Clone(p.args), p.lastID, indexReadBatchSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query documents: %w", err)
+	}
+	defer rows.Close()
+	page, err := scanIndexRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(page) > 0 {
+		p.lastID = page[len(page)-1].id
+	}
+	return page, nil
+}
+
+// 모든 페이지를 읽어 형태소 분석 작업자에게 넘기는 함수 (done이 닫히면 읽기를 멈춤)
+func (p *indexRowPages) send(pending chan<- indexRow, done <-chan struct{}) error {
+	for {
+		page, err := p.next()
+		if err != nil {
+			return err
+		}
+		for _, row := range page {
+			row.seq = p.read
+			p.read++
+			select {
+			case pending <- row:
+			case <-done:
+				return nil
+			}
+		}
+		if len(page) < indexReadBatchSize {
+			return nil
+		}
+	}
+}
+
+// 색인할 문서로 만든 행을 add에 넘기는 함수 (색인한 문서 수를 반환)
+// report는 행 하나를 처리할 때마다 호출되며, 형태소 분석에 실패한 행이면 그 오류를 받는다.
+// report가 오류를 반환하면 중단하고, nil을 반환하면 실패한 행을 건너뛰고 계속한다.
+// 행을 읽는 고루틴 하나와 형태소 분석 작업자 analysisConcurrency(analysisWorkers)개, 이 함수의 색인으로 이어지는 파이프라인으로 처리하므로
+// 문서는 행 순서와 다르게 색인될 수 있지만, add와 report는 이 함수에서만 호출되어 동시에 실행되지 않는다.
+func indexRows(pages *indexRowPages, add func(id string, doc indexDocument) error, report func(err error) error) (int, error) {
+	// 중단하면 done을 닫아 읽기와 분석을 멈추고, 모든 고루틴이 끝난 뒤 반환
+	// (분석 중이거나 재시도를 기다리는 OpenAI API 요청은 ctx를 취소해 멈춤)
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pending := make(chan indexRow, analysisWorkers)
+	analyzed := make(chan analyzedRow, analysisWorkers)
+
+	var readErr error
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		defer close(pending)
+		readErr = pages.send(pending, done)
+	}()
+
+	var workers sync.WaitGroup
+	for range analysisConcurrency(analysisWorkers) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for row := range pending {
+				// 중단한 뒤에는 남은 행을 분석하지 않음
+				select {
+				case <-done:
+					return
+				default:
+				}
+				result := row.analyze(ctx)
+				select {
+				case analyzed <- result:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(analyzed)
+	}()
+
+	// 분석이 끝나는 순서는 읽은 순서와 다르므로, 읽은 순서로 앞에서부터 모두 처리한 행의 마지막 ID를 체크포인트로 넘김
+	finished := make(map[int]int)
+	nextSeq := 0
+	advance := func(result analyzedRow) {
+		finished[result.seq] = result.rowID
+		lastID, advanced := 0, false
+		for {
+			id, ok := finished[nextSeq]
+			if !ok {
+				break
+			}
+			delete(finished, nextSeq)
+			nextSeq++
+			lastID, advanced = id, true
+		}
+		if advanced && pages.checkpoint != nil {
+			pages.checkpoint(lastID)
+		}
+	}
+
+	indexed := 0
+	err := func() error {
+		for result := range analyzed {
+			if result.err != nil {
+				if err := report(result.err); err != nil {
+					return err
+				}
+				advance(result)
+				continue
+			}
+			if result.analyzed && pages.cacheResult != nil {
+				pages.cacheResult(result.cacheHit)
+			}
+			if err := add(result.id, result.doc); err != nil {
+				return fmt.Errorf("Failed to index data: %w", err)
+			}
+			indexed++
+			if err := report(nil); err != nil {
+				return err
+			}
+			advance(result)
+		}
+		return nil
+	}()
+	close(done)
+	cancel()
+	for range analyzed {
+	}
+	<-readerDone
+
+	if err != nil {
+		return 0, err
+	}
+	if readErr != nil {
+		return 0, readErr
+	}
+	return indexed, nil
+}
+
+// 조회한 행을 모두 읽는 함수
+func scanIndexRows(rows *sql.Rows) ([]indexRow, error) {
+	var page []indexRow
+	for rows.Next() {
+		var row indexRow
+		var latitude, longitude *float64
+		var metadataJSON []byte
+		req := &row.req
+		if err := rows.Scan(&row.id, &req.Title, &req.Content, &row.analysis, pq.Array(&req.Tags), &req.Price, &row.createdAt, &latitude, &longitude, &metadataJSON, &row.version, &req.ExpiresAt, &req.Type, &req.ExternalID, &req.Boost); err != nil {
+			return nil, fmt.Errorf("Failed to scan row: %w", err)
+		}
+		if err := json.Unmarshal(metadataJSON, &req.Metadata); err != nil {
+			return nil, fmt.Errorf("Failed to decode metadata of document %d: %w", row.id, err)
+		}
+		if latitude != nil && longitude != nil {
+			req.Location = &geoPoint{Lat: *latitude, Lon: *longitude}
+		}
+		page = append(page, row)
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("Error iterating over rows: %w", err)
+		return nil, fmt.Errorf("Error iterating over rows: %w", err)
 	}
+	return page, nil
+}
 
-	fmt.Println("Index successfully created from database.")
+// 행을 색인할 문서로 만드는 함수
+// 저장된 분석 결과가 없는 행만 OpenAI API를 사용하여 형태소 분석 수행
+func (row indexRow) analyze(ctx context.Context) analyzedRow {
+	result := analyzedRow{seq: row.seq, rowID: row.id, id: strconv.Itoa(row.id)}
+	analysis := row.analysis
+	if analysis == nil {
+		tokens, source, cacheHit, err := analyzeWithFallback(ctx, row.req.Content)
+		if err != nil {
+			result.err = fmt.Errorf("Failed to analyze document %d: %w", row.id, err)
+			return result
+		}
+		analyzed := analysisText(tokens)
+		analysis = &analyzed
+		result.analyzed, result.cacheHit = source == analysisSourceOpenAI, cacheHit
+		// 로컬 분석 결과는 GET /admin/local-analysis로 찾아 다시 분석할 수 있도록 저장
+		if source == analysisSourceLocal {
+			if _, err := db.ExecContext(ctx, "UPDATE documents SET analysis = $2, analysis_source = $3 WHERE id = $1 AND analysis IS NULL", row.id, analyzed, source); err != nil {
+				log.Printf("Failed to save local analysis of document %d: %v", row.id, err)
+			}
+		}
+	}
+	result.doc = row.req.indexDocument(*analysis, row.createdAt, row.version)
+	return result
+}
+
+// 인덱스를 만들 때 Batch 하나에 모을 문서 수 (INDEX_BATCH_SIZE로 변경 가능)
+var indexBatchSize = 500
+
+// 문서를 모아 size개마다 Batch 하나로 색인하는 도구 (마지막에 flush로 남은 문서를 반영해야 함)
+// 문서마다 Index를 호출하면 문서마다 세그먼트를 만들어 병합하므로 인덱스를 처음부터 만들 때 매우 느리다.
+type documentBatcher struct {
+	target bleve.Index
+	size   int
+	ids    []string
+	docs   []indexDocument
+	// 다음 flush에서 인덱스에 기록할 체크포인트 (setCheckpoint로 지정)
+	checkpoint *int
+}
+
+func (b *documentBatcher) add(id string, doc indexDocument) error {
+	b.ids = append(b.ids, id)
+	b.docs = append(b.docs, doc)
+	if len(b.ids) >= b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+// 모아 둔 문서를 색인하는 함수
+func (b *documentBatcher) flush() error {
+	if len(b.ids) == 0 {
+		return nil
+	}
+	var err error
+	switch target := b.target.(type) {
+	case *rebuildingIndex:
+		err = target.indexDocuments(b.ids, b.docs)
+	case *swappableIndex:
+		err = target.indexDocuments(b.ids, b.docs)
+	default:
+		err = indexDocuments(b.target, b.ids, b.docs)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to index data: %w", err)
+	}
+	b.ids, b.docs = b.ids[:0], b.docs[:0]
+	if b.checkpoint != nil {
+		if err := b.target.SetInternal(buildCheckpointKey, []byte(strconv.Itoa(*b.checkpoint))); err != nil {
+			return fmt.Errorf("Failed to record index build checkpoint: %w", err)
+		}
+	}
 	return nil
 }
 
-// OpenAI API를 사용하여 형태소 분석 수행하는 함수
-func getMorphologicalAnalysis(text string) (string, error) {
-	prompt := fmt.Sprintf("Please analyze the following text into its morphological components and return them as a JSON array of strings: \"%s\"", text)
-
-	resp, err := openaiClient.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are a helpful assistant.",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
+// 이 ID 이하의 행을 모두 처리했음을 다음 flush에서 기록하도록 하는 함수
+// 체크포인트 이하의 문서는 모두 add로 받았으므로, flush로 색인한 뒤에 기록하면 이어서 만들 때 빠지는 문서가 없다.
+func (b *documentBatcher) setCheckpoint(lastID int) {
+	b.checkpoint = &lastID
+}
+
+// 문서를 Batch 하나로 target에 색인하는 함수
+func indexDocuments(target bleve.Index, ids []string, docs []indexDocument) error {
+	batch := target.NewBatch()
+	for i, id := range ids {
+		if err := batch.Index(id, docs[i]); err != nil {
+			return err
+		}
+	}
+	return target.Batch(batch)
+}
+
+// 형태소 분석 모델과 요청 파라미터 (OPENAI_MODEL, OPENAI_TEMPERATURE, OPENAI_MAX_TOKENS로 변경)
+// 토큰화에는 큰 모델이 필요 없으므로 기본은 gpt-4o-mini이고, 같은 본문에 같은 결과가 나오도록 temperature는 0이다.
+var (
+	analysisModel               = openai.GPT4oMini
+	analysisTemperature float32 = 0
+	analysisMaxTokens           = 4096
+)
+
+// 문서 하나의 형태소 분석에 쓸 수 있는 시간 (ANALYSIS_TIMEOUT으로 변경, 0이면 제한하지 않음)
+// 재시도와 배치를 기다리는 시간을 포함하며, 넘기면 OpenAI API 요청을 멈추고 로컬 분석기로 대신 분석한다.
+var analysisTimeout = 30 * time.Second
+
+// temperature 0을 요청할 때 실제로 보내는 값
+// go-openai v1.28의 ChatCompletionRequest.Temperature는 omitempty라 0을 보내지 않고, 그러면 API 기본값인 1이 적용된다.
+// 0과 구별할 수 없을 만큼 작은 양수를 보내 결정적인 응답을 받는다. 설정과 로그, /admin/stats에는 설정한 값(0)을 그대로 보인다.
+const openaiZeroTemperature = math.SmallestNonzeroFloat32
+
+// 형태소 분석 요청을 만드는 함수
+func analysisRequest(prompt string) openai.ChatCompletionRequest {
+	temperature := analysisTemperature
+	if temperature == 0 {
+		temperature = openaiZeroTemperature
+	}
+	req := openai.ChatCompletionRequest{
+		Model:       analysisModel,
+		Temperature: temperature,
+		MaxTokens:   analysisMaxTokens,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: "You are a helpful assistant.",
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: prompt,
 			},
 		},
-	)
+	}
+	// 모델이 JSON을 코드 블록으로 감싸거나 설명을 덧붙이지 않도록 JSON 객체로만 응답하게 함
+	if !jsonModeUnsupportedModels[analysisModel] {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return req
+}
 
+// 형태소 분석 수행하는 함수 (형태소 토큰 목록과 분석한 곳을 반환, 같은 본문을 분석한 결과가 캐시에 있으면 그 결과를 사용)
+// ctx가 끝나면(클라이언트가 연결을 끊는 등) OpenAI API 요청과 재시도를 멈추고, analysisTimeout이 지나면 로컬 분석기로 대신 분석함
+func getMorphologicalAnalysis(ctx context.Context, text string) ([]string, string, error) {
+	tokens, source, _, err := analyzeWithFallback(ctx, text)
+	return tokens, source, err
+}
+
+// OpenAI API를 사용하여 형태소 분석 수행하는 함수 (형태소 토큰 목록을 반환)
+// 429나 일시적인 5xx 오류는 createChatCompletion이 다시 시도한다.
+// 응답을 해석할 수 없거나 토큰이 비어 있으면 무엇이 잘못됐는지 알려 한 번 더 요청하고, 그래도 안 되면 응답 앞부분을 붙인 오류를 반환한다.
+func requestMorphologicalAnalysis(ctx context.Context, text string) ([]string, error) {
+	prompt := fmt.Sprintf("Please analyze the following text into its morphological components. Return only a JSON object of the form {\"tokens\": [...]} where tokens is a JSON array of strings: \"%s\"", text)
+
+	req := analysisRequest(prompt)
+	resp, err := createChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API request failed: %v", err)
+		return nil, fmt.Errorf("OpenAI API request failed: %v", err)
+	}
+	content := responseContent(resp)
+	tokens, err := parseAnalysisTokens(content)
+	if err == nil {
+		return tokens, nil
 	}
 
-	// JSON 응답을 파싱
-	var tokens []string
-	err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &tokens)
+	log.Printf("Invalid analysis response, asking the model to correct it: %v", err)
+	req.Messages = append(req.Messages, correctionMessages(content, err)...)
+	resp, err = createChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("Failed to parse JSON response: %v", err)
+		return nil, fmt.Errorf("OpenAI API request failed: %v", err)
 	}
+	content = responseContent(resp)
+	tokens, err = parseAnalysisTokens(content)
+	if err != nil {
+		return nil, invalidAnalysisError(content, err)
+	}
+	return tokens, nil
+}
 
-	// 토큰을 공백으로 구분된 문자열로 반환
-	return fmt.Sprintf("%s", tokens), nil
+// 형태소 분석 토큰을 저장하고 색인할 문자열로 만드는 함수 (토큰을 공백으로 이어 붙임)
+// 분석하지 않았으면(ANALYSIS_MODE=none) 빈 문자열이며, 빈 분석 결과는 Postgres에 NULL로 저장된다.
+func analysisText(tokens []string) string {
+	return strings.Join(tokens, " ")
 }
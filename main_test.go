@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func TestAnalysisRequestSendsZeroTemperature(t *testing.T) {
+	defer func(value float32) { analysisTemperature = value }(analysisTemperature)
+
+	for _, tt := range []struct {
+		configured float32
+		sent       float32
+	}{
+		{0, openaiZeroTemperature},
+		{0.2, 0.2},
+	} {
+		analysisTemperature = tt.configured
+		encoded, err := json.Marshal(analysisRequest("prompt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(encoded, &body); err != nil {
+			t.Fatal(err)
+		}
+		// omitempty로 빠지면 API 기본값(1)이 적용되므로 항상 보내야 함
+		sent, ok := body["temperature"].(float64)
+		if !ok {
+			t.Fatalf("temperature %g was not sent: %s", tt.configured, encoded)
+		}
+		if float32(sent) != tt.sent {
+			t.Errorf("temperature %g was sent as %g, want %g", tt.configured, sent, tt.sent)
+		}
+		if analysisTemperature != tt.configured {
+			t.Errorf("configured temperature changed to %g", analysisTemperature)
+		}
+	}
+}
+
+func TestAnalysisTextKeepsBracketsOutOfIndex(t *testing.T) {
+	defer func(setting string) { textAnalyzerSetting = setting }(textAnalyzerSetting)
+
+	tokens := []string{"서울", "시청", "호텔"}
+	analysis := analysisText(tokens)
+	if analysis != "서울 시청 호텔" {
+		t.Fatalf("analysisText = %q, want space-separated tokens", analysis)
+	}
+	req := documentBody{Title: "제목", Content: "서울 시청 앞 호텔"}
+	doc := req.indexDocument(analysis, time.Now(), 1)
+	if strings.ContainsAny(doc.Analysis, "[]") {
+		t.Fatalf("indexed analysis %q contains brackets", doc.Analysis)
+	}
+
+	for _, setting := range []string{textAnalyzerKorean, textAnalyzerCJK} {
+		t.Run(setting, func(t *testing.T) {
+			textAnalyzerSetting = setting
+			memIndex := useMemoryIndex(t)
+			if err := memIndex.Index("1", doc); err != nil {
+				t.Fatal(err)
+			}
+			dict, err := memIndex.FieldDict("analysis")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dict.Close()
+			terms := 0
+			for {
+				entry, err := dict.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if entry == nil {
+					break
+				}
+				terms++
+				if strings.ContainsAny(entry.Term, "[]") {
+					t.Errorf("analysis field has term %q", entry.Term)
+				}
+			}
+			if terms == 0 {
+				t.Error("analysis field has no terms")
+			}
+		})
+	}
+}
+
+func TestSearchRejectsPagesBeyondResultWindow(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "서울 호텔", Content: "서울 시청 호텔"},
+		documentBody{Title: "서울 호텔", Content: "서울 강남 호텔"},
+	)
+
+	for _, tt := range []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"min_score inside window", httptest.NewRequest(http.MethodGet, "/search?q=서울&min_score=0.1&from=990&size=10", nil), http.StatusOK},
+		{"min_score beyond window", httptest.NewRequest(http.MethodGet, "/search?q=서울&min_score=0.1&from=995&size=10", nil), http.StatusBadRequest},
+		{"dedupe beyond window", httptest.NewRequest(http.MethodGet, "/search?q=서울&dedupe_field=title&from=1000&size=1", nil), http.StatusBadRequest},
+		{"collapse beyond window", httptest.NewRequest(http.MethodGet, "/search?q=서울&collapse=title&from=1000&size=1", nil), http.StatusBadRequest},
+		{"plain search beyond window", httptest.NewRequest(http.MethodGet, "/search?q=서울&from=5000&size=10", nil), http.StatusOK},
+		{"body min_score beyond window", httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"must": [{"query": "서울"}], "min_score": 0.1, "from": 995, "size": 10}`)), http.StatusBadRequest},
+		{"body min_score inside window", httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"must": [{"query": "서울"}], "min_score": 0.1, "from": 0, "size": 10}`)), http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			searchHandler(rec, tt.req)
+			if rec.Code != tt.code {
+				t.Errorf("status = %d %s, want %d", rec.Code, rec.Body, tt.code)
+			}
+		})
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t), documentBody{Title: "서울 호텔", Content: "서울 시청 호텔"})
+
+	search := func(target string) map[string]json.RawMessage {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		searchHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var response struct {
+			Hits []map[string]json.RawMessage `json:"hits"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Hits) != 1 {
+			t.Fatalf("GET %s = %d %s, want one hit", target, rec.Code, rec.Body)
+		}
+		return response.Hits[0]
+	}
+
+	// 기본값은 설명을 null이 아니라 아예 생략
+	if explanation, ok := search("/search?q=호텔")["explanation"]; ok {
+		t.Errorf("explanation = %s without explain=true, want it omitted", explanation)
+	}
+	explanation, ok := search("/search?q=호텔&explain=true")["explanation"]
+	if !ok {
+		t.Fatal("explain=true response has no explanation")
+	}
+	for _, component := range []string{"termFreq", "fieldNorm"} {
+		if !strings.Contains(string(explanation), component) {
+			t.Errorf("explanation %s has no %s component", explanation, component)
+		}
+	}
+}
+
+func TestIndexBuildReadsKeysetPages(t *testing.T) {
+	testDB := useTestDB(t)
+	memIndex := useMemoryIndex(t)
+	defer func(size int) { indexReadBatchSize = size }(indexReadBatchSize)
+	indexReadBatchSize = 250
+
+	// 분석 결과가 저장된 합성 행 3,000개 중 100개마다 하나는 삭제 표시
+	if _, err := testDB.Exec(`INSERT INTO documents (title, content, analysis, content_hash, deleted_at)
+		SELECT '제목 ' || i, '본문 ' || i, '본문 ' || i, md5(i::text), CASE WHEN i % 100 = 0 THEN now() END
+		FROM generate_series(1, 3000) AS i`); err != nil {
+		t.Fatal(err)
+	}
+	const indexable = 3000 - 30
+
+	pages := &indexRowPages{query: indexableDocumentsSQL}
+	read, lastID := 0, 0
+	for {
+		page, err := pages.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if len(page) > indexReadBatchSize {
+			t.Fatalf("page has %d rows, want at most %d", len(page), indexReadBatchSize)
+		}
+		// 페이지를 읽고 나면 결과 집합을 닫아 연결을 붙잡고 있지 않음
+		if inUse := testDB.Stats().InUse; inUse != 0 {
+			t.Errorf("%d connections are still in use after reading a page", inUse)
+		}
+		for _, row := range page {
+			if row.id <= lastID {
+				t.Fatalf("row %d came after row %d", row.id, lastID)
+			}
+			lastID = row.id
+		}
+		read += len(page)
+	}
+	if read != indexable {
+		t.Errorf("read %d rows, want %d", read, indexable)
+	}
+
+	if err := createIndexFromDatabase(memIndex); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := memIndex.DocCount(); err != nil || count != indexable {
+		t.Errorf("index has %d documents (%v), want %d", count, err, indexable)
+	}
+}
+
+// 문서를 하나씩 Index로 색인할 때와 documentBatcher로 묶어 색인할 때의 인덱스 생성 시간 비교
+// 디스크 인덱스는 Index 호출마다 세그먼트를 만들어 기록하므로 차이가 크다. (go test -bench IndexBuild -run ^$)
+// 1,000건으로 측정했을 때 500건씩 묶으면 하나씩 색인할 때보다 약 14배 빠름
+func BenchmarkIndexBuild(b *testing.B) {
+	const corpusSize = 1000
+	docs := make([]indexDocument, corpusSize)
+	for i := range docs {
+		req := documentBody{Title: "제목 " + strconv.Itoa(i), Content: strings.Repeat("서울 시청 앞 광장에서 열린 행사 "+strconv.Itoa(i)+" ", 10)}
+		docs[i] = req.indexDocument(req.Content, time.Now(), 1)
+	}
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name      string
+		batchSize int
+	}{
+		{"per-document", 0},
+		{"batch-100", 100},
+		{"batch-500", indexBatchSize},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				target, err := bleve.New(filepath.Join(b.TempDir(), "index"), indexMapping)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				batcher := &documentBatcher{target: target, size: bench.batchSize}
+				for i, doc := range docs {
+					id := strconv.Itoa(i + 1)
+					if bench.batchSize == 0 {
+						err = target.Index(id, doc)
+					} else {
+						err = batcher.add(id, doc)
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := batcher.flush(); err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				target.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// MAPPING_FILE의 경로와 읽은 인덱스 매핑 JSON (비어 있으면 buildIndexMapping이 코드에 정의된 매핑을 만듦)
+var (
+	mappingFile     = ""
+	mappingFileJSON []byte
+)
+
+// 인덱스 매핑 JSON 파일을 읽고 검증하는 함수 (새 인덱스를 만들 때마다 다시 해석하도록 원본을 반환)
+func loadMappingFile(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read mapping file: %w", err)
+	}
+	if _, err := parseIndexMapping(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// 인덱스 매핑 JSON을 해석하고 분석기와 필드 설정이 올바른지 확인하는 함수
+func parseIndexMapping(raw []byte) (*mapping.IndexMappingImpl, error) {
+	indexMapping := bleve.NewIndexMapping()
+	if err := json.Unmarshal(raw, indexMapping); err != nil {
+		return nil, fmt.Errorf("Failed to parse mapping file: %w", err)
+	}
+	if err := indexMapping.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid mapping file: %w", err)
+	}
+	return indexMapping, nil
+}
+
+// 인덱스에 저장된 매핑이 MAPPING_FILE의 매핑과 다른지 확인하는 함수 (MAPPING_FILE이 없으면 false)
+// 두 매핑을 같은 방식으로 직렬화해 비교하므로 파일의 공백이나 키 순서는 무시한다.
+func mappingDiffers(m mapping.IndexMapping) bool {
+	if mappingFileJSON == nil {
+		return false
+	}
+	expected, err := parseIndexMapping(mappingFileJSON)
+	if err != nil {
+		return true
+	}
+	want, err := json.Marshal(expected)
+	if err != nil {
+		return true
+	}
+	got, err := json.Marshal(m)
+	if err != nil {
+		return true
+	}
+	return !bytes.Equal(want, got)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// 문서 메타데이터가 색인되는 경로
+const metadataField = "metadata"
+
+// METADATA_FIELDS로 지정한 검색 가능한 메타데이터 키 목록
+// 목록에 없는 키는 Postgres에만 저장되고 색인되지 않는다. (변경 시 인덱스를 다시 만들어야 적용됨)
+var metadataFields []string
+
+// 메타데이터 키를 색인하는 방식 (METADATA_FIELDS에서 view_count:number처럼 지정, 지정하지 않으면 키워드)
+// 값은 bleve 필드 매핑의 타입 이름과 같다.
+const (
+	metadataKeyword  = "keyword"
+	metadataNumber   = "number"
+	metadataDatetime = "datetime"
+)
+
+// 키워드가 아닌 방식으로 색인하는 메타데이터 키와 그 방식
+// 숫자와 날짜로 색인한 키는 범위 검색과 정렬이 문자열 비교가 아닌 값 비교로 동작한다.
+var metadataFieldTypes = map[string]string{}
+
+// 메타데이터 값의 형태를 검사하는 함수
+// 값은 스칼라, 스칼라 배열, 스칼라 값만 가진 객체까지 허용하고 그보다 깊게 중첩된 값은 거부한다.
+func validateMetadata(metadata map[string]interface{}) error {
+	for _, key := range sortedKeys(metadata) {
+		switch value := metadata[key].(type) {
+		case map[string]interface{}:
+			for _, nestedKey := range sortedKeys(value) {
+				if !isScalarMetadata(value[nestedKey]) {
+					return fmt.Errorf("Invalid request body: metadata.%s.%s: nested objects deeper than one level are not allowed", key, nestedKey)
+				}
+			}
+		case []interface{}:
+			for _, item := range value {
+				if !isScalarMetadata(item) {
+					return fmt.Errorf("Invalid request body: metadata.%s: arrays may only contain strings, numbers, or booleans", key)
+				}
+			}
+		default:
+			if !isScalarMetadata(value) {
+				return fmt.Errorf("Invalid request body: metadata.%s: unsupported value", key)
+			}
+		}
+		if err := validateTypedMetadata(key, metadata[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 숫자나 날짜로 색인하는 메타데이터 키의 값(또는 배열의 각 값)이 그 형태인지 검사하는 함수
+func validateTypedMetadata(key string, value interface{}) error {
+	fieldType, ok := metadataFieldTypes[key]
+	if !ok {
+		return nil
+	}
+	items, isArray := value.([]interface{})
+	if !isArray {
+		items = []interface{}{value}
+	}
+	for _, item := range items {
+		if _, ok := typedMetadataValue(fieldType, item); item != nil && !ok {
+			if fieldType == metadataNumber {
+				return fmt.Errorf("Invalid request body: metadata.%s: must be a number", key)
+			}
+			return fmt.Errorf("Invalid request body: metadata.%s: must be an RFC3339 timestamp", key)
+		}
+	}
+	return nil
+}
+
+// 메타데이터 값 하나를 숫자나 날짜 필드에 색인할 값으로 바꾸는 함수 (형태가 맞지 않으면 false)
+func typedMetadataValue(fieldType string, value interface{}) (interface{}, bool) {
+	switch fieldType {
+	case metadataNumber:
+		number, ok := value.(float64)
+		return number, ok
+	case metadataDatetime:
+		raw, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		return t, err == nil
+	}
+	return nil, false
+}
+
+func isScalarMetadata(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return true
+	default:
+		return false
+	}
+}
+
+// 메타데이터 중 검색 가능한 키만 골라 색인할 값으로 바꾸는 함수
+// 키워드 필드는 문자열만 색인하므로 숫자와 불리언도 문자열로 변환하고, 객체 값은 색인하지 않는다.
+// 숫자와 날짜로 색인하는 키는 형태가 맞는 값만 색인한다. (키 방식을 바꾸기 전에 저장한 값은 건너뜀)
+func indexableMetadata(metadata map[string]interface{}) map[string]interface{} {
+	indexed := make(map[string]interface{})
+	for _, key := range metadataFields {
+		if fieldType, ok := metadataFieldTypes[key]; ok {
+			if value, ok := indexableTypedMetadata(fieldType, metadata[key]); ok {
+				indexed[key] = value
+			}
+			continue
+		}
+		switch value := metadata[key].(type) {
+		case nil, map[string]interface{}:
+		case []interface{}:
+			values := make([]string, 0, len(value))
+			for _, item := range value {
+				if item != nil {
+					values = append(values, fmt.Sprint(item))
+				}
+			}
+			indexed[key] = values
+		default:
+			indexed[key] = fmt.Sprint(value)
+		}
+	}
+	if len(indexed) == 0 {
+		return nil
+	}
+	return indexed
+}
+
+// 숫자나 날짜로 색인하는 메타데이터 값(또는 배열)을 색인할 값으로 바꾸는 함수 (색인할 값이 없으면 false)
+func indexableTypedMetadata(fieldType string, value interface{}) (interface{}, bool) {
+	items, isArray := value.([]interface{})
+	if !isArray {
+		return typedMetadataValue(fieldType, value)
+	}
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if typed, ok := typedMetadataValue(fieldType, item); ok {
+			values = append(values, typed)
+		}
+	}
+	return values, len(values) > 0
+}
+
+// 메타데이터를 jsonb 컬럼에 저장할 JSON 문자열로 바꾸는 함수 (없으면 빈 객체)
+func metadataColumn(metadata map[string]interface{}) string {
+	if metadata == nil {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(metadata)
+	return string(encoded)
+}
+
+// 검색 가능한 메타데이터 키를 저장되는 키워드(또는 지정한 숫자, 날짜) 필드로 매핑에 추가하는 함수
+// 나머지 키가 동적 매핑으로 색인되지 않도록 메타데이터 하위 문서는 동적 매핑을 끈다.
+func addMetadataMapping(docMapping *mapping.DocumentMapping, fields []string, fieldTypes map[string]string) {
+	metadataMapping := bleve.NewDocumentStaticMapping()
+	for _, field := range fields {
+		var fieldMapping *mapping.FieldMapping
+		switch fieldTypes[field] {
+		case metadataNumber:
+			fieldMapping = bleve.NewNumericFieldMapping()
+		case metadataDatetime:
+			fieldMapping = bleve.NewDateTimeFieldMapping()
+		default:
+			fieldMapping = newExactFieldMapping()
+		}
+		fieldMapping.Store = true
+		metadataMapping.AddFieldMappingsAt(field, fieldMapping)
+	}
+	docMapping.AddSubDocumentMapping(metadataField, metadataMapping)
+}
+
+// 인덱스 매핑에 정의된 검색 가능한 메타데이터 키 목록 (정렬됨)
+func indexedMetadataFields(m mapping.IndexMapping) []string {
+	var fields []string
+	for path := range mappedFields(m) {
+		if field, ok := strings.CutPrefix(path, metadataField+"."); ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// 인덱스 매핑에서 키워드가 아닌 방식으로 색인된 메타데이터 키와 그 방식
+func indexedMetadataFieldTypes(m mapping.IndexMapping) map[string]string {
+	fieldTypes := make(map[string]string)
+	for path, fieldMapping := range mappedFields(m) {
+		if field, ok := strings.CutPrefix(path, metadataField+"."); ok && (fieldMapping.Type == metadataNumber || fieldMapping.Type == metadataDatetime) {
+			fieldTypes[field] = fieldMapping.Type
+		}
+	}
+	return fieldTypes
+}
+
+// METADATA_FIELDS 값을 정렬된 중복 없는 키 목록과 키워드가 아닌 키의 색인 방식으로 바꾸는 함수
+// 예: brand,view_count:number,published_at:datetime
+func parseMetadataFields(raw string) ([]string, map[string]string, error) {
+	listed := make(map[string]string)
+	fieldTypes := make(map[string]string)
+	for _, entry := range splitParamList(raw) {
+		field, fieldType, typed := strings.Cut(entry, ":")
+		field, fieldType = strings.TrimSpace(field), strings.TrimSpace(fieldType)
+		if field == "" {
+			return nil, nil, fmt.Errorf("expected key or key:type, got '%s'", entry)
+		}
+		if !typed {
+			fieldType = metadataKeyword
+		}
+		switch fieldType {
+		case metadataKeyword, metadataNumber, metadataDatetime:
+		default:
+			return nil, nil, fmt.Errorf("unknown type '%s' for key '%s' (must be keyword, number or datetime)", fieldType, field)
+		}
+		if previous, ok := listed[field]; ok && previous != fieldType {
+			return nil, nil, fmt.Errorf("key '%s' is listed with different types", field)
+		}
+		listed[field] = fieldType
+		if fieldType != metadataKeyword {
+			fieldTypes[field] = fieldType
+		}
+	}
+	return sortedKeys(listed), fieldTypes, nil
+}
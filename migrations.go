@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// 관리자가 한 번만 실행하는 데이터 이관 (이름 -> 실행할 SQL)
+// postgres.sql은 시작할 때마다 다시 적용되므로, 두 번 실행하면 데이터가 망가지는 이관은 여기에 두고
+// 실행 기록을 schema_migrations에 남겨 같은 이관을 다시 요청하면 409를 반환한다.
+var dataMigrations = map[string]string{
+	// 이전 버전은 형태소 분석 토큰을 Go 슬라이스 형식("[서울 시청 호텔]")으로 저장했음
+	// 괄호를 벗겨 공백으로 구분한 토큰만 남김 (다시 실행하면 괄호로 시작하고 끝나는 정상 토큰의 글자가 잘리므로 한 번만 실행)
+	"strip-analysis-brackets": "UPDATE documents SET analysis = substr(analysis, 2, length(analysis) - 2) WHERE analysis LIKE '[%]'",
+}
+
+// 데이터 이관 결과
+type migrationResult struct {
+	Migration string `json:"migration"`
+	// 바뀐 행 수
+	Updated int64 `json:"updated"`
+	// 바뀐 행을 인덱스에 반영하려고 시작한 증분 재색인 (바뀐 행이 없으면 생략)
+	Reindex *reindexJob `json:"reindex,omitempty"`
+}
+
+// 데이터 이관 핸들러 (POST /admin/migrations/{name})
+// 실행 기록과 데이터 변경을 한 트랜잭션에서 처리하므로, 실패하면 기록도 남지 않아 다시 실행할 수 있다.
+// 바뀐 행은 updated_at이 갱신되므로 이어서 증분 재색인을 시작해 인덱스에도 반영한다.
+func migrationHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	statement, ok := dataMigrations[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown migration: %s", name), http.StatusNotFound)
+		return
+	}
+	ctx := r.Context()
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	recorded, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record migration: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if n, err := recorded.RowsAffected(); err == nil && n == 0 {
+		http.Error(w, fmt.Sprintf("Migration already applied: %s", name), http.StatusConflict)
+		return
+	}
+
+	changed, err := tx.ExecContext(ctx, statement)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to apply migration %s: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	updated, err := changed.RowsAffected()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to apply migration %s: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to commit migration %s: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Migration %s updated %d documents", name, updated)
+
+	result := migrationResult{Migration: name, Updated: updated}
+	if live, ok := index.(*swappableIndex); ok && updated > 0 {
+		job, _, err := startReindex(live, reindexIncremental)
+		if err != nil {
+			log.Printf("Failed to start reindex after migration %s: %v", name, err)
+		} else {
+			result.Reindex = &job
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMigrationHandlerRejectsUnknownMigration(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/migrations/{name}", migrationHandler)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/migrations/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestStripAnalysisBracketsRunsOnce(t *testing.T) {
+	testDB := useTestDB(t)
+	useMemoryIndex(t)
+
+	// 이전 버전이 저장한 Go 슬라이스 형식과, 괄호로 시작하고 끝나는 정상 토큰
+	if _, err := testDB.Exec("INSERT INTO documents (content, analysis) VALUES ('a', '[서울 시청 호텔]'), ('b', '[[주의]]')"); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/migrations/{name}", migrationHandler)
+	run := func() int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/migrations/strip-analysis-brackets", nil))
+		return rec.Code
+	}
+
+	if code := run(); code != http.StatusOK {
+		t.Fatalf("first run status = %d, want %d", code, http.StatusOK)
+	}
+	if code := run(); code != http.StatusConflict {
+		t.Fatalf("second run status = %d, want %d", code, http.StatusConflict)
+	}
+
+	for content, want := range map[string]string{"a": "서울 시청 호텔", "b": "[주의]"} {
+		var analysis string
+		if err := testDB.QueryRow("SELECT analysis FROM documents WHERE content = $1", content).Scan(&analysis); err != nil {
+			t.Fatal(err)
+		}
+		if analysis != want {
+			t.Errorf("analysis of %q = %q, want %q", content, analysis, want)
+		}
+	}
+}
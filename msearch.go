@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// 일괄 검색의 최대 요청 수와 동시에 실행할 검색 수
+const (
+	maxMultiSearchRequests = 50
+	multiSearchWorkers     = 4
+)
+
+// 일괄 검색의 개별 결과 (실패한 요청은 error만 채워짐)
+type multiSearchItem struct {
+	*searchResponse
+	Error *multiSearchError `json:"error,omitempty"`
+}
+
+// 일괄 검색 개별 요청의 오류 (status는 단일 검색이었다면 반환했을 HTTP 상태 코드)
+type multiSearchError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+}
+
+// 일괄 검색 핸들러 (POST /msearch)
+// 본문은 POST /search 본문의 배열이며, 결과는 요청과 같은 순서의 배열로 반환한다.
+// 개별 요청이 실패해도 나머지 결과는 그대로 반환하고 해당 위치에만 error를 담는다.
+func multiSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var bodies []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&bodies); err != nil {
+		http.Error(w, "Invalid request body: expected an array of search requests", http.StatusBadRequest)
+		return
+	}
+	if len(bodies) > maxMultiSearchRequests {
+		http.Error(w, fmt.Sprintf("Invalid request body: at most %d search requests are allowed", maxMultiSearchRequests), http.StatusBadRequest)
+		return
+	}
+
+	// 고정된 수의 작업자가 요청 번호를 받아 검색하고 같은 위치에 결과를 기록
+	results := make([]multiSearchItem, len(bodies))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(multiSearchWorkers, len(bodies)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = runMultiSearchItem(r, bodies[i])
+			}
+		}()
+	}
+	for i := range bodies {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"responses": results}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// 일괄 검색의 요청 하나를 실행하는 함수
+func runMultiSearchItem(r *http.Request, body json.RawMessage) multiSearchItem {
+	spec, err := newSearchSpecFromBody(bytes.NewReader(body), index.Mapping())
+	if err != nil {
+		searchErr := &multiSearchError{Status: http.StatusBadRequest, Message: err.Error()}
+		var clauseErr *clauseError
+		if errors.As(err, &clauseErr) {
+			searchErr.Path = clauseErr.Path
+		}
+		return multiSearchItem{Error: searchErr}
+	}
+
+	response, err := runSearch(r.Context(), spec)
+	if errors.Is(err, errSearchTimeout) {
+		return multiSearchItem{Error: &multiSearchError{Status: http.StatusGatewayTimeout, Message: err.Error()}}
+	}
+	if err != nil {
+		return multiSearchItem{Error: &multiSearchError{Status: http.StatusInternalServerError, Message: err.Error()}}
+	}
+	return multiSearchItem{searchResponse: response}
+}
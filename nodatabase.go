@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 데이터베이스 없이는 쓸 수 없는 기능임을 나타내는 오류 (501로 응답)
+// 메모리 인덱스 모드에서 POSTGRES_CONN을 설정하지 않으면 문서를 인덱스에만 저장하므로, 원본 행이 필요한 기능은 쓸 수 없다.
+var errNoDatabase = errors.New("Not available without a database (POSTGRES_CONN is not set)")
+
+// 데이터베이스가 없으면 요청을 501로 거부하는 핸들러 래퍼
+func requireDatabase(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if db == nil {
+			http.Error(w, errNoDatabase.Error(), http.StatusNotImplemented)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// 데이터베이스 없이 추가한 문서의 ID를 부여하는 상태 (지금까지 쓴 가장 큰 ID)
+var memoryDocuments struct {
+	mu     sync.Mutex
+	lastID int
+}
+
+// 데이터베이스 없이 분석한 문서를 메모리 인덱스에만 추가하고 응답하는 함수
+// ID는 지정한 값이나 지금까지 쓴 가장 큰 ID의 다음 값을 쓰며, 이미 색인된 ID를 지정하면 409로 거부한다.
+// 같은 external_id의 문서를 찾아 갱신하려면 저장된 행이 필요하므로 external_id가 있으면 501로 거부하고,
+// 내용이 같은 문서도 찾지 않는다.
+func insertWithoutDatabase(w http.ResponseWriter, r *http.Request, req *documentBody, tokens []string, source string) {
+	if req.ExternalID != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: external_id: %v", errNoDatabase), http.StatusNotImplemented)
+		return
+	}
+	createdAt := time.Now()
+	if req.CreatedAt != nil {
+		createdAt = *req.CreatedAt
+	}
+
+	memoryDocuments.mu.Lock()
+	defer memoryDocuments.mu.Unlock()
+	id := memoryDocuments.lastID + 1
+	if req.ID != nil {
+		id = *req.ID
+		_, indexed, err := indexedContent(strconv.Itoa(id))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if indexed {
+			http.Error(w, (&documentExistsError{id: id}).Error(), http.StatusConflict)
+			return
+		}
+	}
+	if err := index.Index(strconv.Itoa(id), req.indexDocument(analysisText(tokens), createdAt, 1)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to index data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	memoryDocuments.lastID = max(memoryDocuments.lastID, id)
+	suggester.markDirty()
+
+	setVersionHeader(w, 1)
+	writeInsertResponse(w, r, http.StatusCreated, analyzedInsertResponse(id, tokens, source))
+}
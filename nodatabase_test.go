@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 데이터베이스 없이 실행하도록 전역 DB를 비우고 문서 ID를 1부터 다시 부여하는 함수
+func useNoDatabase(t *testing.T) {
+	t.Helper()
+	previous, lastID := db, memoryDocuments.lastID
+	db, memoryDocuments.lastID = nil, 0
+	t.Cleanup(func() { db, memoryDocuments.lastID = previous, lastID })
+}
+
+func TestInsertWithoutDatabase(t *testing.T) {
+	useNoDatabase(t)
+	useMemoryIndex(t)
+	useFakeOpenAI(t)
+
+	insert := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		insertHandler(rec, httptest.NewRequest(http.MethodPost, "/insert", strings.NewReader(body)))
+		return rec
+	}
+
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"generated ID", `{"title": "서울", "content": "서울 시청 호텔"}`, http.StatusCreated},
+		{"explicit ID", `{"id": 7, "title": "부산", "content": "부산 해운대 호텔"}`, http.StatusCreated},
+		{"duplicate ID", `{"id": 7, "title": "대구", "content": "대구 동성로"}`, http.StatusConflict},
+		{"external ID", `{"external_id": "a-1", "title": "광주", "content": "광주 충장로"}`, http.StatusNotImplemented},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := insert(tt.body); rec.Code != tt.code {
+				t.Errorf("POST /insert %s = %d %s, want %d", tt.body, rec.Code, rec.Body, tt.code)
+			}
+		})
+	}
+
+	// 가짜 분석 결과로 색인되므로 두 문서 모두 검색되고, 다음 ID는 지정한 ID 뒤에서 이어짐
+	code, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?q=분석", nil))
+	if code != http.StatusOK || !slices.Equal(ids, []string{"1", "7"}) {
+		t.Errorf("search = %d %v, want 200 [1 7]", code, ids)
+	}
+	if rec := insert(`{"title": "대전", "content": "대전 은행동"}`); rec.Code != http.StatusCreated || !documentIndexed(t, 8) {
+		t.Errorf("POST /insert after an explicit ID = %d %s, want 201 with ID 8", rec.Code, rec.Body)
+	}
+}
+
+func TestRequireDatabase(t *testing.T) {
+	useNoDatabase(t)
+	called := false
+	rec := httptest.NewRecorder()
+	requireDatabase(func(http.ResponseWriter, *http.Request) { called = true })(rec, httptest.NewRequest(http.MethodGet, "/documents", nil))
+	if rec.Code != http.StatusNotImplemented || called {
+		t.Errorf("status = %d and handler called = %t, want 501 without calling the handler", rec.Code, called)
+	}
+}
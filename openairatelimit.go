@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OpenAI API 요청 속도 제한 (분당 요청 수 OPENAI_REQUESTS_PER_MINUTE, 분당 토큰 수 OPENAI_TOKENS_PER_MINUTE, 0이면 제한하지 않음)
+// 대량 가져오기가 조직의 한도를 다 써서 단건 색인까지 429로 실패하지 않도록, 형태소 분석을 요청하는 모든 경로가 이 제한을 함께 쓴다.
+var openaiLimiter = newOpenAIRateLimiter(0, 0)
+
+// 분당 한도만큼 채워지는 토큰 버킷 (rate가 0이면 제한하지 않음)
+type tokenBucket struct {
+	// 초당 채워지는 양과 최대로 쌓이는 양 (1분 치)
+	rate      float64
+	capacity  float64
+	available float64
+	updated   time.Time
+}
+
+func newTokenBucket(perMinute int) tokenBucket {
+	return tokenBucket{rate: float64(perMinute) / 60, capacity: float64(perMinute), available: float64(perMinute), updated: time.Now()}
+}
+
+// 마지막으로 갱신한 뒤 지난 시간만큼 버킷을 채우는 함수
+func (b *tokenBucket) refill(now time.Time) {
+	if b.rate == 0 {
+		return
+	}
+	b.available = min(b.capacity, b.available+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+}
+
+// amount만큼 가져가고, 모자라면 모자란 만큼 채워질 때까지 기다려야 하는 시간을 반환하는 함수
+// 모자란 양은 빌려 가므로(available이 음수) 뒤에 온 요청은 먼저 온 요청보다 더 기다린다.
+func (b *tokenBucket) take(amount float64) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.available -= amount
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / b.rate * float64(time.Second))
+}
+
+// 쓰지 않은 양을 돌려주는 함수 (amount가 음수면 더 가져감)
+func (b *tokenBucket) give(amount float64) {
+	if b.rate == 0 {
+		return
+	}
+	b.available = min(b.capacity, b.available+amount)
+}
+
+// 버킷을 얼마나 썼는지 (0이면 가득 참, 1을 넘으면 빌려 간 만큼 요청이 기다리는 중)
+func (b *tokenBucket) saturation() float64 {
+	if b.rate == 0 {
+		return 0
+	}
+	return (b.capacity - b.available) / b.capacity
+}
+
+// OpenAI API 요청 속도 제한기
+type openaiRateLimiter struct {
+	mu       sync.Mutex
+	requests tokenBucket
+	tokens   tokenBucket
+	// 지금 제한 때문에 기다리는 요청 수, 지금까지 기다린 요청 수와 기다린 시간
+	waiting       int
+	throttled     int64
+	throttledTime time.Duration
+	// OpenAI가 429로 거절한 응답 수
+	rateLimited int64
+}
+
+func newOpenAIRateLimiter(requestsPerMinute, tokensPerMinute int) *openaiRateLimiter {
+	return &openaiRateLimiter{requests: newTokenBucket(requestsPerMinute), tokens: newTokenBucket(tokensPerMinute)}
+}
+
+// 요청 하나와 tokens만큼의 토큰을 쓸 수 있을 때까지 기다리는 함수 (ctx가 끝나면 가져간 양을 돌려주고 ctx의 오류를 반환)
+func (l *openaiRateLimiter) wait(ctx context.Context, tokens int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+	delay := max(l.requests.take(1), l.tokens.take(float64(tokens)))
+	if delay == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.waiting++
+	l.throttled++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waiting--
+	l.throttledTime += time.Since(now)
+	if err != nil {
+		l.requests.give(1)
+		l.tokens.give(float64(tokens))
+	}
+	return err
+}
+
+// 요청 전에 추정한 토큰 수를 응답에 기록된 실제 사용량으로 바로잡는 함수
+func (l *openaiRateLimiter) settle(estimated, used int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(time.Now())
+	l.tokens.give(float64(estimated - used))
+}
+
+// OpenAI가 429로 거절한 응답을 기록하는 함수
+func (l *openaiRateLimiter) recordRateLimited() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rateLimited++
+}
+
+// 속도 제한 상태 (/admin/stats)
+// saturation이 1에 가깝거나 waiting이 있으면 이 서버의 제한 때문에, openai_rate_limited가 늘면 OpenAI의 한도 때문에 느려지는 중이다.
+type openaiRateLimitStatus struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+	// 두 버킷 중 더 많이 쓴 쪽의 사용률 (1을 넘으면 그만큼 요청이 밀려 있음)
+	Saturation float64 `json:"saturation"`
+	Waiting    int     `json:"waiting"`
+	// 시작한 뒤 제한 때문에 기다린 요청 수와 기다린 시간의 합
+	ThrottledRequests int64   `json:"throttled_requests"`
+	ThrottledSeconds  float64 `json:"throttled_seconds"`
+	// 시작한 뒤 OpenAI가 429로 거절한 응답 수
+	OpenAIRateLimited int64 `json:"openai_rate_limited"`
+}
+
+// 현재 속도 제한 상태를 반환하는 함수
+func (l *openaiRateLimiter) status() openaiRateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.requests.refill(now)
+	l.tokens.refill(now)
+	saturation := max(l.requests.saturation(), l.tokens.saturation())
+	return openaiRateLimitStatus{
+		RequestsPerMinute: int(l.requests.capacity),
+		TokensPerMinute:   int(l.tokens.capacity),
+		Saturation:        math.Round(saturation*1e3) / 1e3,
+		Waiting:           l.waiting,
+		ThrottledRequests: l.throttled,
+		ThrottledSeconds:  math.Round(l.throttledTime.Seconds()*1e3) / 1e3,
+		OpenAIRateLimited: l.rateLimited,
+	}
+}
+
+// 채팅 완성 요청이 쓸 토큰 수를 추정하는 함수 (메시지의 토큰 수와 그에 비례한 응답 토큰 수, 응답은 max_tokens까지)
+func estimateRequestTokens(req openai.ChatCompletionRequest) int {
+	prompt := 0
+	for _, message := range req.Messages {
+		prompt += estimateTokens(message.Content)
+	}
+	completion := int(math.Ceil(float64(prompt) * analysisCompletionRatio))
+	if req.MaxTokens > 0 {
+		completion = min(completion, req.MaxTokens)
+	}
+	return prompt + completion
+}
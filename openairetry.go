@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OpenAI API 요청 최대 시도 횟수 (OPENAI_MAX_ATTEMPTS로 변경, 1이면 재시도하지 않음)
+var openaiMaxAttempts = 5
+
+// 재시도를 포함해 OpenAI API 요청 하나에 쓸 수 있는 전체 시간 (OPENAI_RETRY_DEADLINE로 변경)
+var openaiRetryDeadline = 2 * time.Minute
+
+// 재시도 대기 시간 (첫 재시도는 openaiBaseBackoff 안팎이고 재시도마다 두 배씩 늘어 openaiMaxBackoff를 넘지 않음)
+const (
+	openaiBaseBackoff = 500 * time.Millisecond
+	openaiMaxBackoff  = 30 * time.Second
+)
+
+// 응답의 Retry-After 값을 요청한 쪽에 전달하기 위한 context 키
+// go-openai의 오류에는 응답 헤더가 없으므로 HTTP 클라이언트의 Transport에서 헤더를 읽어 context에 담긴 변수에 기록한다.
+type retryAfterKey struct{}
+
+// 응답의 Retry-After 헤더를 기록하는 Transport
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if hint, ok := req.Context().Value(retryAfterKey{}).(*time.Duration); ok && resp != nil {
+		*hint = parseRetryAfter(resp.Header)
+	}
+	return resp, err
+}
+
+// Retry-After 헤더를 대기 시간으로 바꾸는 함수 (없거나 잘못된 값이면 0)
+// OpenAI가 보내는 밀리초 단위의 retry-after-ms를 먼저 보고, Retry-After는 초 또는 HTTP 날짜 형식을 받는다.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("Retry-After-Ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// OpenAI API 클라이언트를 만드는 함수 (재시도할 때 Retry-After를 따르도록 Transport를 감쌈)
+func newOpenAIClient(apiKey string) *openai.Client {
+	config := openai.DefaultConfig(apiKey)
+	config.HTTPClient = &http.Client{Transport: retryAfterTransport{base: http.DefaultTransport}}
+	return openai.NewClientWithConfig(config)
+}
+
+// 다시 시도하면 성공할 수 있는 오류인지 확인하는 함수
+// 429와 5xx, 408, 응답을 받지 못한 연결 오류는 재시도하고, 그 밖의 4xx와 사용량 한도 초과(insufficient_quota)는 바로 실패한다.
+func retryableOpenAIError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == "insufficient_quota" {
+		return false
+	}
+	code := openaiStatusCode(err)
+	return code < http.StatusBadRequest || code >= http.StatusInternalServerError ||
+		code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// OpenAI API 오류의 HTTP 상태 코드 (응답을 받지 못한 오류면 0)
+func openaiStatusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// attempt번째 시도가 실패한 뒤 기다릴 시간 (지수 백오프의 절반에 무작위 지터를 더함)
+func openaiBackoff(attempt int) time.Duration {
+	backoff := openaiMaxBackoff
+	if attempt < 16 {
+		backoff = min(openaiBaseBackoff<<(attempt-1), openaiMaxBackoff)
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// 실패하면 지수 백오프로 다시 시도하며 채팅 완성을 요청하는 함수
+// Retry-After가 있으면 그 시간만큼 기다리고, 다음 시도가 전체 제한 시간을 넘기면 기다리지 않고 마지막 오류를 반환한다.
+// 호출 측 context가 끝나면(클라이언트가 연결을 끊는 등) 바로 멈추므로 버려진 요청이 백그라운드에서 계속 재시도되지 않는다.
+// 재시도를 포함한 모든 시도는 openaiLimiter의 속도 제한을 기다린 뒤에 보낸다.
+func createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if openaiRetryDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, openaiRetryDeadline)
+		defer cancel()
+	}
+
+	estimated := estimateRequestTokens(req)
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := openaiLimiter.wait(ctx, estimated); err != nil {
+			if lastErr != nil {
+				return openai.ChatCompletionResponse{}, attemptsError(lastErr, attempt-1)
+			}
+			return openai.ChatCompletionResponse{}, fmt.Errorf("Gave up waiting for the OpenAI rate limit: %w", err)
+		}
+		var retryAfter time.Duration
+		resp, err := openaiClient.CreateChatCompletion(context.WithValue(ctx, retryAfterKey{}, &retryAfter), req)
+		if err == nil {
+			if resp.Usage.TotalTokens > 0 {
+				openaiLimiter.settle(estimated, resp.Usage.TotalTokens)
+			}
+			return resp, nil
+		}
+		lastErr = err
+		if openaiStatusCode(err) == http.StatusTooManyRequests {
+			openaiLimiter.recordRateLimited()
+		}
+		if attempt >= openaiMaxAttempts || !retryableOpenAIError(err) || ctx.Err() != nil {
+			return resp, attemptsError(err, attempt)
+		}
+
+		wait := openaiBackoff(attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, attemptsError(err, attempt)
+		}
+		log.Printf("OpenAI API request failed (attempt %d of %d), retrying in %v: %v", attempt, openaiMaxAttempts, wait.Round(time.Millisecond), err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, attemptsError(err, attempt)
+		}
+	}
+}
+
+// 재시도한 요청이면 시도 횟수를 오류에 붙이는 함수
+func attemptsError(err error, attempts int) error {
+	if attempts == 1 {
+		return err
+	}
+	return fmt.Errorf("%w (gave up after %d attempts)", err, attempts)
+}
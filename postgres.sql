@@ -1,5 +1,136 @@
-CREATE TABLE documents (
+CREATE TABLE IF NOT EXISTS documents (
     id SERIAL PRIMARY KEY,
-    original_content TEXT,
-    analyzed_content TEXT
-);
\ No newline at end of file
+    external_id TEXT UNIQUE,
+    title TEXT,
+    content TEXT,
+    -- 형태소 분석 결과 (content에는 사용자가 보낸 원문을 저장)
+    analysis TEXT,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    price DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1,
+    -- 삭제 표시 시각 (NULL이 아니면 인덱스에서 제외되며 복구 가능)
+    deleted_at TIMESTAMPTZ,
+    -- 만료 시각 (지나면 검색에서 제외되고 주기적으로 삭제됨)
+    expires_at TIMESTAMPTZ,
+    -- 정규화한 본문의 SHA-256 (중복 문서 판정에 사용)
+    content_hash TEXT,
+    -- 문서 종류 (NULL이면 기본 종류, 종류마다 다른 인덱스 매핑으로 색인됨)
+    doc_type TEXT,
+    -- 점수순 검색에서 점수에 곱하는 문서 가중치
+    boost DOUBLE PRECISION NOT NULL DEFAULT 1,
+    -- 마지막으로 바뀐 시각 (트리거로 갱신하며 증분 재색인에 사용)
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 완전히 삭제된 문서 ID (증분 재색인이 인덱스에서도 지울 수 있도록 트리거로 기록)
+CREATE TABLE IF NOT EXISTS document_tombstones (
+    id INTEGER PRIMARY KEY,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+-- 이름 붙은 인덱스 (고객별로 분리된 문서 모음)
+CREATE TABLE IF NOT EXISTS indexes (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS search_templates (
+    name TEXT PRIMARY KEY,
+    template JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 형태소 분석 결과 캐시 (key는 모델, 프롬프트 버전, 본문의 SHA-256)
+CREATE TABLE IF NOT EXISTS analysis_cache (
+    key TEXT PRIMARY KEY,
+    model TEXT NOT NULL,
+    prompt_version INTEGER NOT NULL,
+    tokens TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS analysis_cache_model_idx ON analysis_cache (model);
+
+-- 한 번만 실행해야 하는 데이터 이관의 실행 기록 (POST /admin/migrations/{name})
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    name TEXT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 기존 테이블 마이그레이션
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS price DOUBLE PRECISION;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+-- analysis 컬럼 추가 이전의 행은 content에 원문 대신 형태소 분석 결과가 저장되어 있음
+-- 원문은 복구할 수 없으므로 컬럼을 처음 추가할 때 한 번만 분석 결과를 analysis로 옮겨 재색인 시 다시 분석하지 않게 함
+-- (이후에 analysis가 NULL인 행은 재색인할 때 원문을 다시 분석함)
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'documents' AND column_name = 'analysis'
+    ) THEN
+        ALTER TABLE documents ADD COLUMN analysis TEXT;
+        UPDATE documents SET analysis = content;
+    END IF;
+END;
+$$;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS external_id TEXT UNIQUE;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS documents_expires_at_idx ON documents (expires_at) WHERE expires_at IS NOT NULL;
+-- 기존 행의 해시는 NULL로 남으며 문서를 갱신할 때 채워짐 (NFC 정규화를 SQL로 재현할 수 없으므로)
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_hash TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS documents_updated_at_idx ON documents (updated_at);
+CREATE INDEX IF NOT EXISTS document_tombstones_deleted_at_idx ON document_tombstones (deleted_at);
+-- 문서가 속한 이름 붙은 인덱스 (NULL이면 기본 인덱스, 인덱스를 지우면 문서도 지워짐)
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tenant TEXT REFERENCES indexes(name) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS documents_tenant_idx ON documents (tenant) WHERE tenant IS NOT NULL;
+-- 내용이 같은 문서는 같은 인덱스 안에서만 중복이며, 삭제 표시된 문서는 같은 내용의 새 문서를 막지 않음
+DROP INDEX IF EXISTS documents_content_hash_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS documents_tenant_content_hash_idx ON documents (COALESCE(tenant, ''), content_hash) WHERE deleted_at IS NULL;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS doc_type TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS boost DOUBLE PRECISION NOT NULL DEFAULT 1;
+-- analysis를 만든 곳 (openai, 또는 OpenAI 장애로 로컬 분석기가 대신 분석했으면 local)
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS analysis_source TEXT NOT NULL DEFAULT 'openai';
+CREATE INDEX IF NOT EXISTS documents_local_analysis_idx ON documents (id) WHERE analysis_source = 'local';
+
+-- 행을 갱신할 때마다 updated_at을 현재 트랜잭션 시각으로 바꿈
+CREATE OR REPLACE FUNCTION documents_set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS documents_set_updated_at ON documents;
+CREATE TRIGGER documents_set_updated_at BEFORE UPDATE ON documents
+    FOR EACH ROW EXECUTE FUNCTION documents_set_updated_at();
+
+-- 행을 완전히 삭제하면 ID를 남기고, 같은 ID로 다시 추가하면 지움
+CREATE OR REPLACE FUNCTION documents_record_tombstone() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO document_tombstones(id, deleted_at) VALUES (OLD.id, now())
+            ON CONFLICT (id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at;
+        RETURN OLD;
+    END IF;
+    DELETE FROM document_tombstones WHERE id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS documents_record_tombstone ON documents;
+CREATE TRIGGER documents_record_tombstone AFTER INSERT OR DELETE ON documents
+    FOR EACH ROW EXECUTE FUNCTION documents_record_tombstone();
@@ -0,0 +1,1023 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/geo"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/numeric"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"golang.org/x/text/width"
+)
+
+// 검색 쿼리 종류
+const (
+	queryTypeMatch    = "match"
+	queryTypePhrase   = "phrase"
+	queryTypePrefix   = "prefix"
+	queryTypeTerm     = "term"
+	queryTypeWildcard = "wildcard"
+	queryTypeRegexp   = "regexp"
+	queryTypeMulti    = "multi_match"
+	queryTypeBoolean  = "boolean"
+	queryTypeString   = "query_string"
+)
+
+// 구문 검색에서 허용하는 최대 slop 값 (변형 쿼리 수가 조합적으로 늘어나므로 제한)
+const maxPhraseSlop = 3
+
+// slop이 있는 구문 검색에서 만드는 변형 쿼리의 최대 수 (긴 구문은 slop이 작아도 변형이 많아지므로 제한)
+const maxPhraseVariants = 256
+
+// multi_match 모드의 기본 검색 필드와 가중치 (SEARCH_FIELD_WEIGHTS로 변경 가능)
+var (
+	defaultFieldWeights   = map[string]float64{defaultSearchField: 1}
+	defaultWeightedFields = []string{defaultSearchField}
+)
+
+// 정규식 검색 패턴의 최대 길이 (패턴이 길수록 오토마톤 생성 비용이 커짐)
+const maxRegexpLength = 256
+
+// 요청 파라미터에서 실행할 쿼리 종류를 결정하는 함수
+func searchQueryType(values url.Values) (string, error) {
+	switch syntax := values.Get("syntax"); syntax {
+	case "":
+	case queryTypeString:
+		return queryTypeString, nil
+	default:
+		return "", fmt.Errorf("Invalid query parameter 'syntax': unknown syntax '%s'", syntax)
+	}
+	if values.Get("match_phrase") == "true" {
+		return queryTypePhrase, nil
+	}
+	if values.Get("prefix") == "true" {
+		return queryTypePrefix, nil
+	}
+	if values.Get("wildcard") == "true" {
+		return queryTypeWildcard, nil
+	}
+	if values.Get("regexp") == "true" {
+		return queryTypeRegexp, nil
+	}
+	if values.Get("multi_match") == "true" {
+		return queryTypeMulti, nil
+	}
+
+	switch queryType := values.Get("type"); queryType {
+	case "", queryTypeMatch:
+		return queryTypeMatch, nil
+	case queryTypePhrase, queryTypePrefix, queryTypeWildcard, queryTypeRegexp, queryTypeMulti:
+		return queryType, nil
+	default:
+		return "", fmt.Errorf("Invalid query parameter 'type': unknown query type '%s'", queryType)
+	}
+}
+
+// 요청 파라미터에 따라 텍스트 검색 쿼리를 생성하는 함수
+func buildTextQuery(values url.Values, text string, m mapping.IndexMapping) (query.Query, string, error) {
+	queryType, err := searchQueryType(values)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 필드별 가중치 (boost_fields=content:3,analysis:1)
+	boosts, boostFields, err := parseBoostFields(values.Get("boost_fields"))
+	if err != nil {
+		return nil, "", err
+	}
+	if queryType == queryTypeMulti && len(boostFields) == 0 {
+		// 요청에 가중치가 없으면 설정된 기본 필드와 가중치로 검색
+		boosts, boostFields = defaultFieldWeights, defaultWeightedFields
+	}
+	if len(boostFields) > 0 {
+		if queryType == queryTypeString {
+			return nil, "", fmt.Errorf("Invalid query parameter 'boost_fields': cannot be combined with syntax=query_string, use field^boost instead")
+		}
+		if err := validateFields("boost_fields", boostFields, m); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// 언어 제한 (lang=en이면 영어로 감지된 문서의 언어별 본문 필드만 검색)
+	lang, err := parseLanguageParam(values.Get("lang"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 검색 대상 필드 제한 (빈 문자열은 쿼리 종류별 기본 필드를 의미)
+	// fields가 없으면 가중치를 지정한 필드를, 그것도 없으면 기본 필드와 모든 언어별 본문 필드를 검색 대상으로 사용
+	fields := splitParamList(values.Get("fields"))
+	if lang != "" {
+		if queryType == queryTypeString {
+			return nil, "", fmt.Errorf("Invalid query parameter 'lang': cannot be combined with syntax=query_string, use a %s field prefix instead", languageContentField(lang))
+		}
+		if len(fields) > 0 || len(boostFields) > 0 {
+			return nil, "", fmt.Errorf("Invalid query parameter 'lang': cannot be combined with fields or boost_fields")
+		}
+		fields = []string{languageContentField(lang)}
+		if err := validateFields("lang", fields, m); err != nil {
+			return nil, "", err
+		}
+	} else if len(fields) > 0 {
+		if queryType == queryTypeString {
+			return nil, "", fmt.Errorf("Invalid query parameter 'fields': cannot be combined with syntax=query_string, use field prefixes instead")
+		}
+		if err := validateFields("fields", fields, m); err != nil {
+			return nil, "", err
+		}
+	} else if len(boostFields) > 0 {
+		fields = boostFields
+	} else if queryType == queryTypeMatch || queryType == queryTypePhrase {
+		// 언어별 본문 필드는 _all에 포함되지 않으므로 각 필드의 분석기로 따로 검색
+		fields = append([]string{""}, languageContentFields(m)...)
+	} else {
+		fields = []string{""}
+	}
+
+	if queryType == queryTypeString {
+		// bleve 쿼리 문자열 문법 (+content:김치 -content:레시피 title:서울^2)
+		stringQuery := bleve.NewQueryStringQuery(text)
+		if _, err := stringQuery.Parse(); err != nil {
+			return nil, "", fmt.Errorf("Invalid query string syntax: %v", err)
+		}
+		return stringQuery, queryType, nil
+	}
+
+	// 일치/구문 검색은 동의어로 바꾼 검색어도 함께 검색
+	texts := []string{text}
+	if queryType == queryTypeMatch || queryType == queryTypeMulti || queryType == queryTypePhrase {
+		texts = synonyms.expand(text)
+	}
+
+	// 필드나 검색어 변형이 여러 개면 쿼리를 DisjunctionQuery로 묶음
+	var fieldQueries []query.Query
+	for _, field := range fields {
+		for _, variant := range texts {
+			fieldQuery, err := buildFieldQuery(values, queryType, variant, field, m)
+			if err != nil {
+				return nil, "", err
+			}
+			if boost, ok := boosts[field]; ok {
+				fieldQuery.(query.BoostableQuery).SetBoost(boost)
+			}
+			fieldQueries = append(fieldQueries, fieldQuery)
+		}
+	}
+	if len(fieldQueries) == 1 {
+		return fieldQueries[0], queryType, nil
+	}
+	return bleve.NewDisjunctionQuery(fieldQueries...), queryType, nil
+}
+
+// 검색어에 지정할 수 있는 기본 분석기 (매핑에 등록된 사용자 정의 분석기도 허용)
+var builtinQueryAnalyzers = []string{cjk.AnalyzerName, keyword.Name, standard.Name}
+
+// 검색어에 사용할 수 있는 분석기 이름 목록을 반환하는 함수
+func queryAnalyzerNames(m mapping.IndexMapping) []string {
+	names := slices.Clone(builtinQueryAnalyzers)
+	if impl, ok := m.(*mapping.IndexMappingImpl); ok && impl.CustomAnalysis != nil {
+		for _, name := range sortedKeys(impl.CustomAnalysis.Analyzers) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// analyzer 파라미터를 검증하는 함수 (미지정이면 빈 문자열, 필드의 색인 분석기를 사용)
+func parseAnalyzerParam(values url.Values, m mapping.IndexMapping) (string, error) {
+	name := values.Get("analyzer")
+	if name == "" {
+		return "", nil
+	}
+	names := queryAnalyzerNames(m)
+	if !slices.Contains(names, name) || m.AnalyzerNamed(name) == nil {
+		return "", fmt.Errorf("Invalid query parameter 'analyzer': unknown analyzer '%s' (available analyzers: %s)", name, strings.Join(names, ", "))
+	}
+	return name, nil
+}
+
+// 지정한 분석기, 없으면 필드의 색인 분석기를 반환하는 함수
+func queryAnalyzer(m mapping.IndexMapping, field, analyzerName string) analysis.Analyzer {
+	if analyzerName != "" {
+		return m.AnalyzerNamed(analyzerName)
+	}
+	return m.AnalyzerNamed(m.AnalyzerNameForPath(field))
+}
+
+// operator 파라미터를 일치 검색의 텀 결합 방식으로 해석하는 함수 (기본값은 OR)
+func parseMatchOperator(raw string) (query.MatchQueryOperator, error) {
+	switch raw {
+	case "", "or":
+		return query.MatchQueryOperatorOr, nil
+	case "and":
+		return query.MatchQueryOperatorAnd, nil
+	default:
+		return 0, fmt.Errorf("Invalid query parameter 'operator': must be 'and' or 'or'")
+	}
+}
+
+// 검색어의 텀 중 최소 minimum개가 일치해야 하는 쿼리를 만드는 함수
+// MatchQuery는 최소 일치 개수를 지원하지 않으므로 필드 분석기로 나눈 텀마다 MatchQuery를 만들어
+// BooleanQuery의 should 절로 묶는다. 텀 수보다 큰 값은 모든 텀이 일치해야 하는 것으로 처리한다.
+func newMinimumShouldMatchQuery(m mapping.IndexMapping, field, analyzerName, text string, minimum int, newMatchQuery func(string) *query.MatchQuery) query.Query {
+	var terms []string
+	if analyzer := queryAnalyzer(m, field, analyzerName); analyzer != nil {
+		for _, token := range analyzer.Analyze([]byte(text)) {
+			if term := string(token.Term); !slices.Contains(terms, term) {
+				terms = append(terms, term)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return newMatchQuery(text)
+	}
+
+	booleanQuery := bleve.NewBooleanQuery()
+	for _, term := range terms {
+		booleanQuery.AddShould(newMatchQuery(term))
+	}
+	booleanQuery.SetMinShould(float64(min(minimum, len(terms))))
+	return booleanQuery
+}
+
+// boost_fields 파라미터를 필드별 가중치로 해석하는 함수 (필드 순서도 함께 반환)
+func parseBoostFields(raw string) (map[string]float64, []string, error) {
+	boosts := make(map[string]float64)
+	var fields []string
+	for _, entry := range splitParamList(raw) {
+		field, rawBoost, ok := strings.Cut(entry, ":")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return nil, nil, fmt.Errorf("Invalid query parameter 'boost_fields': expected field:boost, got '%s'", entry)
+		}
+		boost, err := strconv.ParseFloat(strings.TrimSpace(rawBoost), 64)
+		if err != nil || boost <= 0 {
+			return nil, nil, fmt.Errorf("Invalid query parameter 'boost_fields': boost for field '%s' must be a positive number", field)
+		}
+		if _, exists := boosts[field]; !exists {
+			fields = append(fields, field)
+		}
+		boosts[field] = boost
+	}
+	return boosts, fields, nil
+}
+
+// 하나의 필드를 대상으로 쿼리 종류에 맞는 쿼리를 생성하는 함수
+func buildFieldQuery(values url.Values, queryType, text, field string, m mapping.IndexMapping) (query.Query, error) {
+	// 오타 허용을 위한 퍼지 검색 설정
+	fuzziness, err := parseFuzziness(values.Get("fuzziness"), text)
+	if err != nil {
+		return nil, err
+	}
+
+	// 분석을 거치지 않는 쿼리는 기본 필드를 대상으로 함
+	termField := field
+	if termField == "" {
+		termField = defaultSearchField
+	}
+
+	// 검색어 분석기 변경 (색인 분석기와 다르게 분석할 때, 예: analyzer=keyword)
+	analyzerName, err := parseAnalyzerParam(values, m)
+	if err != nil {
+		return nil, err
+	}
+	if analyzerName != "" && queryType != queryTypeMatch && queryType != queryTypeMulti && queryType != queryTypePhrase {
+		return nil, fmt.Errorf("Invalid query parameter 'analyzer': only applies to match, multi_match and phrase queries")
+	}
+	// 기본 필드(_all)는 매핑의 기본 분석기로 검색어를 분석하므로, 본문 분석기로 분석해
+	// 조사를 뗀 어절 등 색인된 텀과 같은 형태로 맞춤
+	if analyzerName == "" && field == "" {
+		analyzerName = m.AnalyzerNameForPath(defaultSearchField)
+	}
+
+	switch queryType {
+	case queryTypePhrase:
+		// 구문 검색은 텀 벡터의 토큰 위치로 인접 여부를 확인하므로 텀 벡터가 없으면 항상 결과가 비어 있음
+		if !hasTermVectors(m, termField) {
+			return nil, fmt.Errorf("Invalid query parameter 'type': field '%s' was indexed without term vectors, rebuild the index with TERM_VECTORS enabled for phrase queries", termField)
+		}
+		slop, err := parseIntParam(values, "slop", 0)
+		if err != nil {
+			return nil, err
+		}
+		if slop > maxPhraseSlop {
+			return nil, fmt.Errorf("Invalid query parameter 'slop': must be at most %d", maxPhraseSlop)
+		}
+		if slop > 0 {
+			return newSloppyPhraseQuery(m, termField, analyzerName, text, slop, fuzziness)
+		}
+
+		phraseQuery := bleve.NewMatchPhraseQuery(text)
+		phraseQuery.SetField(field)
+		phraseQuery.SetFuzziness(fuzziness)
+		phraseQuery.Analyzer = analyzerName
+		return phraseQuery, nil
+	case queryTypePrefix:
+		prefix := normalizeTerm(text)
+		if strings.ContainsFunc(prefix, unicode.IsSpace) {
+			return nil, fmt.Errorf("Invalid query parameter 'q': prefix queries must be a single term")
+		}
+
+		prefixQuery := bleve.NewPrefixQuery(prefix)
+		prefixQuery.SetField(termField)
+		return prefixQuery, nil
+	case queryTypeWildcard:
+		pattern := normalizeTerm(text)
+		if strings.ContainsFunc(pattern, unicode.IsSpace) {
+			return nil, fmt.Errorf("Invalid query parameter 'q': wildcard queries must be a single term")
+		}
+		// 선행 와일드카드는 텀 사전 전체를 훑어야 하므로 명시적으로 허용한 경우에만 실행
+		if strings.IndexAny(pattern, "*?") == 0 && values.Get("allow_expensive") != "true" {
+			return nil, fmt.Errorf("Invalid query parameter 'q': wildcard patterns starting with '*' or '?' are expensive, pass allow_expensive=true to run them")
+		}
+
+		wildcardQuery := bleve.NewWildcardQuery(pattern)
+		wildcardQuery.SetField(termField)
+		return wildcardQuery, nil
+	case queryTypeRegexp:
+		// 정규식은 원문이 아닌 분석된 텀 하나 전체와 일치해야 한다 (암묵적으로 ^...$).
+		// 텀은 소문자로 정규화되어 있고 한글은 어절, 한자/가나는 두 글자 단위로 색인되므로
+		// "V[0-9]+"는 아무것도 찾지 못하고 "v[0-9]+\.[0-9]+"는 "v1.2" 텀을 찾는다.
+		pattern := strings.TrimSpace(text)
+		if utf8.RuneCountInString(pattern) > maxRegexpLength {
+			return nil, fmt.Errorf("Invalid query parameter 'q': regexp patterns must be at most %d characters", maxRegexpLength)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("Invalid query parameter 'q': invalid regexp: %v", err)
+		}
+
+		regexpQuery := bleve.NewRegexpQuery(pattern)
+		regexpQuery.SetField(termField)
+		return regexpQuery, nil
+	default:
+		prefixLength, err := parseIntParam(values, "prefix_length", 0)
+		if err != nil {
+			return nil, err
+		}
+		operator, err := parseMatchOperator(values.Get("operator"))
+		if err != nil {
+			return nil, err
+		}
+		minimumShouldMatch, err := parseIntParam(values, "minimum_should_match", 0)
+		if err != nil {
+			return nil, err
+		}
+		if minimumShouldMatch > 0 && operator == query.MatchQueryOperatorAnd {
+			return nil, fmt.Errorf("Invalid query parameter 'minimum_should_match': cannot be combined with operator=and")
+		}
+
+		newMatchQuery := func(text string) *query.MatchQuery {
+			matchQuery := bleve.NewMatchQuery(text)
+			matchQuery.SetField(field)
+			matchQuery.SetFuzziness(fuzziness)
+			matchQuery.SetPrefix(prefixLength)
+			matchQuery.SetOperator(operator)
+			matchQuery.Analyzer = analyzerName
+			return matchQuery
+		}
+		if minimumShouldMatch > 0 {
+			return newMinimumShouldMatchQuery(m, termField, analyzerName, text, minimumShouldMatch, newMatchQuery), nil
+		}
+		return newMatchQuery(text), nil
+	}
+}
+
+// 검색 대상 필드가 인덱스 매핑에 정의되어 있는지 확인하는 함수
+func validateFields(param string, fields []string, m mapping.IndexMapping) error {
+	known := mappedFields(m)
+	for _, field := range fields {
+		if _, ok := known[field]; !ok {
+			return fmt.Errorf("Invalid query parameter '%s': unknown field '%s' (known fields: %s)", param, field, strings.Join(sortedKeys(known), ", "))
+		}
+	}
+	return nil
+}
+
+// 인덱스 매핑에 정의된 모든 필드를 경로별로 반환하는 함수
+func mappedFields(m mapping.IndexMapping) map[string]*mapping.FieldMapping {
+	fields := make(map[string]*mapping.FieldMapping)
+	impl, ok := m.(*mapping.IndexMappingImpl)
+	if !ok {
+		return fields
+	}
+
+	var collect func(prefix string, dm *mapping.DocumentMapping)
+	collect = func(prefix string, dm *mapping.DocumentMapping) {
+		if dm == nil {
+			return
+		}
+		for name, property := range dm.Properties {
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			for _, field := range property.Fields {
+				if field.Name != "" && field.Name != name {
+					fields[strings.TrimSuffix(path, name)+field.Name] = field
+				} else {
+					fields[path] = field
+				}
+			}
+			collect(path, property)
+		}
+	}
+	collect("", impl.DefaultMapping)
+	for _, dm := range impl.TypeMapping {
+		collect("", dm)
+	}
+	return fields
+}
+
+// 맵의 키를 정렬된 목록으로 반환하는 함수
+func sortedKeys[V any](items map[string]V) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// sort 파라미터(쉼표 구분, '-' 접두어는 내림차순)를 검증하고 정렬 조건 목록을 반환하는 함수
+// _score, _id 외에는 DocValues가 저장된(정렬 가능한) 필드만 허용한다.
+func parseSortParam(raw string, values url.Values, m mapping.IndexMapping) (search.SortOrder, error) {
+	order := splitParamList(raw)
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	known := mappedFields(m)
+	var sortable []string
+	for _, name := range sortedKeys(known) {
+		if known[name].DocValues {
+			sortable = append(sortable, name)
+		}
+	}
+
+	var sortOrder search.SortOrder
+	for _, item := range order {
+		field := strings.TrimPrefix(item, "-")
+		if field == "_distance" {
+			// 거리순 정렬은 기준 좌표(lat, lon)가 있어야 함
+			origin, err := parseGeoParams(values)
+			if err != nil {
+				return nil, err
+			}
+			if origin == nil {
+				return nil, fmt.Errorf("Invalid query parameter 'sort': _distance requires 'lat' and 'lon'")
+			}
+			geoSort, err := search.NewSortGeoDistance(locationField, "m", origin.lon, origin.lat, strings.HasPrefix(item, "-"))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid query parameter 'sort': %v", err)
+			}
+			sortOrder = append(sortOrder, geoSort)
+			continue
+		}
+		if field == "_score" || field == "_id" || slices.Contains(sortable, field) {
+			sortOrder = append(sortOrder, search.ParseSearchSortString(item))
+			continue
+		}
+		return nil, fmt.Errorf("Invalid query parameter 'sort': field '%s' is not sortable (sortable fields: _score, _id, _distance, %s)", field, strings.Join(sortable, ", "))
+	}
+	return sortOrder, nil
+}
+
+// 숫자 범위 필터 파라미터의 접두어와 경계 포함 여부
+// (min_/max_는 경계 포함, gt_/lt_는 경계 제외)
+var numericRangeParams = []struct {
+	prefix    string
+	lower     bool
+	inclusive bool
+}{
+	{"min_", true, true},
+	{"gt_", true, false},
+	{"max_", false, true},
+	{"lt_", false, false},
+}
+
+// 쿼리 파라미터로 지정된 필터 조건들을 쿼리 목록으로 변환하는 함수
+func buildParamFilters(values url.Values, m mapping.IndexMapping) ([]query.Query, error) {
+	var filters []query.Query
+
+	// 숫자 범위 필터 (min_price=1000&lt_price=2000)
+	ranges := make(map[string]*numericFilter)
+	for _, name := range sortedKeys(values) {
+		if name == "min_score" {
+			// 점수 하한은 필드 필터가 아님
+			continue
+		}
+		for _, param := range numericRangeParams {
+			field, ok := strings.CutPrefix(name, param.prefix)
+			if !ok || field == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(values.Get(name), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid query parameter '%s': must be a number", name)
+			}
+
+			filter, ok := ranges[field]
+			if !ok {
+				filter = &numericFilter{Field: field}
+				ranges[field] = filter
+			}
+			inclusive := param.inclusive
+			if param.lower {
+				filter.Min, filter.InclusiveMin = &value, &inclusive
+			} else {
+				filter.Max, filter.InclusiveMax = &value, &inclusive
+			}
+		}
+	}
+	for _, field := range sortedKeys(ranges) {
+		rangeQuery, err := buildNumericFilter(*ranges[field], m)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid numeric range filter: %v", err)
+		}
+		filters = append(filters, rangeQuery)
+	}
+
+	// 작성 시각 범위 필터 (after 이상, before 미만, 한쪽만 지정 가능)
+	after, err := parseTimeParam(values, "after")
+	if err != nil {
+		return nil, err
+	}
+	before, err := parseTimeParam(values, "before")
+	if err != nil {
+		return nil, err
+	}
+	if !after.IsZero() || !before.IsZero() {
+		inclusive, exclusive := true, false
+		dateRange := bleve.NewDateRangeInclusiveQuery(after, before, &inclusive, &exclusive)
+		dateRange.SetField("created_at")
+		filters = append(filters, dateRange)
+	}
+
+	// 다른 날짜 필드의 범위 필터 (after_metadata.published_at=...&before_metadata.published_at=..., after 이상, before 미만)
+	dateRanges := make(map[string][2]time.Time)
+	for _, name := range sortedKeys(values) {
+		var field string
+		var lower bool
+		if after, ok := strings.CutPrefix(name, "after_"); ok {
+			field, lower = after, true
+		} else if before, ok := strings.CutPrefix(name, "before_"); ok {
+			field = before
+		}
+		if field == "" {
+			continue
+		}
+		if fieldMapping, ok := mappedFields(m)[field]; !ok || fieldMapping.Type != "datetime" {
+			return nil, fmt.Errorf("Invalid query parameter '%s': field '%s' has no datetime mapping", name, field)
+		}
+		t, err := parseTimeParam(values, name)
+		if err != nil {
+			return nil, err
+		}
+		bounds := dateRanges[field]
+		if lower {
+			bounds[0] = t
+		} else {
+			bounds[1] = t
+		}
+		dateRanges[field] = bounds
+	}
+	for _, field := range sortedKeys(dateRanges) {
+		bounds := dateRanges[field]
+		if bounds[0].IsZero() && bounds[1].IsZero() {
+			continue
+		}
+		inclusive, exclusive := true, false
+		dateRange := bleve.NewDateRangeInclusiveQuery(bounds[0], bounds[1], &inclusive, &exclusive)
+		dateRange.SetField(field)
+		filters = append(filters, dateRange)
+	}
+
+	// 문서 ID 제한 (ids=가 비어 있으면 아무 문서도 일치하지 않음)
+	if values.Has("ids") {
+		filters = append(filters, docIDFilter(splitParamList(values.Get("ids"))))
+	}
+
+	// 키워드 필드 정확히 일치 필터 (filter_term=status:published, 여러 번 지정 가능)
+	termFilters, err := buildTermFilters(values, m)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, termFilters...)
+
+	// 문서 종류 제한 (doc_type=article,faq, type은 쿼리 종류 파라미터이므로 doc_type)
+	if values.Has("doc_type") {
+		filters = append(filters, documentTypeFilter(splitParamList(values.Get("doc_type"))))
+	}
+
+	// 위치 반경 필터 (좌표가 없는 문서는 일치하지 않음, 좌표만 있으면 거리순 정렬 기준으로만 사용)
+	origin, err := parseGeoParams(values)
+	if err != nil {
+		return nil, err
+	}
+	if origin != nil && origin.distance != "" {
+		geoQuery := bleve.NewGeoDistanceQuery(origin.lon, origin.lat, origin.distance)
+		geoQuery.SetField(locationField)
+		filters = append(filters, geoQuery)
+	}
+
+	return filters, nil
+}
+
+// 숫자 필드의 범위 조건 (경계 포함 여부를 지정하지 않으면 min은 포함, max는 제외)
+type numericFilter struct {
+	Field        string   `json:"field"`
+	Min          *float64 `json:"min"`
+	Max          *float64 `json:"max"`
+	InclusiveMin *bool    `json:"inclusive_min"`
+	InclusiveMax *bool    `json:"inclusive_max"`
+}
+
+// 숫자 범위 조건을 NumericRangeQuery로 변환하는 함수
+// 숫자 매핑이 없는 필드를 대상으로 하면 결과가 항상 비므로 오류로 처리한다.
+func buildNumericFilter(filter numericFilter, m mapping.IndexMapping) (query.Query, error) {
+	fieldMapping, ok := mappedFields(m)[filter.Field]
+	if !ok || fieldMapping.Type != "number" {
+		return nil, fmt.Errorf("field '%s' has no numeric mapping", filter.Field)
+	}
+	if filter.Min == nil && filter.Max == nil {
+		return nil, fmt.Errorf("field '%s' needs at least one of min or max", filter.Field)
+	}
+
+	rangeQuery := bleve.NewNumericRangeInclusiveQuery(filter.Min, filter.Max, filter.InclusiveMin, filter.InclusiveMax)
+	rangeQuery.SetField(filter.Field)
+	return rangeQuery, nil
+}
+
+// 거리순 정렬 값은 인코딩된 문자열로 반환되므로 미터 단위 숫자 문자열로 바꾸는 함수
+// 좌표가 없는 문서는 bleve가 최대 거리 값을 넣으므로 빈 문자열로 바꾼다.
+func decodeDistanceSorts(result *bleve.SearchResult, order search.SortOrder) {
+	for i, sortItem := range order {
+		if _, ok := sortItem.(*search.SortGeoDistance); !ok {
+			continue
+		}
+		for _, hit := range result.Hits {
+			if i >= len(hit.Sort) {
+				continue
+			}
+			i64, err := numeric.PrefixCoded(hit.Sort[i]).Int64()
+			if err != nil {
+				continue
+			}
+			distance := numeric.Int64ToFloat64(i64)
+			if math.IsNaN(distance) {
+				hit.Sort[i] = ""
+				continue
+			}
+			hit.Sort[i] = strconv.FormatFloat(distance, 'f', 1, 64)
+		}
+	}
+}
+
+// 검색 대상을 주어진 문서 ID로 제한하는 필터를 만드는 함수
+// DocIDQuery는 ID 목록을 정렬된 집합으로 보관하므로 수천 개의 ID도 쿼리 하나로 처리된다.
+// 빈 목록은 "제한 없음"이 아니라 "허용된 문서 없음"이므로 아무것도 일치하지 않는 쿼리를 반환한다.
+func docIDFilter(ids []string) query.Query {
+	if len(ids) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	return bleve.NewDocIDQuery(ids)
+}
+
+// 위치 검색의 기준 좌표와 반경
+type geoOrigin struct {
+	lat, lon float64
+	distance string
+}
+
+// lat, lon, distance 파라미터를 파싱하는 함수 (좌표가 없으면 nil 반환)
+func parseGeoParams(values url.Values) (*geoOrigin, error) {
+	rawLat, rawLon := values.Get("lat"), values.Get("lon")
+	if rawLat == "" && rawLon == "" {
+		if values.Get("distance") != "" {
+			return nil, fmt.Errorf("Invalid query parameter 'distance': requires 'lat' and 'lon'")
+		}
+		return nil, nil
+	}
+
+	lat, err := strconv.ParseFloat(rawLat, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("Invalid query parameter 'lat': must be a number between -90 and 90")
+	}
+	lon, err := strconv.ParseFloat(rawLon, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("Invalid query parameter 'lon': must be a number between -180 and 180")
+	}
+	distance := values.Get("distance")
+	if distance != "" {
+		if _, err := geo.ParseDistance(distance); err != nil {
+			return nil, fmt.Errorf("Invalid query parameter 'distance': expected a distance like 500m or 3km")
+		}
+	}
+	return &geoOrigin{lat: lat, lon: lon, distance: distance}, nil
+}
+
+// RFC3339 형식의 시각 쿼리 파라미터를 파싱하는 함수 (값이 없으면 zero time 반환)
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid query parameter '%s': expected RFC3339 timestamp (e.g. 2006-01-02T15:04:05Z07:00)", name)
+	}
+	return t, nil
+}
+
+// 접두어/와일드카드 패턴을 색인된 토큰과 같은 형태로 정규화하는 함수
+// PrefixQuery, WildcardQuery는 분석기를 거치지 않고 색인된 텀과 바로 비교되므로, 본문 분석기가
+// 색인 시 적용하는 필터(전각/반각 폭 정규화, 소문자 변환)를 직접 적용해야 한다.
+// 한글은 어절 단위 토큰으로 색인되므로 "개발"이 "개발자", "개발팀"과 매칭되지만,
+// 한자/가나는 두 글자씩 묶여(bigram) 색인되므로 두 글자 이하의 접두어만 의미가 있다.
+func normalizeTerm(text string) string {
+	return strings.ToLower(width.Fold.String(strings.TrimSpace(text)))
+}
+
+// 단어 사이에 최대 slop개의 다른 토큰을 허용하는 구문 쿼리를 생성하는 함수
+// bleve의 구문 검색은 위치 간격을 허용하지 않으므로, 단어 사이에 빈 위치("")를
+// 끼워 넣은 구문 변형들을 만들어 DisjunctionQuery로 묶는다.
+// 필드 분석기를 그대로 사용하므로 MatchPhraseQuery와 같은 토큰이 만들어진다.
+// 변형 수가 maxPhraseVariants를 넘으면 쿼리를 만들지 않고 오류를 반환한다.
+func newSloppyPhraseQuery(m mapping.IndexMapping, field, analyzerName, text string, slop, fuzziness int) (query.Query, error) {
+	analyzer := queryAnalyzer(m, field, analyzerName)
+	if analyzer == nil {
+		return bleve.NewMatchNoneQuery(), nil
+	}
+
+	// 토큰 위치별로 텀 목록 구성
+	tokens := analyzer.Analyze([]byte(text))
+	if len(tokens) == 0 {
+		return bleve.NewMatchNoneQuery(), nil
+	}
+	first, last := tokens[0].Position, tokens[0].Position
+	for _, token := range tokens {
+		first = min(first, token.Position)
+		last = max(last, token.Position)
+	}
+	phrase := make([][]string, last-first+1)
+	for _, token := range tokens {
+		phrase[token.Position-first] = append(phrase[token.Position-first], string(token.Term))
+	}
+	if phraseVariantCount(len(phrase), slop) > maxPhraseVariants {
+		return nil, fmt.Errorf("Invalid query parameter 'slop': phrase of %d words with slop %d needs more than %d variants, use a shorter phrase or a smaller slop", len(phrase), slop, maxPhraseVariants)
+	}
+
+	disjunction := bleve.NewDisjunctionQuery()
+	for _, variant := range phraseGapVariants(phrase, slop) {
+		phraseQuery := query.NewMultiPhraseQuery(variant, field)
+		phraseQuery.SetFuzziness(fuzziness)
+		disjunction.AddQuery(phraseQuery)
+	}
+	return disjunction, nil
+}
+
+// phraseGapVariants가 만드는 변형 수를 미리 계산하는 함수
+// 위치가 n개인 구문의 n-1개 틈에 총 slop개 이하의 빈 위치를 나누는 경우의 수 C(n-1+slop, slop)이며,
+// 상한을 넘으면 계산을 멈추고 상한보다 큰 값을 반환한다.
+func phraseVariantCount(positions, slop int) int {
+	if positions <= 1 {
+		return 1
+	}
+	count := 1
+	for i := 1; i <= slop; i++ {
+		count = count * (positions - 1 + i) / i
+		if count > maxPhraseVariants {
+			return maxPhraseVariants + 1
+		}
+	}
+	return count
+}
+
+// 구문의 각 단어 사이에 총 slop개 이하의 빈 위치를 배치한 모든 변형을 만드는 함수
+func phraseGapVariants(phrase [][]string, slop int) [][][]string {
+	if len(phrase) <= 1 {
+		return [][][]string{phrase}
+	}
+
+	var variants [][][]string
+	for gap := 0; gap <= slop; gap++ {
+		for _, rest := range phraseGapVariants(phrase[1:], slop-gap) {
+			variant := [][]string{phrase[0]}
+			for i := 0; i < gap; i++ {
+				variant = append(variant, []string{""})
+			}
+			variants = append(variants, append(variant, rest...))
+		}
+	}
+	return variants
+}
+
+// POST /search 요청 본문 (불리언 쿼리)
+type searchBody struct {
+	Must        []queryClause   `json:"must"`
+	Should      []queryClause   `json:"should"`
+	MustNot     []queryClause   `json:"must_not"`
+	Filters     []numericFilter `json:"filters"`
+	From        int             `json:"from"`
+	Size        *int            `json:"size"`
+	Explain     bool            `json:"explain"`
+	MinScore    *float64        `json:"min_score"`
+	TimeoutMS   int             `json:"timeout_ms"`
+	IDs         *[]string       `json:"ids"`
+	DocTypes    *[]string       `json:"doc_types"`
+	FilterTerms []string        `json:"filter_terms"`
+	DedupeField string          `json:"dedupe_field"`
+	Collapse    string          `json:"collapse"`
+	InnerHits   int             `json:"inner_hits"`
+}
+
+// 불리언 쿼리를 구성하는 개별 절
+type queryClause struct {
+	Type  string   `json:"type"`
+	Field string   `json:"field"`
+	Query string   `json:"query"`
+	Boost *float64 `json:"boost"`
+}
+
+// encoding/json 오류 경로의 배열 인덱스 (must.0.type -> must[0].type 변환용)
+var arrayIndexPattern = regexp.MustCompile(`\.(\d+)`)
+
+// 잘못된 절의 JSON 경로를 담는 오류
+type clauseError struct {
+	Path    string
+	Message string
+}
+
+func (e *clauseError) Error() string {
+	return fmt.Sprintf("Invalid clause at '%s': %s", e.Path, e.Message)
+}
+
+// POST 검색의 JSON 본문으로부터 검색 요청을 생성하는 함수
+func newSearchSpecFromBody(body io.Reader, m mapping.IndexMapping) (*searchSpec, error) {
+	var req searchBody
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			path := arrayIndexPattern.ReplaceAllString(typeErr.Field, "[$1]")
+			return nil, &clauseError{Path: path, Message: fmt.Sprintf("expected %s", typeErr.Type)}
+		}
+		return nil, fmt.Errorf("Invalid request body: %v", err)
+	}
+
+	booleanQuery, err := buildBooleanQuery(req, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// 숫자 범위 필터는 must 조건으로 추가
+	for i, filter := range req.Filters {
+		rangeQuery, err := buildNumericFilter(filter, m)
+		if err != nil {
+			return nil, &clauseError{Path: fmt.Sprintf("filters[%d]", i), Message: err.Error()}
+		}
+		booleanQuery.AddMust(rangeQuery)
+	}
+
+	// 문서 ID 제한도 must 조건으로 추가
+	if req.IDs != nil {
+		booleanQuery.AddMust(docIDFilter(*req.IDs))
+	}
+	if req.DocTypes != nil {
+		booleanQuery.AddMust(documentTypeFilter(*req.DocTypes))
+	}
+	for i, raw := range req.FilterTerms {
+		termFilter, err := buildTermFilter(raw, m)
+		if err != nil {
+			return nil, &clauseError{Path: fmt.Sprintf("filter_terms[%d]", i), Message: err.Error()}
+		}
+		booleanQuery.AddMust(termFilter)
+	}
+
+	if req.From < 0 {
+		return nil, &clauseError{Path: "from", Message: "must be a non-negative integer"}
+	}
+	size := defaultSearchSize
+	if req.Size != nil {
+		if *req.Size < 0 {
+			return nil, &clauseError{Path: "size", Message: "must be a non-negative integer"}
+		}
+		size = *req.Size
+	}
+
+	if req.MinScore != nil && *req.MinScore < 0 {
+		return nil, &clauseError{Path: "min_score", Message: "must be a non-negative number"}
+	}
+
+	if req.TimeoutMS < 0 {
+		return nil, &clauseError{Path: "timeout_ms", Message: "must be a non-negative integer"}
+	}
+	timeout, err := searchTimeout(req.TimeoutMS)
+	if err != nil {
+		return nil, &clauseError{Path: "timeout_ms", Message: fmt.Sprintf("must be at most %d", maxSearchTimeout.Milliseconds())}
+	}
+
+	searchRequest := bleve.NewSearchRequestOptions(booleanQuery, min(size, maxSearchSize), req.From, req.Explain)
+	searchRequest.Fields = []string{"*"}
+	if err := prepareDedupeField(searchRequest, req.DedupeField, m); err != nil {
+		return nil, &clauseError{Path: "dedupe_field", Message: fmt.Sprintf("field '%s' is not a stored field", req.DedupeField)}
+	}
+	collapse, err := collapseFromBody(req, searchRequest, m)
+	if err != nil {
+		return nil, err
+	}
+	spec := &searchSpec{request: searchRequest, queryType: queryTypeBoolean, minScore: req.MinScore, timeout: timeout, dedupeField: req.DedupeField, collapse: collapse}
+	if spec.exceedsResultWindow() {
+		return nil, &clauseError{Path: "from", Message: fmt.Sprintf("from+size must be at most %d when min_score, dedupe_field or collapse is set", minScoreWindow)}
+	}
+	return spec, nil
+}
+
+// 요청 본문의 must/should/must_not 절을 bleve BooleanQuery로 변환하는 함수
+func buildBooleanQuery(req searchBody, m mapping.IndexMapping) (*query.BooleanQuery, error) {
+	if len(req.Must) == 0 && len(req.Should) == 0 && len(req.MustNot) == 0 {
+		return nil, fmt.Errorf("Invalid request body: at least one of must, should or must_not is required")
+	}
+
+	booleanQuery := bleve.NewBooleanQuery()
+	for i, clause := range req.Must {
+		q, err := buildClauseQuery(clause, fmt.Sprintf("must[%d]", i), m)
+		if err != nil {
+			return nil, err
+		}
+		booleanQuery.AddMust(q)
+	}
+	for i, clause := range req.Should {
+		q, err := buildClauseQuery(clause, fmt.Sprintf("should[%d]", i), m)
+		if err != nil {
+			return nil, err
+		}
+		booleanQuery.AddShould(q)
+	}
+	for i, clause := range req.MustNot {
+		q, err := buildClauseQuery(clause, fmt.Sprintf("must_not[%d]", i), m)
+		if err != nil {
+			return nil, err
+		}
+		booleanQuery.AddMustNot(q)
+	}
+	return booleanQuery, nil
+}
+
+// 개별 절을 bleve 쿼리로 변환하는 함수 (path는 오류 보고용 JSON 경로)
+// 매핑에 없는 필드는 항상 결과가 비므로 오류로 알린다.
+func buildClauseQuery(clause queryClause, path string, m mapping.IndexMapping) (query.Query, error) {
+	if clause.Query == "" {
+		return nil, &clauseError{Path: path + ".query", Message: "must not be empty"}
+	}
+	if clause.Boost != nil && *clause.Boost <= 0 {
+		return nil, &clauseError{Path: path + ".boost", Message: "must be a positive number"}
+	}
+	field := clause.Field
+	if field == "" {
+		field = defaultSearchField
+	}
+	known := mappedFields(m)
+	if _, ok := known[field]; !ok {
+		return nil, &clauseError{Path: path + ".field", Message: fmt.Sprintf("unknown field '%s' (known fields: %s)", field, strings.Join(sortedKeys(known), ", "))}
+	}
+
+	var q query.FieldableQuery
+	switch clause.Type {
+	case "", queryTypeMatch:
+		q = bleve.NewMatchQuery(clause.Query)
+	case queryTypePhrase:
+		q = bleve.NewMatchPhraseQuery(clause.Query)
+	case queryTypePrefix:
+		q = bleve.NewPrefixQuery(normalizeTerm(clause.Query))
+	case queryTypeTerm:
+		q = bleve.NewTermQuery(clause.Query)
+	default:
+		return nil, &clauseError{Path: path + ".type", Message: fmt.Sprintf("unknown query type '%s'", clause.Type)}
+	}
+	q.SetField(field)
+	if clause.Boost != nil {
+		q.(query.BoostableQuery).SetBoost(*clause.Boost)
+	}
+	return q, nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhraseVariantCount(t *testing.T) {
+	for _, tt := range []struct {
+		positions, slop int
+	}{
+		{1, 3}, {2, 0}, {2, 3}, {3, 2}, {5, 3}, {8, 3},
+	} {
+		phrase := make([][]string, tt.positions)
+		for i := range phrase {
+			phrase[i] = []string{"t"}
+		}
+		if got, want := phraseVariantCount(tt.positions, tt.slop), len(phraseGapVariants(phrase, tt.slop)); got != want {
+			t.Errorf("phraseVariantCount(%d, %d) = %d, want %d", tt.positions, tt.slop, got, want)
+		}
+	}
+	if got := phraseVariantCount(100, maxPhraseSlop); got <= maxPhraseVariants {
+		t.Errorf("phraseVariantCount(100, %d) = %d, want more than %d", maxPhraseSlop, got, maxPhraseVariants)
+	}
+}
+
+func TestPhraseSearch(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "1", Content: "서울 시청 호텔"},
+		documentBody{Title: "2", Content: "호텔 서울 시청"},
+		documentBody{Title: "3", Content: "서울 강남 시청 호텔"},
+		documentBody{Title: "4", Content: "부산 해운대 호텔"},
+	)
+
+	for _, tt := range []struct {
+		name   string
+		params url.Values
+		code   int
+		ids    []string
+	}{
+		{"match", url.Values{"q": {"서울 호텔"}}, http.StatusOK, []string{"1", "2", "3", "4"}},
+		{"phrase", url.Values{"q": {"시청 호텔"}, "type": {"phrase"}}, http.StatusOK, []string{"1", "3"}},
+		{"phrase order", url.Values{"q": {"서울 시청"}, "type": {"phrase"}}, http.StatusOK, []string{"1", "2"}},
+		{"phrase with slop", url.Values{"q": {"서울 시청"}, "type": {"phrase"}, "slop": {"1"}}, http.StatusOK, []string{"1", "2", "3"}},
+		{"slop above limit", url.Values{"q": {"서울 시청"}, "type": {"phrase"}, "slop": {"4"}}, http.StatusBadRequest, nil},
+		{"too many variants", url.Values{"q": {strings.Repeat("서울 ", 100)}, "type": {"phrase"}, "slop": {"3"}}, http.StatusBadRequest, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+tt.params.Encode(), nil))
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d", code, tt.code)
+			}
+			if tt.code == http.StatusOK && !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("hits = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestBooleanSearchValidatesClauseFields(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t), documentBody{Title: "서울 호텔", Content: "서울 시청 호텔"})
+
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+		path string
+	}{
+		{"default field", `{"must": [{"query": "서울"}]}`, http.StatusOK, ""},
+		{"mapped field", `{"must": [{"field": "title", "query": "서울"}]}`, http.StatusOK, ""},
+		{"unknown must field", `{"must": [{"field": "titel", "query": "서울"}]}`, http.StatusBadRequest, "must[0].field"},
+		{"unknown should field", `{"should": [{"query": "서울"}, {"field": "nope", "query": "호텔"}]}`, http.StatusBadRequest, "should[1].field"},
+		{"unknown must_not field", `{"must": [{"query": "서울"}], "must_not": [{"field": "nope", "query": "호텔"}]}`, http.StatusBadRequest, "must_not[0].field"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			searchHandler(rec, httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body, tt.code)
+			}
+			if tt.path != "" && !strings.Contains(rec.Body.String(), "'"+tt.path+"'") {
+				t.Errorf("error %q does not name %s", rec.Body, tt.path)
+			}
+		})
+	}
+}
+
+func TestPrefixSearch(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "1", Content: "개발자 채용"},
+		documentBody{Title: "2", Content: "개발팀 소개"},
+		documentBody{Title: "3", Content: "개발 일정"},
+		documentBody{Title: "4", Content: "DevOps 엔지니어"},
+		documentBody{Title: "5", Content: "디자인팀 소개"},
+	)
+
+	for _, tt := range []struct {
+		name   string
+		params url.Values
+		code   int
+		ids    []string
+	}{
+		// 일치 검색은 어절 전체가 같은 텀만 찾음
+		{"match", url.Values{"q": {"개발"}}, http.StatusOK, []string{"3"}},
+		{"prefix", url.Values{"q": {"개발"}, "prefix": {"true"}}, http.StatusOK, []string{"1", "2", "3"}},
+		{"prefix type", url.Values{"q": {"개발"}, "type": {"prefix"}}, http.StatusOK, []string{"1", "2", "3"}},
+		// 접두어는 색인된 텀처럼 소문자와 반각 문자로 정규화됨
+		{"prefix normalized", url.Values{"q": {" ＤＥＶ "}, "prefix": {"true"}}, http.StatusOK, []string{"4"}},
+		{"prefix of several words", url.Values{"q": {"개발 일정"}, "prefix": {"true"}}, http.StatusBadRequest, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+tt.params.Encode(), nil))
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d", code, tt.code)
+			}
+			if tt.code == http.StatusOK && !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("hits = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestQueryStringSearch(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "서울", Content: "김치 찌개 맛집"},
+		documentBody{Title: "부산", Content: "김치 레시피 모음"},
+		documentBody{Title: "부산", Content: "김치 볶음밥 맛집"},
+		documentBody{Title: "서울", Content: "냉면 맛집"},
+	)
+
+	for _, tt := range []struct {
+		name   string
+		params url.Values
+		code   int
+		ids    []string
+	}{
+		{"field prefix", url.Values{"q": {"title:서울"}, "syntax": {"query_string"}}, http.StatusOK, []string{"1", "4"}},
+		{"negation", url.Values{"q": {"+content:김치 -content:레시피"}, "syntax": {"query_string"}}, http.StatusOK, []string{"1", "3"}},
+		{"boost, field prefix and negation", url.Values{"q": {"+content:김치 -content:레시피 title:서울^2"}, "syntax": {"query_string"}}, http.StatusOK, []string{"1", "3"}},
+		{"parse error", url.Values{"q": {`content:"김치`}, "syntax": {"query_string"}}, http.StatusBadRequest, nil},
+		{"unknown syntax", url.Values{"q": {"김치"}, "syntax": {"lucene"}}, http.StatusBadRequest, nil},
+		{"with fields", url.Values{"q": {"김치"}, "syntax": {"query_string"}, "fields": {"title"}}, http.StatusBadRequest, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+tt.params.Encode(), nil))
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d", code, tt.code)
+			}
+			if tt.code == http.StatusOK && !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("hits = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	// 구문 오류는 파서의 메시지와 함께 400으로 응답
+	rec := httptest.NewRecorder()
+	searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {"content:김치^abc"}, "syntax": {"query_string"}}.Encode(), nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid boost value") {
+		t.Errorf("invalid boost = %d %s, want 400 with the parser's message", rec.Code, rec.Body)
+	}
+
+	// title:서울^2가 같은 조건의 부산 문서보다 서울 문서를 앞에 둠
+	rec = httptest.NewRecorder()
+	searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {"+content:맛집 title:서울^2"}, "syntax": {"query_string"}}.Encode(), nil))
+	var response struct {
+		Hits []struct {
+			ID string `json:"id"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", rec.Body, err)
+	}
+	if len(response.Hits) != 3 || response.Hits[2].ID != "3" {
+		t.Errorf("hits = %+v, want the boosted 서울 documents before document 3", response.Hits)
+	}
+}
+
+func TestRegexpSearch(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "1", Content: "릴리스 v1.2 배포"},
+		documentBody{Title: "2", Content: "릴리스 V2.10 배포"},
+		documentBody{Title: "3", Content: "버전 v2 없음"},
+	)
+
+	for _, tt := range []struct {
+		name    string
+		pattern string
+		code    int
+		ids     []string
+	}{
+		{"version tokens", `v[0-9]+\.[0-9]+`, http.StatusOK, []string{"1", "2"}},
+		{"any version", `v[0-9.]+`, http.StatusOK, []string{"1", "2", "3"}},
+		// 텀은 소문자로 색인되므로 대문자 패턴은 원문에 있어도 찾지 못함
+		{"uppercase pattern", `V[0-9]+\.[0-9]+`, http.StatusOK, []string{}},
+		// 패턴은 텀 전체와 일치해야 하며 여러 텀에 걸친 원문과는 일치하지 않음
+		{"partial token", `릴리`, http.StatusOK, []string{}},
+		{"whole token", `릴리.*`, http.StatusOK, []string{"1", "2"}},
+		{"across tokens", `릴리스 v1\.2`, http.StatusOK, []string{}},
+		{"invalid pattern", `v[0-9`, http.StatusBadRequest, nil},
+		{"pattern too long", strings.Repeat("a", maxRegexpLength+1), http.StatusBadRequest, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{"q": {tt.pattern}, "regexp": {"true"}}
+			code, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+params.Encode(), nil))
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d", code, tt.code)
+			}
+			if tt.code == http.StatusOK && !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("hits = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestParseBoostFields(t *testing.T) {
+	for _, tt := range []struct {
+		raw     string
+		boosts  map[string]float64
+		fields  []string
+		wantErr bool
+	}{
+		{"", map[string]float64{}, nil, false},
+		{"title:3,content:1", map[string]float64{"title": 3, "content": 1}, []string{"title", "content"}, false},
+		{" title : 2.5 , content:1", map[string]float64{"title": 2.5, "content": 1}, []string{"title", "content"}, false},
+		{"title:2,title:4", map[string]float64{"title": 4}, []string{"title"}, false},
+		{"title", nil, nil, true},
+		{":3", nil, nil, true},
+		{"title:abc", nil, nil, true},
+		{"title:0", nil, nil, true},
+		{"title:-1", nil, nil, true},
+	} {
+		boosts, fields, err := parseBoostFields(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBoostFields(%q) error = %v, wantErr %t", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (!reflect.DeepEqual(boosts, tt.boosts) || !reflect.DeepEqual(fields, tt.fields)) {
+			t.Errorf("parseBoostFields(%q) = %v, %v, want %v, %v", tt.raw, boosts, fields, tt.boosts, tt.fields)
+		}
+	}
+}
+
+func TestBoostReordersTiedDocuments(t *testing.T) {
+	// 제목과 본문이 서로 뒤바뀐 두 문서는 가중치가 없으면 점수가 같음
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "서울 호텔", Content: "부산 해운대 바다"},
+		documentBody{Title: "부산 해운대 바다", Content: "서울 호텔"},
+	)
+
+	search := func(req *http.Request) ([]string, float64) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		searchHandler(rec, req)
+		var response struct {
+			Hits []struct {
+				ID string `json:"id"`
+			} `json:"hits"`
+			MaxScore float64 `json:"max_score"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("search = %d %s", rec.Code, rec.Body)
+		}
+		var ids []string
+		for _, hit := range response.Hits {
+			ids = append(ids, hit.ID)
+		}
+		return ids, response.MaxScore
+	}
+	get := func(boostFields string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {"서울"}, "boost_fields": {boostFields}}.Encode(), nil)
+	}
+	post := func(titleBoost, contentBoost float64) *http.Request {
+		body, _ := json.Marshal(map[string]any{"should": []map[string]any{
+			{"field": "title", "query": "서울", "boost": titleBoost},
+			{"field": "content", "query": "서울", "boost": contentBoost},
+		}})
+		return httptest.NewRequest(http.MethodPost, "/search", bytes.NewReader(body))
+	}
+
+	_, tiedScore := search(get("title:1,content:1"))
+	for _, tt := range []struct {
+		name  string
+		req   *http.Request
+		first string
+	}{
+		{"boost_fields title", get("title:3,content:1"), "1"},
+		{"boost_fields content", get("title:1,content:3"), "2"},
+		{"clause boost title", post(3, 1), "1"},
+		{"clause boost content", post(1, 3), "2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, maxScore := search(tt.req)
+			if len(ids) != 2 || ids[0] != tt.first {
+				t.Errorf("hits = %v, want document %s first", ids, tt.first)
+			}
+			if maxScore <= tiedScore {
+				t.Errorf("max_score = %v, want more than the unboosted %v", maxScore, tiedScore)
+			}
+		})
+	}
+}
+
+func TestNumericAndDateRanges(t *testing.T) {
+	price := func(value float64) *float64 { return &value }
+	created := func(day int) *time.Time {
+		value := time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+		return &value
+	}
+	// 문자열로 비교하면 "10000" < "2000" < "999"
+	memIndex := useMemoryIndex(t)
+	for i, doc := range []documentBody{
+		{Title: "1", Content: "상품", Price: price(999), CreatedAt: created(1)},
+		{Title: "2", Content: "상품", Price: price(1000), CreatedAt: created(2)},
+		{Title: "3", Content: "상품", Price: price(1500.5), CreatedAt: created(10)},
+		{Title: "4", Content: "상품", Price: price(2000), CreatedAt: created(20)},
+		{Title: "5", Content: "상품", Price: price(10000), CreatedAt: created(30)},
+	} {
+		if err := memIndex.Index(strconv.Itoa(i+1), doc.indexDocument(doc.Content, *doc.CreatedAt, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		params url.Values
+		ids    []string
+	}{
+		{"price between 1000 and 2000", url.Values{"min_price": {"1000"}, "max_price": {"2000"}}, []string{"2", "3", "4"}},
+		{"exclusive bounds", url.Values{"gt_price": {"1000"}, "lt_price": {"2000"}}, []string{"3"}},
+		{"lower bound only", url.Values{"min_price": {"2000"}}, []string{"4", "5"}},
+		{"sort by price", url.Values{"sort": {"price"}}, []string{"1", "2", "3", "4", "5"}},
+		{"sort by price descending", url.Values{"sort": {"-price"}}, []string{"5", "4", "3", "2", "1"}},
+		{"created between", url.Values{"after": {"2024-03-02T00:00:00Z"}, "before": {"2024-03-20T00:00:00Z"}}, []string{"2", "3"}},
+		{"sort by created_at descending", url.Values{"sort": {"-created_at"}, "max_price": {"1500.5"}}, []string{"3", "2", "1"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Set("q", "상품")
+			code, ids := rankedIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+tt.params.Encode(), nil))
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", code)
+			}
+			if !tt.params.Has("sort") {
+				slices.Sort(ids)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("hits = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	if code, _ := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?q=상품&min_price=abc", nil)); code != http.StatusBadRequest {
+		t.Errorf("non-numeric bound = %d, want 400", code)
+	}
+}
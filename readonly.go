@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+)
+
+// 읽기 전용 모드 (READ_ONLY=true)
+// 여러 서버가 미리 만든 인덱스 볼륨을 함께 쓰고 한 서버만 쓰는 구성을 위한 모드로, 인덱스를 bleve의 읽기 전용 옵션으로 열고
+// 변경 요청은 403으로 거부하며, 인덱스가 없거나 열 수 없으면 데이터베이스에서 만들지 않고 바로 종료한다.
+// 읽기 전용으로 연 scorch 인덱스는 색인 요청에 오류를 반환하지 않고 멈추므로, 변경 요청은 반드시 핸들러에 닿기 전에 거부해야 한다.
+var readOnly = false
+
+// 읽기 전용 모드에서 변경 요청을 거부할 때의 메시지
+const readOnlyMessage = "Server is in read-only mode (READ_ONLY=true): send writes to the writer instance"
+
+// 서버 모드 (하트비트에 포함되어 로드 밸런서가 쓰기 요청을 보낼 서버를 고를 수 있음)
+const (
+	serverModeReadWrite = "read_write"
+	serverModeReadOnly  = "read_only"
+)
+
+// 현재 서버 모드를 반환하는 함수
+func serverMode() string {
+	if readOnly {
+		return serverModeReadOnly
+	}
+	return serverModeReadWrite
+}
+
+// 변경만 하는 핸들러를 읽기 전용 모드에서 403으로 거부하도록 감싸는 함수
+func rejectInReadOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if readOnly {
+			http.Error(w, readOnlyMessage, http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// 조회와 변경을 함께 처리하는 핸들러를 읽기 전용 모드에서 GET/HEAD만 받도록 감싸는 함수
+func rejectWritesInReadOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if readOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, readOnlyMessage, http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 인덱스를 지우고 다시 만드는 작업의 종류 (재색인 작업과 같은 목록에 기록되어 동시에 실행되지 않음)
+const reindexRecreate = "recreate"
+
+// 인덱스 재생성 핸들러 (DELETE /admin/index?confirm={문서 수}&rebuild=true)
+// 실수로 호출하지 않도록 confirm에 현재 인덱스의 문서 수를 그대로 넘겨야 한다.
+// 현재 인덱스를 닫고 디렉터리를 지운 뒤 현재 매핑으로 빈 인덱스를 만든다.
+// rebuild=true이면 빈 인덱스를 데이터베이스에서 채우는 재색인을 백그라운드에서 시작하고 202로 응답하며,
+// 다 채울 때까지 검색은 진행률과 함께 503을 받고 문서 추가/수정은 새 인덱스에 반영된다.
+func recreateIndexHandler(w http.ResponseWriter, r *http.Request) {
+	live, ok := index.(*swappableIndex)
+	if !ok {
+		http.Error(w, "Index is not initialized", http.StatusInternalServerError)
+		return
+	}
+	if !indexReady(w) {
+		return
+	}
+	count, err := live.DocCount()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	confirm := r.URL.Query().Get("confirm")
+	if confirm == "" {
+		http.Error(w, fmt.Sprintf("Missing query parameter 'confirm': pass the current document count (%d) to delete the index", count), http.StatusBadRequest)
+		return
+	}
+	if confirm != strconv.FormatUint(count, 10) {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'confirm': must be the current document count (%d)", count), http.StatusBadRequest)
+		return
+	}
+	rebuild := r.URL.Query().Get("rebuild") == "true"
+
+	job, started, err := reindexes.start(reindexRecreate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	rebuilt, path, previous, err := live.recreate(rebuild)
+	if err != nil {
+		reindexes.finish(reindexJob{}, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Deleted index %s with %d documents and created %s", previous, count, path)
+	suggester.markDirty()
+
+	if !rebuild {
+		reindexes.finish(reindexJob{}, nil)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"job":                 job.ID,
+			"index_path":          path,
+			"previous_index_path": previous,
+			"deleted_documents":   count,
+		})
+		return
+	}
+
+	go func() {
+		var result reindexJob
+		var err error
+		result.Documents, err = live.populate(rebuilt, path)
+		if err != nil {
+			log.Printf("Rebuilding recreated index failed: %v", err)
+		} else {
+			suggester.markDirty()
+			log.Printf("Rebuilt recreated index %s with %d documents", path, result.Documents)
+		}
+		reindexes.finish(result, err)
+	}()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// 현재 인덱스를 별칭에서 빼고 닫은 뒤 디렉터리를 지우고, 현재 매핑으로 만든 빈 인덱스로 바꾸는 함수
+// rebuild가 true이면 빈 인덱스를 별칭에 넣지 않고 재색인 중인 인덱스로 두어 반환하며, 호출 측에서 populate로 채워야 한다.
+// 새 인덱스와 지운 인덱스의 디렉터리를 반환한다.
+// 잠금을 잡은 채 별칭을 바꾸므로 이후의 변경은 이전 인덱스에 가지 않고, 진행 중인 검색은 이전 인덱스를 닫기 전에 끝난다.
+func (s *swappableIndex) recreate(rebuild bool) (*rebuildingIndex, string, string, error) {
+	created, path, err := newEmptyIndex()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	s.mu.Lock()
+	previous, previousPath := s.current, s.path
+	var rebuilt *rebuildingIndex
+	if rebuild && !indexInMemory {
+		// 채우다 종료되어도 지운 디렉터리를 가리키지 않도록 현재 인덱스 기록을 지움 (다음 시작 때 다시 만듦)
+		err = os.Remove(filepath.Join(indexDir, currentIndexFile))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		err = writeCurrentIndex(filepath.Base(path))
+	}
+	if err != nil {
+		s.mu.Unlock()
+		created.Close()
+		os.RemoveAll(path)
+		return nil, "", "", fmt.Errorf("Failed to reset current index: %w", err)
+	}
+
+	if previous != nil {
+		s.IndexAlias.Remove(previous)
+	}
+	s.current, s.path = nil, ""
+	if rebuild {
+		rebuilt = &rebuildingIndex{bleveIndex: created, written: make(map[string]bool)}
+		s.rebuilding = rebuilt
+	} else {
+		s.IndexAlias.Add(created)
+		s.current, s.path = created, path
+	}
+	s.mu.Unlock()
+
+	if previous != nil {
+		closeAndRemoveIndex(previous, previousPath)
+	}
+	return rebuilt, path, previousPath, nil
+}
+
+// 별칭에서 뺀 인덱스를 닫고 디렉터리를 지우는 함수 (Close는 진행 중인 검색이 끝날 때까지 기다림)
+func closeAndRemoveIndex(previous bleve.Index, path string) {
+	if err := previous.Close(); err != nil {
+		log.Printf("Failed to close index %s: %v", path, err)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		log.Printf("Failed to remove index %s: %v", path, err)
+	}
+}
@@ -0,0 +1,880 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	indexapi "github.com/blevesearch/bleve_index_api"
+)
+
+// 인덱스 디렉터리 이름의 접두사(뒤에 만든 시각이 붙음), 현재 인덱스 디렉터리 이름을 기록하는 파일,
+// 그 이전 버전에서 사용하던 인덱스 디렉터리, 교체한 이전 인덱스를 닫고 지우기 전에 기다리는 시간
+const (
+	indexDirPrefix         = ".index-"
+	indexDirTimeFormat     = "20060102T150405.000000000"
+	currentIndexFile       = ".index.current"
+	legacyIndexDir         = ".index"
+	indexRetireGracePeriod = time.Minute
+)
+
+// 인덱스 디렉터리를 만드는 위치 (INDEX_PATH로 변경)
+var indexDir = "."
+
+// 재색인 방식 (full은 새 인덱스를 만들어 교체, incremental은 마지막 동기화 이후 바뀐 행만 현재 인덱스에 반영)
+const (
+	reindexFull        = "full"
+	reindexIncremental = "incremental"
+)
+
+// 재색인 작업 상태
+const (
+	reindexRunning   = "running"
+	reindexSucceeded = "succeeded"
+	reindexFailed    = "failed"
+)
+
+// 처음 인덱스를 만드는 중이라 아직 검색할 수 없음을 나타내는 오류 (503으로 응답)
+var errIndexBuilding = errors.New("Index is not ready")
+
+// 실행 중에 새 인덱스로 교체할 수 있도록 별칭으로 감싼 인덱스
+// 검색은 별칭을 거치므로 교체는 별칭이 가리키는 인덱스를 바꾸는 것만으로 끝나고, 이전 인덱스는 진행 중인 검색이 끝나도록 잠시 뒤에 닫는다.
+// 색인은 읽기 잠금을 잡고 실행되며, 재색인하는 동안에는 같은 변경을 만들고 있는 새 인덱스에도 반영한다.
+// 처음 인덱스를 만드는 동안에는 별칭이 비어 있으며, 검색은 errIndexBuilding을 반환하고 변경은 새 인덱스에만 반영한다.
+type swappableIndex struct {
+	bleve.IndexAlias
+	mu sync.RWMutex
+	// 별칭이 가리키는 인덱스와 그 디렉터리
+	current    bleve.Index
+	path       string
+	rebuilding *rebuildingIndex
+}
+
+// 현재 인덱스 디렉터리를 열어 별칭으로 감싸는 함수 (현재 인덱스가 없으면 빈 별칭을 반환)
+// 현재 인덱스가 아닌 인덱스 디렉터리는 만들다 중단되었거나 지우기 전에 종료된 것이므로 지운다.
+// 단, 현재 인덱스가 없을 때 처음 만들다 중단된 인덱스는 rebuild가 이어서 만들도록 남긴다.
+// 백엔드가 설정과 다른 경우가 아닌데 열 수 없으면 corruptIndexError를 반환한다.
+func openSwappableIndex() (*swappableIndex, error) {
+	live := &swappableIndex{IndexAlias: bleve.NewIndexAlias()}
+	if indexInMemory {
+		return live, nil
+	}
+	name, err := readCurrentIndex()
+	if err != nil {
+		return nil, err
+	}
+	// 처음 인덱스를 만들다 중단된 디렉터리는 이어서 만들도록 남김 (현재 인덱스가 있으면 중단된 재색인이므로 지움)
+	var building string
+	if name == "" {
+		if building, err = readBuildingIndex(); err != nil {
+			return nil, err
+		}
+	} else if !readOnly {
+		removeBuildingIndex()
+	}
+	// 읽기 전용 모드에서는 함께 쓰는 볼륨의 디렉터리를 지우지 않음 (쓰기 서버가 정리)
+	if !readOnly {
+		if err := removeStaleIndexes(name, building); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return live, nil
+	}
+	path := filepath.Join(indexDir, name)
+	opened, err := openIndex(path)
+	var mismatch *indexTypeMismatchError
+	if errors.As(err, &mismatch) {
+		return nil, fmt.Errorf("Failed to open index %s: %w", path, err)
+	}
+	if err != nil {
+		return nil, &corruptIndexError{path: path, err: err}
+	}
+	live.IndexAlias.Add(opened)
+	live.current, live.path = opened, path
+	return live, nil
+}
+
+// 현재 인덱스 디렉터리 이름을 읽는 함수 (기록된 이름이 없으면 이전 버전의 인덱스 디렉터리, 그것도 없으면 빈 문자열)
+func readCurrentIndex() (string, error) {
+	name, err := os.ReadFile(filepath.Join(indexDir, currentIndexFile))
+	if err == nil {
+		return strings.TrimSpace(string(name)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("Failed to read current index: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(indexDir, legacyIndexDir)); err == nil {
+		return legacyIndexDir, nil
+	}
+	return "", nil
+}
+
+// 현재 인덱스 디렉터리 이름을 기록하는 함수 (임시 파일에 쓴 뒤 이름을 바꾸므로 도중에 종료되어도 이전 기록이 남음)
+func writeCurrentIndex(name string) error {
+	if indexInMemory {
+		return nil
+	}
+	path := filepath.Join(indexDir, currentIndexFile)
+	if err := os.WriteFile(path+".tmp", []byte(name+"\n"), 0o644); err != nil {
+		return fmt.Errorf("Failed to write current index: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("Failed to write current index: %w", err)
+	}
+	return nil
+}
+
+// 현재 인덱스(와 이어서 만들 인덱스)가 아닌 인덱스 디렉터리와 백업하다 중단된 사본 디렉터리를 지우는 함수
+func removeStaleIndexes(keep ...string) error {
+	entries, err := os.ReadDir(indexDir)
+	if err != nil {
+		return fmt.Errorf("Failed to read index directory: %w", err)
+	}
+	for _, entry := range entries {
+		stale := strings.HasPrefix(entry.Name(), indexDirPrefix) && !slices.Contains(keep, entry.Name())
+		if !entry.IsDir() || !(stale || strings.HasPrefix(entry.Name(), backupSnapshotDir)) {
+			continue
+		}
+		log.Printf("Removing stale index directory %s", entry.Name())
+		if err := os.RemoveAll(filepath.Join(indexDir, entry.Name())); err != nil {
+			return fmt.Errorf("Failed to remove stale index directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// 검색할 수 있는 인덱스가 있는지 확인하는 함수
+func (s *swappableIndex) ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current != nil
+}
+
+// 별칭이 가리키는 인덱스의 디렉터리를 반환하는 함수 (처음 인덱스를 만드는 중이면 빈 문자열)
+func (s *swappableIndex) directory() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.path
+}
+
+func (s *swappableIndex) Index(id string, data interface{}) error {
+	return s.apply(func() error { return s.IndexAlias.Index(id, data) }, map[string]interface{}{id: data}, nil)
+}
+
+func (s *swappableIndex) Delete(id string) error {
+	return s.apply(func() error { return s.IndexAlias.Delete(id) }, nil, []string{id})
+}
+
+// Batch에 담긴 변경은 새 인덱스에 반영할 수 없으므로 재색인과 함께 쓰려면 applyBatch를 사용해야 한다.
+func (s *swappableIndex) Batch(b *bleve.Batch) error {
+	return s.batch(b, nil, nil)
+}
+
+func (s *swappableIndex) batch(b *bleve.Batch, docs map[string]interface{}, deleted []string) error {
+	return s.apply(func() error { return s.IndexAlias.Batch(b) }, docs, deleted)
+}
+
+// 문서를 Batch 하나로 색인하고, 재색인 중이면 같은 문서를 새 인덱스에도 반영하는 함수
+func (s *swappableIndex) indexDocuments(ids []string, docs []indexDocument) error {
+	batch := s.NewBatch()
+	mirrored := make(map[string]interface{}, len(ids))
+	for i, id := range ids {
+		if err := batch.Index(id, docs[i]); err != nil {
+			return err
+		}
+		mirrored[id] = docs[i]
+	}
+	return s.batch(batch, mirrored, nil)
+}
+
+// 현재 인덱스에 변경을 반영하고, 재색인 중이면 같은 변경을 새 인덱스에도 반영하는 함수
+func (s *swappableIndex) apply(change func() error, docs map[string]interface{}, deleted []string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.current == nil && s.rebuilding == nil {
+		return errIndexBuilding
+	}
+	if s.current != nil {
+		if err := change(); err != nil {
+			return err
+		}
+	}
+	if s.rebuilding != nil {
+		s.rebuilding.mirror(docs, deleted)
+	}
+	return nil
+}
+
+// 처음 인덱스를 만드는 중이면 만들고 있는 인덱스의 Batch를 반환한다 (Batch는 새 인덱스에만 반영됨).
+func (s *swappableIndex) NewBatch() *bleve.Batch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.current == nil && s.rebuilding != nil {
+		return s.rebuilding.bleveIndex.NewBatch()
+	}
+	return s.IndexAlias.NewBatch()
+}
+
+// 처음 인덱스를 만드는 중이면 만들고 있는 인덱스의 매핑을 반환한다.
+func (s *swappableIndex) Mapping() mapping.IndexMapping {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.current == nil && s.rebuilding != nil {
+		return s.rebuilding.Mapping()
+	}
+	return s.IndexAlias.Mapping()
+}
+
+// 아래 조회 함수는 별칭이 비어 있으면 (처음 인덱스를 만드는 중) errIndexBuilding을 반환한다.
+
+func (s *swappableIndex) SearchInContext(ctx context.Context, req *bleve.SearchRequest) (*bleve.SearchResult, error) {
+	result, err := s.IndexAlias.SearchInContext(ctx, req)
+	return result, aliasError(err)
+}
+
+func (s *swappableIndex) Document(id string) (indexapi.Document, error) {
+	doc, err := s.IndexAlias.Document(id)
+	return doc, aliasError(err)
+}
+
+func (s *swappableIndex) FieldDict(field string) (indexapi.FieldDict, error) {
+	dict, err := s.IndexAlias.FieldDict(field)
+	return dict, aliasError(err)
+}
+
+func (s *swappableIndex) FieldDictPrefix(field string, termPrefix []byte) (indexapi.FieldDict, error) {
+	dict, err := s.IndexAlias.FieldDictPrefix(field, termPrefix)
+	return dict, aliasError(err)
+}
+
+func (s *swappableIndex) Advanced() (indexapi.Index, error) {
+	advanced, err := s.IndexAlias.Advanced()
+	return advanced, aliasError(err)
+}
+
+func (s *swappableIndex) GetInternal(key []byte) ([]byte, error) {
+	value, err := s.IndexAlias.GetInternal(key)
+	return value, aliasError(err)
+}
+
+// 별칭이 비어 있다는 오류를 errIndexBuilding으로 바꾸는 함수
+func aliasError(err error) error {
+	if errors.Is(err, bleve.ErrorAliasEmpty) {
+		return errIndexBuilding
+	}
+	return err
+}
+
+// 별칭과 별칭이 가리키는 인덱스를 닫는 함수
+func (s *swappableIndex) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.IndexAlias.Close()
+	if s.current == nil {
+		return nil
+	}
+	return s.current.Close()
+}
+
+// 인덱스를 사용할 수 없으면 그 이유를 반환하는 함수 (처음 인덱스를 만드는 중이거나 만들지 못했으면 errIndexBuilding)
+func indexUnavailable() error {
+	if index == nil {
+		return errors.New("Index is not initialized")
+	}
+	if s, ok := index.(*swappableIndex); ok && !s.ready() {
+		return errIndexBuilding
+	}
+	return nil
+}
+
+// 인덱스를 사용할 수 있는지 확인하는 함수 (사용할 수 없으면 오류를 응답하고 false를 반환)
+func indexReady(w http.ResponseWriter) bool {
+	err := indexUnavailable()
+	if errors.Is(err, errIndexBuilding) {
+		writeIndexBuilding(w)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// 처음 인덱스를 만드는 중임을 진행률과 함께 503으로 응답하는 함수
+func writeIndexBuilding(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "30")
+	http.Error(w, reindexes.buildingMessage(), http.StatusServiceUnavailable)
+}
+
+// Batch를 색인하는 함수 (docs와 deleted는 Batch에 담은 문서와 지운 문서 ID)
+// Batch에 담긴 문서는 이미 이전 인덱스의 매핑으로 분석되어 있으므로, 재색인 중이면 docs와 deleted로 새 인덱스에 따로 반영한다.
+func applyBatch(batch *bleve.Batch, docs map[string]interface{}, deleted []string) error {
+	if s, ok := index.(*swappableIndex); ok {
+		return s.batch(batch, docs, deleted)
+	}
+	return index.Batch(batch)
+}
+
+// 인덱스를 감싸는 타입에 임베드하기 위한 별칭 (임베드한 필드 이름이 Index 메서드와 겹치지 않도록)
+type bleveIndex = bleve.Index
+
+// 재색인 중인 새 인덱스
+// Postgres에서 행을 읽는 동안 이전 인덱스에 반영된 변경도 받으며, 읽은 행보다 직접 받은 변경이 최신이므로 우선한다.
+type rebuildingIndex struct {
+	bleveIndex
+	mu      sync.Mutex
+	written map[string]bool
+	// 변경을 반영하다 처음 발생한 오류 (있으면 교체하지 않음)
+	err error
+}
+
+// Postgres에서 읽은 행을 색인하는 함수 (읽은 뒤 바뀐 문서는 이미 최신 내용이 반영되어 있으므로 건너뜀)
+func (r *rebuildingIndex) Index(id string, data interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.written[id] {
+		return nil
+	}
+	return r.bleveIndex.Index(id, data)
+}
+
+// Postgres에서 읽은 행을 Batch로 색인하는 함수 (Index와 마찬가지로 읽은 뒤 바뀐 문서는 건너뜀)
+func (r *rebuildingIndex) indexDocuments(ids []string, docs []indexDocument) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batch := r.bleveIndex.NewBatch()
+	for i, id := range ids {
+		if r.written[id] {
+			continue
+		}
+		if err := batch.Index(id, docs[i]); err != nil {
+			return err
+		}
+	}
+	return r.bleveIndex.Batch(batch)
+}
+
+// 이전 인덱스에 반영된 변경을 새 인덱스에도 반영하는 함수
+func (r *rebuildingIndex) mirror(docs map[string]interface{}, deleted []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, doc := range docs {
+		r.written[id] = true
+		if err := r.bleveIndex.Index(id, doc); err != nil && r.err == nil {
+			r.err = fmt.Errorf("Failed to index document %s changed during reindex: %w", id, err)
+		}
+	}
+	for _, id := range deleted {
+		r.written[id] = true
+		if err := r.bleveIndex.Delete(id); err != nil && r.err == nil {
+			r.err = fmt.Errorf("Failed to delete document %s changed during reindex: %w", id, err)
+		}
+	}
+}
+
+// 현재 Postgres 내용으로 새 인덱스 디렉터리에 인덱스를 만들어 교체하는 함수 (새 인덱스의 문서 수를 반환)
+// 새 인덱스를 만드는 동안 검색은 이전 인덱스로 계속하고, 다 만들면 현재 인덱스 기록을 바꾼 뒤 별칭을 새 인덱스로 바꾼다.
+// 실패하면 만들던 디렉터리를 지우며, 교체하기 전에 종료되면 다음 시작 때 지워진다.
+func (s *swappableIndex) rebuild() (uint64, error) {
+	// 처음 인덱스를 만들다 중단되었으면 그 인덱스를 이어서 만듦
+	s.mu.RLock()
+	first := s.current == nil
+	s.mu.RUnlock()
+	var created bleve.Index
+	var path string
+	if first {
+		created, path = openInterruptedBuild()
+	}
+	if created == nil {
+		var err error
+		if created, path, err = newEmptyIndex(); err != nil {
+			return 0, err
+		}
+	}
+	rebuilt := &rebuildingIndex{bleveIndex: created, written: make(map[string]bool)}
+
+	s.mu.Lock()
+	s.rebuilding = rebuilt
+	s.mu.Unlock()
+	return s.populate(rebuilt, path)
+}
+
+// 데이터베이스에서 채우지 않고 빈 인덱스로 시작하는 함수 (데이터베이스 없이 메모리 인덱스로 실행할 때)
+func (s *swappableIndex) startEmpty() error {
+	created, path, err := newEmptyIndex()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.install(created, path)
+}
+
+// 현재 매핑으로 새 인덱스 디렉터리에 빈 인덱스를 만드는 함수 (만든 인덱스와 디렉터리를 반환)
+func newEmptyIndex() (bleve.Index, string, error) {
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to build index mapping: %w", err)
+	}
+	path := newIndexDirectory()
+	created, err := newIndex(path, indexMapping)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to create index: %w", err)
+	}
+	return created, path, nil
+}
+
+// 재색인 중인 새 인덱스(s.rebuilding)를 Postgres 내용으로 채우고 현재 인덱스로 교체하는 함수 (새 인덱스의 문서 수를 반환)
+func (s *swappableIndex) populate(rebuilt *rebuildingIndex, path string) (uint64, error) {
+	created := rebuilt.bleveIndex
+	err := writeBuildingIndex(filepath.Base(path))
+	if err == nil {
+		err = createIndexFromDatabase(rebuilt)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rebuilding = nil
+	if err == nil {
+		err = rebuilt.err
+	}
+	var count uint64
+	if err == nil {
+		count, err = created.DocCount()
+	}
+	if err == nil {
+		err = s.install(created, path)
+	}
+	if err != nil {
+		created.Close()
+		os.RemoveAll(path)
+		removeBuildingIndex()
+		return 0, err
+	}
+	removeBuildingIndex()
+	return count, nil
+}
+
+// 새 인덱스를 현재 인덱스로 기록하고 별칭을 새 인덱스로 바꾸는 함수 (호출 측에서 s.mu를 잡고 있어야 함)
+// 기록에 실패하면 별칭을 바꾸지 않으며, 이전 인덱스는 잠시 뒤에 닫고 지운다.
+func (s *swappableIndex) install(created bleve.Index, path string) error {
+	if err := writeCurrentIndex(filepath.Base(path)); err != nil {
+		return err
+	}
+	previous, previousPath := s.current, s.path
+	if previous != nil {
+		s.IndexAlias.Swap([]bleve.Index{created}, []bleve.Index{previous})
+		retireIndex(previous, previousPath)
+	} else {
+		s.IndexAlias.Add(created)
+	}
+	s.current, s.path = created, path
+	return nil
+}
+
+// 새 인덱스를 만들 디렉터리 경로 (이름에 만든 시각이 붙음, 메모리 인덱스 모드면 빈 문자열)
+func newIndexDirectory() string {
+	if indexInMemory {
+		return ""
+	}
+	return filepath.Join(indexDir, indexDirPrefix+time.Now().UTC().Format(indexDirTimeFormat))
+}
+
+// 교체한 이전 인덱스를 진행 중인 검색이 끝나도록 잠시 기다린 뒤 닫고 디렉터리를 지우는 함수
+func retireIndex(previous bleve.Index, path string) {
+	time.AfterFunc(indexRetireGracePeriod, func() {
+		if err := previous.Close(); err != nil {
+			log.Printf("Failed to close previous index %s: %v", path, err)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf("Failed to remove previous index %s: %v", path, err)
+		}
+	})
+}
+
+// 인덱스 내부 저장소에 기록하는 마지막 동기화 기준 시각의 키
+// 인덱스와 함께 저장되므로 전체 재색인으로 인덱스를 교체하면 새 인덱스의 기준 시각을 사용하게 된다.
+var syncWatermarkKey = []byte("sync_watermark")
+
+// 다음 동기화 기준 시각을 구하는 함수
+// updated_at은 트랜잭션 시작 시각이므로, 지금 열려 있는 트랜잭션이 나중에 커밋하는 행을 놓치지 않도록
+// 가장 오래된 트랜잭션의 시작 시각을 사용한다.
+func nextSyncWatermark() ([]byte, error) {
+	var watermark time.Time
+	err := db.QueryRow("SELECT LEAST(now(), COALESCE(MIN(xact_start), now())) FROM pg_stat_activity WHERE datname = current_database()").Scan(&watermark)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read sync watermark: %w", err)
+	}
+	return []byte(watermark.Format(time.RFC3339Nano)), nil
+}
+
+// 인덱스에 기록된 마지막 동기화 기준 시각을 읽는 함수
+func readSyncWatermark() (time.Time, error) {
+	raw, err := index.GetInternal(syncWatermarkKey)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to read sync watermark: %w", err)
+	}
+	if raw == nil {
+		return time.Time{}, errors.New("Index has no sync watermark: run a full reindex first")
+	}
+	since, err := time.Parse(time.RFC3339Nano, string(raw))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid sync watermark: %w", err)
+	}
+	return since, nil
+}
+
+// 마지막 동기화 이후 바뀐 행만 다시 색인하고, 삭제 표시되거나 완전히 삭제된 행은 인덱스에서 지우는 함수 (색인한 수와 지운 수를 반환)
+// 형태소 분석은 저장된 분석 결과가 없는 행에만 수행한다.
+// 변경은 indexBatchSize개씩 나눠 반영하고 새 기준 시각은 마지막 Batch를 반영한 뒤에 기록하므로,
+// 도중에 실패하면 기준 시각이 그대로 남아 다음 실행에서 같은 행을 다시 처리한다.
+func reindexChangedDocuments() (int, int, error) {
+	since, err := readSyncWatermark()
+	if err != nil {
+		return 0, 0, err
+	}
+	next, err := nextSyncWatermark()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM documents WHERE "+indexableDocumentsFilter+" AND updated_at >= $1", since).Scan(&total); err != nil {
+		return 0, 0, fmt.Errorf("Failed to count documents: %w", err)
+	}
+	reindexes.setTotal(total)
+
+	batcher := &documentBatcher{target: index, size: indexBatchSize}
+	// 분석에 실패한 행을 건너뛰면 기준 시각이 그 행을 지나치므로 실패하면 중단
+	changed := &indexRowPages{query: indexableDocumentsSQL + " AND updated_at >= $1", args: []interface{}{since}, cacheResult: reindexes.recordCacheResult}
+	indexed, err := indexRows(changed, batcher.add, func(err error) error {
+		reindexes.advance(err)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := batcher.flush(); err != nil {
+		return 0, 0, err
+	}
+
+	// 바뀌었지만 색인 대상이 아닌 행(삭제 표시, 만료, 이름 붙은 인덱스의 문서)과 완전히 삭제된 행
+	// 두 쿼리 사이에 색인 대상이 된 행은 updated_at이 새 기준 시각 이후이므로 다음 실행에서 색인된다.
+	deleted, err := deleteChangedDocuments(since)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := index.SetInternal(syncWatermarkKey, next); err != nil {
+		return 0, 0, fmt.Errorf("Failed to record sync watermark: %w", err)
+	}
+	return indexed, deleted, nil
+}
+
+// 기준 시각 이후 색인 대상에서 빠진 행을 indexBatchSize개씩 인덱스에서 지우는 함수 (지운 수를 반환)
+func deleteChangedDocuments(since time.Time) (int, error) {
+	rows, err := db.Query("SELECT id FROM documents WHERE updated_at >= $1 AND NOT ("+indexableDocumentsFilter+") UNION SELECT id FROM document_tombstones WHERE deleted_at >= $1", since)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to query deleted documents: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	deleted := 0
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		batch := index.NewBatch()
+		for _, id := range ids {
+			batch.Delete(id)
+		}
+		if err := applyBatch(batch, nil, ids); err != nil {
+			return fmt.Errorf("Failed to index data: %w", err)
+		}
+		deleted += len(ids)
+		ids = nil
+		return nil
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("Failed to scan row: %w", err)
+		}
+		ids = append(ids, strconv.Itoa(id))
+		if len(ids) >= indexBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("Error iterating over rows: %w", err)
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// 재색인 작업 하나의 상태
+type reindexJob struct {
+	ID         string     `json:"id"`
+	Mode       string     `json:"mode"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// 전체 재색인한 새 인덱스의 문서 수 (성공한 뒤에만 채워짐)
+	Documents uint64 `json:"documents,omitempty"`
+	// 증분 재색인에서 다시 색인하거나 지운 문서 수
+	Indexed int    `json:"indexed,omitempty"`
+	Deleted int    `json:"deleted,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// 처리한 행 수와 처리할 전체 행 수, 분석에 실패해 건너뛴 행 수와 마지막 실패 이유
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`
+	Errors    int    `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+	// 저장된 분석 결과가 없어 분석한 행 중 형태소 분석 캐시에서 찾은 수와 OpenAI API로 분석한 수
+	AnalysisCacheHits   int `json:"analysis_cache_hits"`
+	AnalysisCacheMisses int `json:"analysis_cache_misses"`
+	// 초당 처리한 행 수와 남은 예상 시간 (실행 중일 때만 채워짐)
+	Throughput float64 `json:"throughput"`
+	ETASeconds *int    `json:"eta_seconds,omitempty"`
+}
+
+// 처리 속도와 남은 예상 시간을 채운 복사본을 반환하는 함수
+func (job reindexJob) withRates() reindexJob {
+	end := time.Now()
+	if job.FinishedAt != nil {
+		end = *job.FinishedAt
+	}
+	if elapsed := end.Sub(job.StartedAt).Seconds(); elapsed > 0 {
+		job.Throughput = float64(job.Processed) / elapsed
+	}
+	if job.Status == reindexRunning && job.Throughput > 0 && job.Total > job.Processed {
+		eta := int(float64(job.Total-job.Processed) / job.Throughput)
+		job.ETASeconds = &eta
+	}
+	return job
+}
+
+// 메모리에 보관하는 재색인 작업 목록 (한 번에 하나만 실행)
+type reindexRegistry struct {
+	mu      sync.Mutex
+	jobs    map[string]*reindexJob
+	running *reindexJob
+	latest  *reindexJob
+}
+
+var reindexes = &reindexRegistry{jobs: make(map[string]*reindexJob)}
+
+// 새 작업을 등록하는 함수 (이미 실행 중인 작업이 있으면 그 작업과 false를 반환)
+func (r *reindexRegistry) start(mode string) (reindexJob, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running != nil {
+		return *r.running, false, nil
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return reindexJob{}, false, fmt.Errorf("Failed to generate job ID: %w", err)
+	}
+	job := &reindexJob{ID: hex.EncodeToString(buf), Mode: mode, Status: reindexRunning, StartedAt: time.Now()}
+	r.jobs[job.ID] = job
+	r.running = job
+	r.latest = job
+	return *job, true, nil
+}
+
+// 실행 중인 작업이 처리할 전체 행 수를 기록하는 함수
+func (r *reindexRegistry) setTotal(total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running != nil {
+		r.running.Total = total
+	}
+}
+
+// 실행 중인 작업이 행 하나를 처리했음을 기록하는 함수 (err는 분석에 실패한 이유)
+func (r *reindexRegistry) advance(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running == nil {
+		return
+	}
+	r.running.Processed++
+	if err != nil {
+		r.running.Errors++
+		r.running.LastError = err.Error()
+	}
+}
+
+// 실행 중인 작업이 분석한 행의 결과를 캐시에서 찾았는지 기록하는 함수
+func (r *reindexRegistry) recordCacheResult(hit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running == nil {
+		return
+	}
+	if hit {
+		r.running.AnalysisCacheHits++
+	} else {
+		r.running.AnalysisCacheMisses++
+	}
+}
+
+// 처음 인덱스를 만드는 동안 검색 요청에 돌려줄 메시지를 만드는 함수
+func (r *reindexRegistry) buildingMessage() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.running != nil && r.running.Total > 0:
+		return fmt.Sprintf("Index building, %d%% complete", r.running.Processed*100/r.running.Total)
+	case r.running != nil:
+		return "Index building"
+	case r.latest != nil && r.latest.Status == reindexFailed:
+		return fmt.Sprintf("Index build failed: %s", r.latest.Error)
+	}
+	return errIndexBuilding.Error()
+}
+
+// 실행 중인 작업의 결과를 기록하는 함수 (result의 문서 수만 사용)
+func (r *reindexRegistry) finish(result reindexJob, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.running.FinishedAt = &now
+	r.running.Status = reindexSucceeded
+	r.running.Documents = result.Documents
+	r.running.Indexed = result.Indexed
+	r.running.Deleted = result.Deleted
+	if err != nil {
+		r.running.Status = reindexFailed
+		r.running.Error = err.Error()
+	}
+	r.running = nil
+}
+
+// 작업 상태를 복사해 반환하는 함수
+func (r *reindexRegistry) get(id string) (reindexJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return reindexJob{}, false
+	}
+	return job.withRates(), true
+}
+
+// 실행 중이거나 마지막으로 실행한 작업의 상태를 복사해 반환하는 함수
+func (r *reindexRegistry) current() (reindexJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latest == nil {
+		return reindexJob{}, false
+	}
+	return r.latest.withRates(), true
+}
+
+// 재색인 시작 핸들러 (POST /admin/reindex?mode=full|incremental&dry_run=true)
+// 작업을 백그라운드에서 시작하고 작업 ID를 바로 202로 응답한다. 이미 실행 중이면 실행 중인 작업을 409로 응답한다.
+// dry_run=true이면 작업을 시작하지 않고 분석할 행 수와 예상 OpenAI 토큰 사용량, 비용만 응답한다.
+func reindexHandler(w http.ResponseWriter, r *http.Request) {
+	live, ok := index.(*swappableIndex)
+	if !ok {
+		http.Error(w, "Index is not initialized", http.StatusInternalServerError)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = reindexFull
+	}
+	if mode != reindexFull && mode != reindexIncremental {
+		http.Error(w, "Invalid query parameter 'mode': must be full or incremental", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		writeReindexPlan(w, r, mode)
+		return
+	}
+
+	job, started, err := startReindex(live, mode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	status := http.StatusAccepted
+	if !started {
+		status = http.StatusConflict
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+// 재색인 작업을 백그라운드에서 시작하는 함수 (이미 실행 중이면 실행 중인 작업과 false를 반환)
+func startReindex(live *swappableIndex, mode string) (reindexJob, bool, error) {
+	job, started, err := reindexes.start(mode)
+	if err != nil || !started {
+		return job, started, err
+	}
+	go func() {
+		var result reindexJob
+		var err error
+		if mode == reindexIncremental {
+			result.Indexed, result.Deleted, err = reindexChangedDocuments()
+		} else {
+			result.Documents, err = live.rebuild()
+		}
+		if err != nil {
+			log.Printf("Reindex %s failed: %v", job.ID, err)
+		} else {
+			suggester.markDirty()
+			log.Printf("Reindex %s (%s) finished", job.ID, mode)
+		}
+		reindexes.finish(result, err)
+	}()
+	return job, true, nil
+}
+
+// 현재 재색인 진행 상황 핸들러 (GET /admin/reindex/status)
+// 실행 중인 작업이 없으면 마지막으로 실행한 작업의 결과를 반환한다.
+func reindexProgressHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := reindexes.current()
+	if !ok {
+		http.Error(w, "No reindex has been run", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// 재색인 작업 상태 핸들러 (GET /admin/reindex/{job})
+func reindexStatusHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := reindexes.get(r.PathValue("job"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("Reindex job not found: %s", r.PathValue("job")), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// Batch를 allowed번까지만 반영하고 그 뒤로는 실패하는 인덱스
+type failingBatchIndex struct {
+	bleveIndex
+	allowed int
+}
+
+func (f *failingBatchIndex) Batch(b *bleve.Batch) error {
+	if f.allowed == 0 {
+		return errors.New("disk full")
+	}
+	f.allowed--
+	return f.bleveIndex.Batch(b)
+}
+
+// 아주 오래된 동기화 기준 시각을 기록해 모든 행이 바뀐 것으로 보이게 하는 함수
+func resetSyncWatermark(t *testing.T) []byte {
+	t.Helper()
+	watermark := []byte("2000-01-01T00:00:00Z")
+	if err := index.SetInternal(syncWatermarkKey, watermark); err != nil {
+		t.Fatal(err)
+	}
+	return watermark
+}
+
+func TestReindexChangedDocumentsInChunks(t *testing.T) {
+	testDB := useTestDB(t)
+	memIndex := useMemoryIndex(t)
+	defer func(size int) { indexBatchSize = size }(indexBatchSize)
+	indexBatchSize = 2
+
+	var ids []int
+	for _, content := range []string{"서울 시청", "부산 해운대", "대구 동성로", "광주 충장로", "대전 은행동"} {
+		ids = append(ids, insertTestDocument(t, content))
+	}
+	if _, err := testDB.Exec("UPDATE documents SET deleted_at = now() WHERE id = $1", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	watermark := resetSyncWatermark(t)
+
+	// 두 번째 Batch에서 실패하면 기준 시각을 옮기지 않음
+	index = &failingBatchIndex{bleveIndex: memIndex, allowed: 1}
+	if _, _, err := reindexChangedDocuments(); err == nil {
+		t.Fatal("reindex succeeded although the second batch failed")
+	}
+	if got, err := memIndex.GetInternal(syncWatermarkKey); err != nil || string(got) != string(watermark) {
+		t.Fatalf("watermark after a failed chunk = %q (%v), want %q", got, err, watermark)
+	}
+
+	index = memIndex
+	indexed, deleted, err := reindexChangedDocuments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != len(ids)-1 || deleted != 1 {
+		t.Errorf("indexed %d and deleted %d documents, want %d and 1", indexed, deleted, len(ids)-1)
+	}
+	if got, _ := memIndex.GetInternal(syncWatermarkKey); string(got) == string(watermark) {
+		t.Error("watermark was not advanced after the last chunk")
+	}
+	if documentIndexed(t, ids[0]) {
+		t.Error("soft-deleted document is still in the index")
+	}
+	for _, id := range ids[1:] {
+		if !documentIndexed(t, id) {
+			t.Errorf("document %d was not indexed", id)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 재색인 작업 목록에 기록하는 복원 작업의 방식 (복원하는 동안에는 재색인을 시작할 수 없음)
+const reindexRestore = "restore"
+
+// 백업 파일이 잘못되었음을 나타내는 오류 (400으로 응답)
+type invalidBackupError struct {
+	err error
+}
+
+func (e *invalidBackupError) Error() string {
+	return fmt.Sprintf("Invalid backup archive: %v", e.err)
+}
+
+func (e *invalidBackupError) Unwrap() error {
+	return e.err
+}
+
+// 인덱스 복원 핸들러 (POST /admin/restore?path=...)
+// path가 있으면 서버의 백업 파일(디렉터리 없이 이름만 주면 백업 디렉터리의 파일)을, 없으면 요청 본문의 tar.gz를 복원한다.
+// 새 인덱스 디렉터리에 풀고 열어서 문서 수와 검색을 확인한 뒤에만 별칭을 바꾸므로, 손상된 백업은 현재 인덱스를 대체하지 않는다.
+// 이전 인덱스는 교체한 뒤에도 잠시 남겨 두었다가 지운다.
+func restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if indexInMemory {
+		http.Error(w, errInMemoryIndex.Error(), http.StatusNotImplemented)
+		return
+	}
+	live, ok := index.(*swappableIndex)
+	if !ok {
+		http.Error(w, "Index is not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	var archive io.Reader = r.Body
+	if path := r.URL.Query().Get("path"); path != "" {
+		if !strings.ContainsRune(path, os.PathSeparator) {
+			path = filepath.Join(backupDirectory(), path)
+		}
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, fmt.Sprintf("Backup not found: %s", path), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to open backup: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		archive = file
+	}
+
+	job, started, err := reindexes.start(reindexRestore)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+	path, previous, documents, err := live.restore(archive)
+	reindexes.finish(reindexJob{Documents: documents}, err)
+
+	var invalid *invalidBackupError
+	if errors.As(err, &invalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	suggester.markDirty()
+	log.Printf("Restored index %s with %d documents", path, documents)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job":                 job.ID,
+		"index_path":          path,
+		"previous_index_path": previous,
+		"documents":           documents,
+	})
+}
+
+// 백업 파일을 새 인덱스 디렉터리에 풀고 확인한 뒤 현재 인덱스와 교체하는 함수 (새 인덱스와 이전 인덱스의 경로, 문서 수를 반환)
+func (s *swappableIndex) restore(archive io.Reader) (string, string, uint64, error) {
+	path := newIndexDirectory()
+	if err := extractBackup(archive, path); err != nil {
+		os.RemoveAll(path)
+		return "", "", 0, err
+	}
+	restored, count, err := verifyRestoredIndex(path)
+	if err != nil {
+		os.RemoveAll(path)
+		return "", "", 0, err
+	}
+	checkIndexMapping(path, restored.Mapping())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.path
+	if err := s.install(restored, path); err != nil {
+		restored.Close()
+		os.RemoveAll(path)
+		return "", "", 0, err
+	}
+	return path, previous, count, nil
+}
+
+// 풀어 놓은 인덱스를 열어 문서 수를 읽고 검색이 되는지 확인하는 함수 (확인한 인덱스와 문서 수를 반환)
+func verifyRestoredIndex(path string) (bleve.Index, uint64, error) {
+	restored, err := openIndex(path)
+	var mismatch *indexTypeMismatchError
+	if errors.As(err, &mismatch) {
+		return nil, 0, &invalidBackupError{err: err}
+	}
+	if err != nil {
+		return nil, 0, &invalidBackupError{err: fmt.Errorf("failed to open index: %w", err)}
+	}
+	count, err := restored.DocCount()
+	if err != nil {
+		restored.Close()
+		return nil, 0, &invalidBackupError{err: fmt.Errorf("failed to count documents: %w", err)}
+	}
+	if _, err := restored.Search(bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), 1, 0, false)); err != nil {
+		restored.Close()
+		return nil, 0, &invalidBackupError{err: fmt.Errorf("sample search failed: %w", err)}
+	}
+	return restored, count, nil
+}
+
+// tar.gz를 dest 디렉터리에 푸는 함수
+// 디렉터리 밖을 가리키는 경로나 일반 파일과 디렉터리가 아닌 항목이 있으면 invalidBackupError를 반환한다.
+func extractBackup(archive io.Reader, dest string) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return &invalidBackupError{err: err}
+	}
+	defer gz.Close()
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("Failed to create index directory: %w", err)
+	}
+
+	entries := tar.NewReader(gz)
+	for {
+		header, err := entries.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &invalidBackupError{err: err}
+		}
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return &invalidBackupError{err: fmt.Errorf("unsafe path %q", header.Name)}
+		}
+		target := filepath.Join(dest, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("Failed to extract backup: %w", err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("Failed to extract backup: %w", err)
+			}
+			if err := extractFile(entries, target); err != nil {
+				return err
+			}
+		default:
+			return &invalidBackupError{err: fmt.Errorf("unsupported entry %q", header.Name)}
+		}
+	}
+}
+
+// tar 항목 하나를 파일로 쓰는 함수 (항목이 중간에 끊기면 invalidBackupError)
+func extractFile(entry io.Reader, target string) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("Failed to extract backup: %w", err)
+	}
+	if _, err := io.Copy(file, entry); err != nil {
+		file.Close()
+		return &invalidBackupError{err: err}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Failed to extract backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// 스크롤 배치 크기 기본값과 최대값, 커서 유효 시간
+const (
+	defaultScrollBatchSize = 100
+	maxScrollBatchSize     = 1000
+	scrollTTL              = 5 * time.Minute
+)
+
+// 스크롤 시작 요청 본문 (query는 POST /search와 같은 형식)
+type scrollRequest struct {
+	Query     json.RawMessage `json:"query"`
+	BatchSize *int            `json:"batch_size"`
+}
+
+// 스크롤 응답 (hits가 batch_size보다 적으면 마지막 배치)
+type scrollResponse struct {
+	ScrollID  string                         `json:"scroll_id"`
+	TotalHits uint64                         `json:"total_hits"`
+	Hits      search.DocumentMatchCollection `json:"hits"`
+	Done      bool                           `json:"done"`
+	ExpiresAt time.Time                      `json:"expires_at"`
+}
+
+// 진행 중인 스크롤 하나의 상태
+// 문서 ID 순으로 정렬하고 마지막으로 반환한 ID 다음부터 검색(search-after)하므로
+// 깊은 페이지에서도 from 오프셋만큼 히트를 다시 모을 필요가 없다.
+type scrollCursor struct {
+	mu        sync.Mutex
+	request   *bleve.SearchRequest
+	minScore  *float64
+	timeout   time.Duration
+	after     []string
+	expiresAt time.Time
+}
+
+// 메모리에 보관하는 스크롤 커서 목록 (만료된 커서는 접근할 때마다 정리)
+type scrollRegistry struct {
+	mu      sync.Mutex
+	cursors map[string]*scrollCursor
+}
+
+var scrolls = &scrollRegistry{cursors: make(map[string]*scrollCursor)}
+
+// 새 커서를 등록하고 토큰을 반환하는 함수
+func (s *scrollRegistry) add(cursor *scrollCursor) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Failed to generate scroll token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	s.cursors[token] = cursor
+	return token, nil
+}
+
+// 토큰에 해당하는 유효한 커서를 반환하는 함수
+func (s *scrollRegistry) get(token string) *scrollCursor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	return s.cursors[token]
+}
+
+// 커서를 목록에서 제거하는 함수
+func (s *scrollRegistry) remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cursors, token)
+}
+
+// 만료된 커서를 제거하는 함수 (호출 측에서 s.mu를 잡고 있어야 함)
+func (s *scrollRegistry) sweep() {
+	now := time.Now()
+	for token, cursor := range s.cursors {
+		if now.After(cursor.expiresAt) {
+			delete(s.cursors, token)
+		}
+	}
+}
+
+// 스크롤 시작 핸들러 (POST /search/scroll)
+func scrollStartHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	var req scrollRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Query) == 0 {
+		http.Error(w, "Invalid request body: 'query' is required", http.StatusBadRequest)
+		return
+	}
+	batchSize := defaultScrollBatchSize
+	if req.BatchSize != nil {
+		if *req.BatchSize <= 0 {
+			http.Error(w, "Invalid request body: 'batch_size' must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		batchSize = min(*req.BatchSize, maxScrollBatchSize)
+	}
+
+	spec, err := newSearchSpecFromBody(bytes.NewReader(req.Query), index.Mapping())
+	if err != nil {
+		var clauseErr *clauseError
+		if errors.As(err, &clauseErr) {
+			err = &clauseError{Path: "query." + clauseErr.Path, Message: clauseErr.Message}
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor := newScrollCursor(spec, batchSize)
+	token, err := scrolls.add(cursor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeScrollBatch(w, r, token, cursor)
+}
+
+// 검색 조건으로 첫 배치부터 읽는 커서를 만드는 함수
+func newScrollCursor(spec *searchSpec, batchSize int) *scrollCursor {
+	// 문서 ID 순으로 정렬해야 search-after로 이어서 읽을 수 있음
+	request := spec.request
+	request.From = 0
+	request.Size = batchSize
+	request.Explain = false
+	request.SortBy([]string{"_id"})
+
+	return &scrollCursor{
+		request:   request,
+		minScore:  spec.minScore,
+		timeout:   spec.timeout,
+		expiresAt: time.Now().Add(scrollTTL),
+	}
+}
+
+// 다음 배치 핸들러 (GET /search/scroll/{token})
+func scrollNextHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	token := r.PathValue("token")
+	cursor := scrolls.get(token)
+	if cursor == nil {
+		http.Error(w, "Scroll cursor not found or expired", http.StatusNotFound)
+		return
+	}
+
+	writeScrollBatch(w, r, token, cursor)
+}
+
+// 커서의 다음 배치를 검색해 응답하는 함수 (마지막 배치를 반환하면 커서를 제거)
+func writeScrollBatch(w http.ResponseWriter, r *http.Request, token string, cursor *scrollCursor) {
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+
+	hits, total, done, err := cursor.next(r.Context())
+	if err != nil {
+		writeSearchError(w, err, cursor.timeout)
+		return
+	}
+
+	cursor.expiresAt = time.Now().Add(scrollTTL)
+	if done {
+		scrolls.remove(token)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scrollResponse{
+		ScrollID:  token,
+		TotalHits: total,
+		Hits:      hits,
+		Done:      done,
+		ExpiresAt: cursor.expiresAt,
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// 마지막으로 반환한 ID 다음 배치를 검색하는 함수 (호출 측에서 c.mu를 잡고 있어야 함)
+// 세 번째 반환값은 마지막 배치인지 여부
+func (c *scrollCursor) next(ctx context.Context) (search.DocumentMatchCollection, uint64, bool, error) {
+	c.request.SearchAfter = c.after
+	searchResult, err := searchWithTimeout(ctx, c.request, c.timeout)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	hits := searchResult.Hits
+	done := len(hits) < c.request.Size
+	if len(hits) > 0 {
+		c.after = []string{hits[len(hits)-1].ID}
+	}
+	if c.minScore != nil {
+		// 점수 하한은 배치마다 적용 (배치가 batch_size보다 작아질 수 있음)
+		kept := hits[:0]
+		for _, hit := range hits {
+			if hit.Score >= *c.minScore {
+				kept = append(kept, hit)
+			}
+		}
+		hits = kept
+	}
+	return hits, searchResult.Total, done, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	indexapi "github.com/blevesearch/bleve_index_api"
+)
+
+// 맞춤법 제안 설정
+const (
+	suggestHitThreshold  = 3                     // 히트가 이보다 적을 때만 제안
+	maxSpellSuggestions  = 3                     // 응답에 포함할 대체 검색어 수
+	spellCandidatesLimit = 3                     // 토큰별로 유지할 후보 수
+	spellCheckBudget     = 50 * time.Millisecond // 제안 계산에 쓸 최대 시간
+)
+
+// 철자 교정 후보 (편집 거리가 같으면 문서 수가 많은 쪽이 우선)
+type spellCandidate struct {
+	term     string
+	distance int
+	count    uint64
+}
+
+// 검색어 토큰마다 텀 사전에서 편집 거리 1~2인 텀을 찾아 대체 검색어를 만드는 함수
+// 사전에 이미 있는 토큰은 그대로 두고, 없는 토큰만 가장 가깝고 흔한 텀으로 바꾼다.
+// 한글은 음절 단위로 퍼지 검색한 뒤 자모 단위 거리로 후보를 정렬하므로,
+// 자모 하나가 틀린 오타("김처")가 가장 가까운 후보("김치")로 교정된다.
+func spellSuggestions(idx bleve.Index, field, text string) ([]string, error) {
+	deadline := time.Now().Add(spellCheckBudget)
+
+	m := idx.Mapping()
+	analyzer := m.AnalyzerNamed(m.AnalyzerNameForPath(field))
+	if analyzer == nil {
+		return nil, nil
+	}
+	tokens := analyzer.Analyze([]byte(text))
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	advanced, err := idx.Advanced()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to access index: %w", err)
+	}
+	reader, err := advanced.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open index reader: %w", err)
+	}
+	defer reader.Close()
+
+	terms := make([]string, len(tokens))
+	candidates := make([][]spellCandidate, len(tokens))
+	corrected := false
+	for i, token := range tokens {
+		terms[i] = string(token.Term)
+		if time.Now().After(deadline) {
+			continue
+		}
+
+		found, err := fuzzyCandidates(reader, field, terms[i])
+		if err != nil {
+			return nil, err
+		}
+		candidates[i] = found
+		corrected = corrected || len(found) > 0
+	}
+	if !corrected {
+		return nil, nil
+	}
+
+	// k번째 제안은 각 토큰의 k번째 후보를 사용 (후보가 부족하면 마지막 후보 사용)
+	var suggestions []string
+	for k := 0; k < maxSpellSuggestions; k++ {
+		words := make([]string, len(terms))
+		for i, term := range terms {
+			words[i] = term
+			if n := len(candidates[i]); n > 0 {
+				words[i] = candidates[i][min(k, n-1)].term
+			}
+		}
+		suggestion := strings.Join(words, " ")
+		if !slices.Contains(suggestions, suggestion) {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
+}
+
+// 사전에 없는 텀에 대해 퍼지 사전 검색으로 교정 후보를 찾는 함수
+func fuzzyCandidates(reader indexapi.IndexReader, field, term string) ([]spellCandidate, error) {
+	fuzziness := 1
+	if utf8.RuneCountInString(term) > 4 {
+		fuzziness = 2
+	}
+
+	var dict indexapi.FieldDict
+	var err error
+	if fuzzyReader, ok := reader.(indexapi.IndexReaderFuzzy); ok {
+		dict, err = fuzzyReader.FieldDictFuzzy(field, term, fuzziness, "")
+	} else {
+		// 퍼지 사전을 지원하지 않는 인덱스(메모리 인덱스 등)는 전체 사전을 훑음
+		dict, err = reader.FieldDict(field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+	}
+	defer dict.Close()
+
+	var found []spellCandidate
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Term == term {
+			// 사전에 있는 토큰은 교정하지 않음
+			return nil, nil
+		}
+		if levenshtein([]rune(term), []rune(entry.Term)) > fuzziness {
+			continue
+		}
+		found = append(found, spellCandidate{
+			term:     entry.Term,
+			distance: levenshtein(decomposeHangul(term), decomposeHangul(entry.Term)),
+			count:    entry.Count,
+		})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].count > found[j].count
+	})
+	if len(found) > spellCandidatesLimit {
+		found = found[:spellCandidatesLimit]
+	}
+	return found, nil
+}
+
+// 한글 음절을 초성/중성/종성 자모로 분해하는 함수 (한글이 아닌 글자는 그대로 유지)
+func decomposeHangul(text string) []rune {
+	const (
+		syllableBase = 0xAC00
+		syllableEnd  = 0xD7A3
+		jungCount    = 21
+		jongCount    = 28
+	)
+
+	var jamo []rune
+	for _, r := range text {
+		if r < syllableBase || r > syllableEnd {
+			jamo = append(jamo, r)
+			continue
+		}
+		offset := r - syllableBase
+		jamo = append(jamo,
+			0x1100+offset/(jungCount*jongCount),
+			0x1161+(offset%(jungCount*jongCount))/jongCount,
+		)
+		if jong := offset % jongCount; jong != 0 {
+			jamo = append(jamo, 0x11A7+jong)
+		}
+	}
+	return jamo
+}
+
+// 두 글자 시퀀스 사이의 레벤슈타인 편집 거리
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
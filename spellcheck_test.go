@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLevenshteinOnJamo(t *testing.T) {
+	for _, tt := range []struct {
+		a, b     string
+		syllable int
+		jamo     int
+	}{
+		{"김치", "김치", 0, 0},
+		{"김처", "김치", 1, 1}, // 중성 하나만 다름
+		{"김치", "김밥", 1, 3},
+		{"강", "가", 1, 1},     // 종성 하나만 다름
+		{"abc", "abd", 1, 1}, // 한글이 아닌 글자는 그대로 비교
+	} {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.syllable {
+			t.Errorf("syllable distance(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.syllable)
+		}
+		if got := levenshtein(decomposeHangul(tt.a), decomposeHangul(tt.b)); got != tt.jamo {
+			t.Errorf("jamo distance(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.jamo)
+		}
+	}
+}
+
+func TestSpellSuggestions(t *testing.T) {
+	memIndex := useMemoryIndex(t)
+	indexTestDocuments(t, memIndex,
+		documentBody{Title: "1", Content: "김치 찌개"},
+		documentBody{Title: "2", Content: "김치 볶음밥"},
+		documentBody{Title: "3", Content: "김밥 찌개"},
+		documentBody{Title: "4", Content: "된장 찌개"},
+	)
+
+	for _, tt := range []struct {
+		text string
+		want []string
+	}{
+		{"김처", []string{"김치", "김밥"}},          // 자모 하나가 틀린 오타는 자모 거리가 가까운 후보가 먼저
+		{"김처 찌게", []string{"김치 찌개", "김밥 찌개"}}, // 토큰마다 교정
+		{"김치 찌개", nil},                        // 사전에 있으면 제안하지 않음
+		{"우주선", nil},                          // 가까운 텀이 없음
+	} {
+		start := time.Now()
+		got, err := spellSuggestions(memIndex, defaultSearchField, tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("spellSuggestions(%s) = %v, want %v", tt.text, got, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > 2*spellCheckBudget {
+			t.Errorf("spellSuggestions(%s) took %v, want about %v at most", tt.text, elapsed, spellCheckBudget)
+		}
+	}
+
+	search := func(params url.Values) []string {
+		rec := httptest.NewRecorder()
+		searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?"+params.Encode(), nil))
+		var response searchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", rec.Body, err)
+		}
+		return response.Suggestions
+	}
+	if got := search(url.Values{"q": {"김처"}, "suggest": {"true"}}); len(got) == 0 || got[0] != "김치" {
+		t.Errorf("search suggestions = %v, want 김치 first", got)
+	}
+	if got := search(url.Values{"q": {"김처"}}); got != nil {
+		t.Errorf("search suggestions without suggest=true = %v, want none", got)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// 통계를 모을 때 Postgres 쿼리를 기다리는 최대 시간 (넘으면 해당 값만 비우고 응답)
+const statsQueryTimeout = 2 * time.Second
+
+// 인덱스 통계 응답
+// 모니터링에서 수집할 수 있도록 필드는 항상 같은 이름으로 내보내며, 구할 수 없는 값은 null이다.
+type indexStats struct {
+	// 검색할 수 있는 인덱스가 있는지 (처음 인덱스를 만드는 중이면 false)
+	Ready bool `json:"ready"`
+	// 인덱스의 문서 수와 색인해야 할 Postgres 행 수, 그 차이 (postgres_documents - index_documents)
+	IndexDocuments    *uint64 `json:"index_documents"`
+	PostgresDocuments *uint64 `json:"postgres_documents"`
+	Divergence        *int64  `json:"divergence"`
+	// 인덱스 디렉터리와 디스크에서 차지하는 크기
+	IndexPath      string `json:"index_path"`
+	IndexSizeBytes *int64 `json:"index_size_bytes"`
+	// 세그먼트 수와 삭제되었지만 세그먼트 병합 전이라 디스크에 남아 있는 문서 수 (scorch 인덱스만)
+	Segments       *int    `json:"segments"`
+	PendingDeletes *uint64 `json:"pending_deletes"`
+	// 마지막 동기화 기준 시각(전체 또는 증분 재색인)과 이 서버에서 마지막으로 실행한 재색인 작업
+	LastSyncAt  *time.Time  `json:"last_sync_at"`
+	LastReindex *reindexJob `json:"last_reindex"`
+	// 실행 중이거나 마지막으로 실행한 압축 작업 (POST /admin/compact)
+	LastCompaction *compactionJob `json:"last_compaction"`
+	// 시작할 때 실행한 인덱스 예열과 걸린 시간 (WARMUP_QUERIES를 설정하지 않았으면 null)
+	Warmup *warmupStatus `json:"warmup"`
+	// 형태소 분석 모드(ANALYSIS_MODE, llm 또는 none)와 분석을 수행하는 곳(ANALYSIS_PROVIDER), OpenAI 모델(OPENAI_MODEL)과 temperature(OPENAI_TEMPERATURE)
+	AnalysisMode        string  `json:"analysis_mode"`
+	AnalysisProvider    string  `json:"analysis_provider"`
+	AnalysisModel       string  `json:"analysis_model"`
+	AnalysisTemperature float32 `json:"analysis_temperature"`
+	// OpenAI API 요청 속도 제한 상태 (제한을 설정하지 않아도 OpenAI가 429로 거절한 수를 보여줌)
+	OpenAIRateLimit openaiRateLimitStatus `json:"openai_rate_limit"`
+	// 인덱스에 저장된 매핑이 MAPPING_FILE과 다른지 (다르면 재색인해야 파일의 매핑이 적용됨)
+	MappingFile     string `json:"mapping_file"`
+	MappingMismatch bool   `json:"mapping_mismatch"`
+	// bleve 내부 통계 (StatsMap)
+	Bleve map[string]interface{} `json:"bleve"`
+	// 값을 구하지 못한 항목별 오류
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// 인덱스 통계 핸들러 (GET /admin/stats)
+// 오래 걸릴 수 있는 Postgres 쿼리는 statsQueryTimeout까지만 기다리고, 실패한 항목은 errors에 담아 나머지 값과 함께 응답한다.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := indexStats{MappingFile: mappingFile, AnalysisMode: analysisMode, AnalysisProvider: analysisProvider, AnalysisModel: analysisModel, AnalysisTemperature: analysisTemperature, OpenAIRateLimit: openaiLimiter.status(), Errors: make(map[string]string)}
+
+	if live, ok := index.(*swappableIndex); ok {
+		stats.Ready = live.ready()
+		stats.IndexPath = live.directory()
+	} else {
+		stats.Ready = index != nil
+	}
+	if job, ok := reindexes.current(); ok {
+		stats.LastReindex = &job
+	}
+	if job, ok := compactions.current(); ok {
+		stats.LastCompaction = &job
+	}
+	if status, ok := warmup.current(); ok {
+		stats.Warmup = &status
+	}
+
+	if stats.Ready {
+		if count, err := index.DocCount(); err != nil {
+			stats.Errors["index_documents"] = err.Error()
+		} else {
+			stats.IndexDocuments = &count
+		}
+		if usage, err := segmentCounts(index); err != nil {
+			stats.Errors["segments"] = err.Error()
+		} else if usage != nil {
+			stats.Segments = &usage.Segments
+			stats.PendingDeletes = &usage.PendingDeletes
+		}
+		if raw, err := index.GetInternal(syncWatermarkKey); err != nil {
+			stats.Errors["last_sync_at"] = err.Error()
+		} else if raw != nil {
+			if syncedAt, err := time.Parse(time.RFC3339Nano, string(raw)); err == nil {
+				stats.LastSyncAt = &syncedAt
+			}
+		}
+		stats.MappingMismatch = mappingDiffers(index.Mapping())
+		stats.Bleve = index.StatsMap()
+	}
+	if stats.IndexPath != "" {
+		if size, err := directorySize(stats.IndexPath); err != nil {
+			stats.Errors["index_size_bytes"] = err.Error()
+		} else {
+			stats.IndexSizeBytes = &size
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), statsQueryTimeout)
+	defer cancel()
+	var rows uint64
+	if db == nil {
+		stats.Errors["postgres_documents"] = errNoDatabase.Error()
+	} else if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents WHERE "+indexableDocumentsFilter).Scan(&rows); err != nil {
+		stats.Errors["postgres_documents"] = fmt.Sprintf("Failed to count documents: %v", err)
+	} else {
+		stats.PostgresDocuments = &rows
+	}
+	if stats.IndexDocuments != nil && stats.PostgresDocuments != nil {
+		divergence := int64(*stats.PostgresDocuments) - int64(*stats.IndexDocuments)
+		stats.Divergence = &divergence
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// 디렉터리 아래 파일 크기의 합을 구하는 함수
+// 병합 중에 지워지는 세그먼트 파일은 건너뛴다.
+func directorySize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to measure index directory: %w", err)
+	}
+	return size, nil
+}
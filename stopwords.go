@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/stop"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/analysis/tokenmap"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// 불용어를 제거하는 본문 분석기 구성 요소 이름 (인덱스 매핑에 저장됨)
+const (
+	stopWordsMapName     = "domain_stop_words"
+	stopWordsFilterName  = "domain_stop_filter"
+	stopWordAnalyzerName = "cjk_domain_stop"
+)
+
+// STOPWORDS_PATH에서 읽은 도메인 불용어 목록 (비어 있으면 불용어를 제거하지 않음)
+var stopWords []string
+
+// 불용어 파일을 읽는 함수 (한 줄에 하나, '#'으로 시작하는 줄은 주석)
+// 분석기의 전각/반각 및 소문자 정규화 이후에 비교되므로 normalizeTerm으로 같은 형태로 맞춘다.
+func loadStopWords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open stop word file: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word := normalizeTerm(line)
+		if !slices.Contains(words, word) {
+			words = append(words, word)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read stop word file: %w", err)
+	}
+	slices.Sort(words)
+	return words, nil
+}
+
+// CJK 분석기와 같은 토크나이저/필터 체인에 불용어 필터를 추가한 분석기를 등록하는 함수
+// 불용어는 바이그램 이전에 제거해야 한자/가나 불용어가 바이그램 조각으로 남지 않는다.
+func addStopWordAnalyzer(indexMapping *mapping.IndexMappingImpl, words []string) error {
+	if err := addStopWordFilter(indexMapping, words); err != nil {
+		return err
+	}
+	err := indexMapping.AddCustomAnalyzer(stopWordAnalyzerName, map[string]interface{}{
+		"type":      custom.Name,
+		"tokenizer": unicode.Name,
+		"token_filters": []interface{}{
+			cjk.WidthName,
+			lowercase.Name,
+			stopWordsFilterName,
+			cjk.BigramName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register stop word analyzer: %w", err)
+	}
+	return nil
+}
+
+// 불용어 목록과 그 목록으로 토큰을 제거하는 필터(stopWordsFilterName)를 매핑에 등록하는 함수
+func addStopWordFilter(indexMapping *mapping.IndexMappingImpl, words []string) error {
+	tokens := make([]interface{}, len(words))
+	for i, word := range words {
+		tokens[i] = word
+	}
+
+	err := indexMapping.AddCustomTokenMap(stopWordsMapName, map[string]interface{}{
+		"type":   tokenmap.Name,
+		"tokens": tokens,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register stop word list: %w", err)
+	}
+
+	err = indexMapping.AddCustomTokenFilter(stopWordsFilterName, map[string]interface{}{
+		"type":           stop.Name,
+		"stop_token_map": stopWordsMapName,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to register stop word filter: %w", err)
+	}
+	return nil
+}
+
+// 인덱스 매핑에 저장된 불용어 목록을 반환하는 함수
+func indexedStopWords(m mapping.IndexMapping) []string {
+	impl, ok := m.(*mapping.IndexMappingImpl)
+	if !ok || impl.CustomAnalysis == nil {
+		return nil
+	}
+	tokens, _ := impl.CustomAnalysis.TokenMaps[stopWordsMapName]["tokens"].([]interface{})
+
+	var words []string
+	for _, token := range tokens {
+		if word, ok := token.(string); ok {
+			words = append(words, word)
+		}
+	}
+	slices.Sort(words)
+	return words
+}
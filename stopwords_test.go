@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadStopWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stopwords.txt")
+	content := "# 기사 말미 문구\n무단전재\n  재배포  \n\nＣＯＰＹＲＩＧＨＴ\n무단전재\n著作権\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := loadStopWords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 주석과 빈 줄은 건너뛰고, 정규화한 뒤 중복을 없애고 정렬
+	if want := []string{"copyright", "著作権", "무단전재", "재배포"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("loadStopWords = %q, want %q", words, want)
+	}
+	if _, err := loadStopWords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("loadStopWords of a missing file succeeded")
+	}
+}
+
+func TestStopWordsLeaveTermDictionary(t *testing.T) {
+	defer func(setting string, words []string) { textAnalyzerSetting, stopWords = setting, words }(textAnalyzerSetting, stopWords)
+	doc := documentBody{Title: "서울 뉴스", Content: "서울 시청 소식 무단전재 재배포 금지 Copyright"}
+
+	for _, setting := range []string{textAnalyzerKorean, textAnalyzerCJK} {
+		t.Run(setting, func(t *testing.T) {
+			textAnalyzerSetting = setting
+
+			// 불용어가 없으면 사전에 남음
+			stopWords = nil
+			memIndex := useMemoryIndex(t)
+			if err := memIndex.Index("1", doc.indexDocument(doc.Content, time.Now(), 1)); err != nil {
+				t.Fatal(err)
+			}
+			if terms := fieldTerms(t, memIndex, defaultSearchField); !slices.Contains(terms, "무단전재") {
+				t.Fatalf("content terms without stop words = %q, want 무단전재", terms)
+			}
+			// 목록이 바뀐 인덱스는 다시 만들어야 한다고 알림
+			stopWords = []string{"copyright", "무단전재", "재배포"}
+			if mismatches := indexMappingMismatches(memIndex.Mapping()); len(mismatches) == 0 {
+				t.Error("changed stop word list is not reported as a mapping mismatch")
+			}
+
+			// 다시 만든 인덱스에는 불용어가 없음
+			memIndex = useMemoryIndex(t)
+			if err := memIndex.Index("1", doc.indexDocument(doc.Content, time.Now(), 1)); err != nil {
+				t.Fatal(err)
+			}
+			terms := fieldTerms(t, memIndex, defaultSearchField)
+			for _, word := range stopWords {
+				if slices.Contains(terms, word) {
+					t.Errorf("content terms %q contain stop word %q", terms, word)
+				}
+			}
+			if !slices.Contains(terms, "서울") {
+				t.Errorf("content terms %q lost 서울", terms)
+			}
+			if mismatches := indexMappingMismatches(memIndex.Mapping()); len(mismatches) != 0 {
+				t.Errorf("rebuilt index mismatches = %q, want none", mismatches)
+			}
+		})
+	}
+}
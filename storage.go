@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/index/scorch"
+	"github.com/blevesearch/bleve/v2/index/upsidedown"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// INDEX_TYPE에 쓰는 이름과 bleve에 등록된 백엔드 이름
+var indexTypes = map[string]string{
+	"scorch":     scorch.Name,
+	"upsidedown": upsidedown.Name,
+}
+
+// 새로 만드는 인덱스의 백엔드 (INDEX_TYPE으로 변경)
+var indexType = scorch.Name
+
+// INDEX_PATH에 지정하면 디스크 대신 메모리에 인덱스를 만드는 값
+const memoryIndexPath = ":memory:"
+
+// 인덱스를 메모리에만 만드는지 (INDEX_PATH=:memory: 또는 INDEX_IN_MEMORY=true)
+// 인덱스 디렉터리를 전혀 만들지 않으며, 인덱스는 시작할 때마다 데이터베이스에서 새로 만들고 종료하면 사라진다.
+var indexInMemory = false
+
+// 메모리 인덱스에서는 쓸 수 없는 기능임을 나타내는 오류 (501로 응답)
+var errInMemoryIndex = errors.New("Not available with an in-memory index (INDEX_PATH=:memory:)")
+
+// 인덱스 디렉터리의 백엔드가 설정과 다름을 나타내는 오류
+type indexTypeMismatchError struct {
+	path     string
+	found    string
+	expected string
+}
+
+func (e *indexTypeMismatchError) Error() string {
+	found, expected := indexTypeSetting(e.found), indexTypeSetting(e.expected)
+	return fmt.Sprintf("Index at %s uses the %s backend but INDEX_TYPE is %s: set INDEX_TYPE=%s to keep using it, or remove the directory to rebuild it from the database with the %s backend", e.path, found, expected, found, expected)
+}
+
+// INDEX_TYPE 값을 bleve의 백엔드 이름으로 바꾸는 함수
+func parseIndexType(value string) (string, error) {
+	name, ok := indexTypes[value]
+	if !ok {
+		return "", errors.New("must be scorch or upsidedown")
+	}
+	return name, nil
+}
+
+// bleve의 백엔드 이름에 해당하는 INDEX_TYPE 값을 찾는 함수 (모르는 백엔드면 그대로 반환)
+func indexTypeSetting(name string) string {
+	for setting, registered := range indexTypes {
+		if registered == name {
+			return setting
+		}
+	}
+	return name
+}
+
+// 인덱스 디렉터리를 만들 위치를 준비하는 함수 (상위 디렉터리까지 만들고 쓸 수 있는지 확인)
+func prepareIndexDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("Failed to create index directory %s: %w", dir, err)
+	}
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("Index directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// 설정한 백엔드로 새 인덱스를 만드는 함수 (메모리 인덱스 모드면 path를 무시하고 메모리에 만듦)
+func newIndex(path string, indexMapping mapping.IndexMapping) (bleve.Index, error) {
+	if indexInMemory {
+		return bleve.NewMemOnly(indexMapping)
+	}
+	return bleve.NewUsing(path, indexMapping, indexType, bleve.Config.DefaultKVStore, nil)
+}
+
+// 기존 인덱스를 여는 함수 (백엔드가 설정과 다르면 indexTypeMismatchError, 읽기 전용 모드면 읽기 전용으로 엶)
+func openIndex(path string) (bleve.Index, error) {
+	found, err := storedIndexType(path)
+	if err != nil {
+		return nil, err
+	}
+	if found != indexType {
+		return nil, &indexTypeMismatchError{path: path, found: found, expected: indexType}
+	}
+	if readOnly {
+		return bleve.OpenUsing(path, map[string]interface{}{"read_only": true})
+	}
+	return bleve.Open(path)
+}
+
+// 인덱스 디렉터리의 index_meta.json에 기록된 백엔드를 읽는 함수 (기록이 없으면 bleve와 같이 upsidedown으로 봄)
+func storedIndexType(path string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(path, "index_meta.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("Index at %s has no index_meta.json: remove the directory to rebuild it from the database", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to read index metadata: %w", err)
+	}
+	var meta struct {
+		IndexType string `json:"index_type"`
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return "", fmt.Errorf("Index at %s has corrupt index_meta.json: remove the directory to rebuild it from the database", path)
+	}
+	if meta.IndexType == "" {
+		return upsidedown.Name, nil
+	}
+	return meta.IndexType, nil
+}
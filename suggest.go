@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 자동완성 결과 개수 기본값과 최대값
+const (
+	defaultSuggestSize = 5
+	maxSuggestSize     = 50
+)
+
+// 텀 사전의 항목 (텀과 해당 텀을 포함한 문서 수)
+type termCount struct {
+	Term  string `json:"term"`
+	Count uint64 `json:"count"`
+}
+
+// 인덱스 텀 사전을 메모리에 정렬해 두고 접두어 검색을 제공하는 캐시
+// 매 요청마다 FieldDict를 훑으면 짧은 접두어에서 느려지므로, 한 번 읽은 사전을
+// 정렬된 슬라이스로 보관하고 이진 탐색으로 접두어 구간을 찾는다.
+type termSuggester struct {
+	field   string
+	mu      sync.RWMutex
+	terms   []termCount
+	loaded  bool
+	dirty   atomic.Bool
+	loading atomic.Bool
+}
+
+var suggester = &termSuggester{field: defaultSearchField}
+
+// 문서가 추가되어 캐시를 다시 읽어야 함을 표시하는 함수
+func (s *termSuggester) markDirty() {
+	s.dirty.Store(true)
+}
+
+// 인덱스의 텀 사전을 다시 읽어 캐시를 교체하는 함수
+func (s *termSuggester) refresh(idx bleve.Index) error {
+	s.dirty.Store(false)
+
+	dict, err := idx.FieldDict(s.field)
+	if err != nil {
+		s.dirty.Store(true)
+		return fmt.Errorf("Failed to read term dictionary: %w", err)
+	}
+	defer dict.Close()
+
+	// FieldDict는 사전순으로 텀을 반환하므로 별도 정렬이 필요 없음
+	var terms []termCount
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			s.dirty.Store(true)
+			return fmt.Errorf("Failed to read term dictionary: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+		terms = append(terms, termCount{Term: entry.Term, Count: entry.Count})
+	}
+
+	s.mu.Lock()
+	s.terms = terms
+	s.loaded = true
+	s.mu.Unlock()
+	return nil
+}
+
+// 캐시가 비어 있으면 즉시 읽고, 오래되었으면 백그라운드에서 갱신하는 함수
+// 갱신 중에는 이전 캐시로 응답하므로 자동완성 응답 시간이 재색인에 영향을 받지 않는다.
+func (s *termSuggester) ensureFresh(idx bleve.Index) error {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+	if !loaded {
+		return s.refresh(idx)
+	}
+
+	if s.dirty.Load() && s.loading.CompareAndSwap(false, true) {
+		go func() {
+			defer s.loading.Store(false)
+			if err := s.refresh(idx); err != nil {
+				log.Printf("Failed to refresh suggestion cache: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// 접두어로 시작하는 텀을 문서 수 내림차순으로 최대 size개 반환하는 함수
+func (s *termSuggester) lookup(prefix string, size int) []termCount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.terms), func(i int) bool { return s.terms[i].Term >= prefix })
+	end := start
+	for end < len(s.terms) && strings.HasPrefix(s.terms[end].Term, prefix) {
+		end++
+	}
+
+	matches := make([]termCount, end-start)
+	copy(matches, s.terms[start:end])
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Count > matches[j].Count })
+	if len(matches) > size {
+		matches = matches[:size]
+	}
+	return matches
+}
+
+// 자동완성 핸들러
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !indexReady(w) {
+		return
+	}
+
+	prefix := normalizeTerm(r.URL.Query().Get("q"))
+	if prefix == "" {
+		http.Error(w, "Missing query parameter 'q'", http.StatusBadRequest)
+		return
+	}
+	size, err := parseIntParam(r.URL.Query(), "size", defaultSuggestSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := suggester.ensureFresh(index); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"suggestions": suggester.lookup(prefix, min(size, maxSuggestSize)),
+	})
+}
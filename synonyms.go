@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 동의어 확장으로 만들 수 있는 검색어 변형의 최대 개수
+const maxSynonymVariants = 16
+
+// 질의 시점 동의어 사전
+// 같은 그룹에 속한 표현("핸드폰", "휴대폰", "cell phone")은 서로 바꿔 검색한다.
+// 표현은 normalizeTerm으로 정규화한 단어 열로 저장하므로 대소문자와 전각/반각 차이는 무시된다.
+type synonymSet struct {
+	mu      sync.RWMutex
+	path    string
+	groups  [][][]string   // 그룹별 표현 목록 (표현은 단어 열)
+	lookup  map[string]int // 정규화된 표현 -> 그룹 번호
+	longest int            // 가장 긴 표현의 단어 수
+}
+
+var synonyms = &synonymSet{}
+
+// 동의어 파일을 읽어 사전을 교체하는 함수
+// .json 파일은 문자열 배열의 배열, 그 외는 한 줄에 한 그룹인 CSV로 읽는다.
+func (s *synonymSet) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open synonym file: %w", err)
+	}
+	defer file.Close()
+
+	var rawGroups [][]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(file).Decode(&rawGroups); err != nil {
+			return fmt.Errorf("Failed to parse synonym file: %w", err)
+		}
+	} else {
+		reader := csv.NewReader(file)
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rawGroups, err = reader.ReadAll()
+		if err != nil {
+			return fmt.Errorf("Failed to parse synonym file: %w", err)
+		}
+	}
+
+	var groups [][][]string
+	lookup := make(map[string]int)
+	longest := 0
+	for _, raw := range rawGroups {
+		var group [][]string
+		for _, expression := range raw {
+			words := strings.Fields(normalizeTerm(expression))
+			key := strings.Join(words, " ")
+			if len(words) == 0 {
+				continue
+			}
+			if _, exists := lookup[key]; exists {
+				continue
+			}
+			lookup[key] = len(groups)
+			group = append(group, words)
+			longest = max(longest, len(words))
+		}
+		if len(group) < 2 {
+			// 짝이 없는 표현은 확장할 대상이 없으므로 제외
+			for _, words := range group {
+				delete(lookup, strings.Join(words, " "))
+			}
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	s.mu.Lock()
+	s.path = path
+	s.groups = groups
+	s.lookup = lookup
+	s.longest = longest
+	s.mu.Unlock()
+	return nil
+}
+
+// 마지막으로 읽은 파일을 다시 읽는 함수
+func (s *synonymSet) reload() (int, error) {
+	s.mu.RLock()
+	path := s.path
+	s.mu.RUnlock()
+	if path == "" {
+		return 0, fmt.Errorf("Synonym file is not configured (set SYNONYMS_PATH)")
+	}
+	if err := s.load(path); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.groups), nil
+}
+
+// 검색어에 포함된 동의어를 찾아 가능한 모든 변형 검색어를 반환하는 함수
+// 첫 번째 값은 항상 원래 검색어이며, 동의어가 없으면 원래 검색어만 반환한다.
+// 여러 단어로 된 표현은 가장 긴 표현부터 일치시킨다("cell phone" > "phone").
+func (s *synonymSet) expand(text string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	variants := []string{text}
+	if len(s.groups) == 0 {
+		return variants
+	}
+
+	// 단어 열을 고정 구간과 동의어 구간으로 나눔
+	words := strings.Fields(normalizeTerm(text))
+	var segments [][]string
+	matched := false
+	for i := 0; i < len(words); {
+		width, group := s.match(words[i:])
+		if width == 0 {
+			segments = append(segments, []string{words[i]})
+			i++
+			continue
+		}
+		var alternatives []string
+		for _, expression := range s.groups[group] {
+			alternatives = append(alternatives, strings.Join(expression, " "))
+		}
+		segments = append(segments, alternatives)
+		matched = true
+		i += width
+	}
+	if !matched {
+		return variants
+	}
+
+	// 구간별 후보의 조합으로 변형 검색어를 생성
+	combinations := []string{""}
+	for _, alternatives := range segments {
+		var next []string
+		for _, prefix := range combinations {
+			for _, alternative := range alternatives {
+				if len(next) >= maxSynonymVariants {
+					break
+				}
+				next = append(next, strings.TrimSpace(prefix+" "+alternative))
+			}
+		}
+		combinations = next
+	}
+	for _, combination := range combinations {
+		if combination != strings.Join(words, " ") {
+			variants = append(variants, combination)
+		}
+	}
+	return variants
+}
+
+// 단어 열의 앞부분과 일치하는 가장 긴 동의어 표현을 찾는 함수
+func (s *synonymSet) match(words []string) (int, int) {
+	for width := min(s.longest, len(words)); width > 0; width-- {
+		if group, ok := s.lookup[strings.Join(words[:width], " ")]; ok {
+			return width, group
+		}
+	}
+	return 0, 0
+}
+
+// 동의어 파일 재로드 핸들러
+func synonymsReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	groups, err := synonyms.reload()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"groups": groups})
+}
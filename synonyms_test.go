@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 동의어 파일을 만들어 전역 동의어 사전으로 읽는 함수 (테스트가 끝나면 이전 사전으로 되돌림)
+func useSynonyms(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	previous := synonyms
+	synonyms = &synonymSet{}
+	t.Cleanup(func() { synonyms = previous })
+	if err := synonyms.load(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSynonymExpand(t *testing.T) {
+	for _, file := range []struct{ name, content string }{
+		{"synonyms.csv", "# 휴대전화\n핸드폰, 휴대폰, Cell Phone\nＴＶ,텔레비전\n외톨이\n"},
+		{"synonyms.json", `[["핸드폰", "휴대폰", "Cell Phone"], ["ＴＶ", "텔레비전"], ["외톨이"]]`},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			useSynonyms(t, file.name, file.content)
+			for _, tt := range []struct {
+				text string
+				want []string
+			}{
+				{"핸드폰 케이스", []string{"핸드폰 케이스", "휴대폰 케이스", "cell phone 케이스"}},
+				// 여러 단어로 된 표현과 대소문자/공백 차이
+				{"CELL  phone", []string{"CELL  phone", "핸드폰", "휴대폰"}},
+				// 전각/반각 차이
+				{"tv 거치대", []string{"tv 거치대", "텔레비전 거치대"}},
+				{"ｔｖ", []string{"ｔｖ", "텔레비전"}},
+				{"휴대폰 tv", []string{"휴대폰 tv", "핸드폰 tv", "핸드폰 텔레비전", "휴대폰 텔레비전", "cell phone tv", "cell phone 텔레비전"}},
+				// 짝이 없는 표현과 사전에 없는 검색어는 그대로
+				{"외톨이", []string{"외톨이"}},
+				{"노트북", []string{"노트북"}},
+			} {
+				if got := synonyms.expand(tt.text); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("expand(%q) = %q, want %q", tt.text, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSynonymSearchAndReload(t *testing.T) {
+	indexTestDocuments(t, useMemoryIndex(t),
+		documentBody{Title: "1", Content: "휴대폰 케이스 할인"},
+		documentBody{Title: "2", Content: "노트북 가방 할인"},
+	)
+	path := useSynonyms(t, "synonyms.csv", "핸드폰,휴대폰\n")
+
+	search := func(q string) []string {
+		_, ids := searchIDs(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?"+url.Values{"q": {q}}.Encode(), nil))
+		return ids
+	}
+	if ids := search("핸드폰"); !reflect.DeepEqual(ids, []string{"1"}) {
+		t.Errorf("search 핸드폰 = %v, want [1]", ids)
+	}
+	if ids := search("랩톱"); len(ids) != 0 {
+		t.Errorf("search 랩톱 before reload = %v, want no hits", ids)
+	}
+
+	// 파일을 바꾸고 재로드하면 재시작 없이 적용
+	if err := os.WriteFile(path, []byte("핸드폰,휴대폰\n랩톱,노트북\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	synonymsReloadHandler(rec, httptest.NewRequest(http.MethodPost, "/synonyms/reload", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"groups\":2}\n" {
+		t.Fatalf("reload = %d %s, want 200 with 2 groups", rec.Code, rec.Body)
+	}
+	if ids := search("랩톱"); !reflect.DeepEqual(ids, []string{"2"}) {
+		t.Errorf("search 랩톱 after reload = %v, want [2]", ids)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 템플릿 자리표시자 ({{keyword}})
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// 템플릿 이름은 URL 경로에 쓰이므로 영문/숫자/-/_만 허용
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// 저장된 검색 템플릿 (template은 POST /search 본문 형식의 JSON)
+type searchTemplate struct {
+	Name      string          `json:"name"`
+	Template  json.RawMessage `json:"template"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// 템플릿 목록 조회(GET)와 생성(POST) 핸들러 (/templates)
+func templatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listTemplates(w, r)
+	case http.MethodPost:
+		var req struct {
+			Name     string          `json:"name"`
+			Template json.RawMessage `json:"template"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !templateNamePattern.MatchString(req.Name) {
+			http.Error(w, "Invalid template name: use 1-64 letters, digits, '-' or '_'", http.StatusBadRequest)
+			return
+		}
+		if err := validateTemplate(req.Template); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tmpl, err := scanTemplate(db.QueryRowContext(r.Context(), "INSERT INTO search_templates(name, template) VALUES($1, $2) RETURNING name, template, created_at, updated_at", req.Name, string(req.Template)))
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			http.Error(w, fmt.Sprintf("Template already exists: %s", req.Name), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save template: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tmpl)
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// 템플릿 조회(GET), 수정(PUT), 삭제(DELETE) 핸들러 (/templates/{name})
+func templateHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		tmpl, err := loadTemplate(r.Context(), name)
+		if err != nil {
+			writeTemplateError(w, name, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tmpl)
+	case http.MethodPut:
+		var req struct {
+			Template json.RawMessage `json:"template"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := validateTemplate(req.Template); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tmpl, err := scanTemplate(db.QueryRowContext(r.Context(), "UPDATE search_templates SET template = $2, updated_at = now() WHERE name = $1 RETURNING name, template, created_at, updated_at", name, string(req.Template)))
+		if err != nil {
+			writeTemplateError(w, name, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tmpl)
+	case http.MethodDelete:
+		result, err := db.ExecContext(r.Context(), "DELETE FROM search_templates WHERE name = $1", name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to delete template: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			writeTemplateError(w, name, sql.ErrNoRows)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// 템플릿 검색 핸들러 (GET /search/template/{name}?vars={"keyword":"김치"})
+func templateSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+
+	name := r.PathValue("name")
+	vars := map[string]string{}
+	if raw := r.URL.Query().Get("vars"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+			http.Error(w, "Invalid query parameter 'vars': expected a JSON object of string values", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tmpl, err := loadTemplate(r.Context(), name)
+	if err != nil {
+		writeTemplateError(w, name, err)
+		return
+	}
+	body, err := renderTemplate(tmpl.Template, vars)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	spec, err := newSearchSpecFromBody(bytes.NewReader(body), index.Mapping())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	executeSearch(w, r, spec)
+}
+
+// 템플릿 목록을 이름순으로 응답하는 함수
+func listTemplates(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.QueryContext(r.Context(), "SELECT name, template, created_at, updated_at FROM search_templates ORDER BY name")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to query templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	templates := []*searchTemplate{}
+	for rows.Next() {
+		tmpl, err := scanTemplate(rows)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scan row: %v", err), http.StatusInternalServerError)
+			return
+		}
+		templates = append(templates, tmpl)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Error iterating over rows: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"templates": templates})
+}
+
+// 이름으로 템플릿을 읽는 함수 (없으면 sql.ErrNoRows)
+func loadTemplate(ctx context.Context, name string) (*searchTemplate, error) {
+	return scanTemplate(db.QueryRowContext(ctx, "SELECT name, template, created_at, updated_at FROM search_templates WHERE name = $1", name))
+}
+
+// name, template, created_at, updated_at 순서의 행을 템플릿으로 읽는 함수
+func scanTemplate(row interface {
+	Scan(dest ...interface{}) error
+}) (*searchTemplate, error) {
+	var tmpl searchTemplate
+	var template []byte
+	if err := row.Scan(&tmpl.Name, &template, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+		return nil, err
+	}
+	tmpl.Template = template
+	return &tmpl, nil
+}
+
+// 템플릿 조회 오류를 응답하는 함수 (없는 템플릿은 404)
+func writeTemplateError(w http.ResponseWriter, name string, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, fmt.Sprintf("Template not found: %s", name), http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Failed to load template: %v", err), http.StatusInternalServerError)
+}
+
+// 템플릿이 자리표시자를 그대로 둔 상태로도 올바른 검색 본문인지 확인하는 함수
+// 자리표시자는 JSON 문자열 값 안에만 올 수 있으므로, 치환 전 템플릿도 검색 본문으로 해석되어야 한다.
+func validateTemplate(template json.RawMessage) error {
+	if len(template) == 0 {
+		return fmt.Errorf("Invalid request body: 'template' is required")
+	}
+	if err := indexUnavailable(); err != nil {
+		return err
+	}
+	if _, err := newSearchSpecFromBody(bytes.NewReader(template), index.Mapping()); err != nil {
+		return fmt.Errorf("Invalid template: %v", err)
+	}
+	return nil
+}
+
+// 템플릿의 문자열 값 안에 있는 자리표시자를 변수 값으로 치환하는 함수
+// 텍스트 치환 대신 JSON을 해석한 뒤 문자열 값만 바꾸므로, 변수 값에 따옴표나 괄호가 있어도
+// 쿼리 구조(절, 필드, 필터)는 바뀌지 않는다.
+func renderTemplate(template json.RawMessage, vars map[string]string) ([]byte, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(template))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("Invalid template: %v", err)
+	}
+
+	var missing error
+	var render func(node interface{}) interface{}
+	render = func(node interface{}) interface{} {
+		switch value := node.(type) {
+		case string:
+			return templatePlaceholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+				name := templatePlaceholderPattern.FindStringSubmatch(placeholder)[1]
+				replacement, ok := vars[name]
+				if !ok && missing == nil {
+					missing = fmt.Errorf("Missing template variable '%s'", name)
+				}
+				return replacement
+			})
+		case []interface{}:
+			for i, item := range value {
+				value[i] = render(item)
+			}
+			return value
+		case map[string]interface{}:
+			for key, item := range value {
+				value[key] = render(item)
+			}
+			return value
+		default:
+			return value
+		}
+	}
+	rendered := render(tree)
+	if missing != nil {
+		return nil, missing
+	}
+	return json.Marshal(rendered)
+}
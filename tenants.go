@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/lib/pq"
+)
+
+// 이름 붙은 인덱스의 bleve 디렉터리를 두는 위치 (indexDir 아래, 인덱스마다 이름으로 된 하위 디렉터리)
+const namedIndexesDir = ".indexes"
+
+// 인덱스 이름은 URL 경로와 디렉터리 이름에 쓰이므로 영문/숫자/-/_만 허용
+var indexNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// 이름 붙은 인덱스의 문서 행을 읽는 쿼리 ($1은 인덱스 이름)
+const namedDocumentsSQL = "SELECT id, COALESCE(title, ''), content, analysis, tags, price, created_at, latitude, longitude, metadata, version, expires_at, COALESCE(doc_type, ''), external_id, boost FROM documents WHERE tenant = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > now())"
+
+// 이름 붙은 인덱스에 문서를 추가하는 쿼리 (인자는 upsertDocumentSQL 뒤에 인덱스 이름을 붙인 것)
+const insertNamedDocumentSQL = `INSERT INTO documents(tenant, external_id, title, content, analysis, tags, price, latitude, longitude, created_at, metadata, expires_at, content_hash, doc_type, boost, analysis_source)
+VALUES($16, $1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, COALESCE($9, now()), $10, $11, $12, NULLIF($13, ''), COALESCE($14, 1), $15)
+RETURNING id, created_at, version`
+
+// 고객별로 분리된 문서 모음 하나 (Postgres에서는 tenant 컬럼이 이름과 같은 행)
+type namedIndex struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	index     bleve.Index
+	path      string
+}
+
+// 이름 붙은 인덱스 목록
+type namedIndexRegistry struct {
+	mu      sync.RWMutex
+	indexes map[string]*namedIndex
+}
+
+var namedIndexes = &namedIndexRegistry{indexes: make(map[string]*namedIndex)}
+
+// 이름 붙은 인덱스를 찾는 함수 (없으면 nil)
+func (n *namedIndexRegistry) get(name string) *namedIndex {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.indexes[name]
+}
+
+// 이름 순으로 정렬한 인덱스 목록을 반환하는 함수
+func (n *namedIndexRegistry) list() []*namedIndex {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	list := make([]*namedIndex, 0, len(n.indexes))
+	for _, named := range n.indexes {
+		list = append(list, named)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// 이름 붙은 인덱스에서 문서를 지우는 함수 (인덱스가 이미 지워졌으면 아무것도 하지 않음)
+func (n *namedIndexRegistry) deleteDocuments(name string, ids []string) error {
+	named := n.get(name)
+	if named == nil {
+		return nil
+	}
+	batch := named.index.NewBatch()
+	for _, id := range ids {
+		batch.Delete(id)
+	}
+	return named.index.Batch(batch)
+}
+
+// 이름 붙은 인덱스의 디렉터리 경로 (메모리 인덱스 모드면 빈 문자열)
+func namedIndexPath(name string) string {
+	if indexInMemory {
+		return ""
+	}
+	return filepath.Join(indexDir, namedIndexesDir, name)
+}
+
+// Postgres에 등록된 이름 붙은 인덱스를 모두 여는 함수
+// 디렉터리가 없는 인덱스(다른 서버에서 만들었거나 디렉터리를 지운 경우)는 Postgres의 행으로 다시 만든다.
+func loadNamedIndexes(ctx context.Context) error {
+	rows, err := db.QueryContext(ctx, "SELECT name, created_at FROM indexes ORDER BY name")
+	if err != nil {
+		return fmt.Errorf("Failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		named := &namedIndex{}
+		if err := rows.Scan(&named.Name, &named.CreatedAt); err != nil {
+			return fmt.Errorf("Failed to scan row: %w", err)
+		}
+		named.path = namedIndexPath(named.Name)
+		if _, err := os.Stat(named.path); err == nil {
+			named.index, err = openIndex(named.path)
+			if err != nil {
+				return fmt.Errorf("Failed to open index '%s': %w", named.Name, err)
+			}
+		} else if readOnly {
+			return fmt.Errorf("Index '%s' not found at %s: READ_ONLY=true, so it is not created from the database", named.Name, named.path)
+		} else {
+			log.Printf("Index '%s' not found, creating it from database...", named.Name)
+			if err := buildNamedIndex(named); err != nil {
+				return err
+			}
+		}
+		namedIndexes.indexes[named.Name] = named
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error iterating over rows: %w", err)
+	}
+	return nil
+}
+
+// 이름 붙은 인덱스의 디렉터리를 새로 만들고 Postgres의 행을 색인하는 함수 (실패하면 만들던 디렉터리를 지움)
+func buildNamedIndex(named *namedIndex) error {
+	if err := fillNamedIndex(named); err != nil {
+		if named.index != nil {
+			named.index.Close()
+		}
+		os.RemoveAll(named.path)
+		return err
+	}
+	return nil
+}
+
+func fillNamedIndex(named *namedIndex) error {
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		return fmt.Errorf("Failed to build index mapping: %w", err)
+	}
+	named.index, err = newIndex(named.path, indexMapping)
+	if err != nil {
+		return fmt.Errorf("Failed to create index '%s': %w", named.Name, err)
+	}
+
+	batcher := &documentBatcher{target: named.index, size: indexBatchSize}
+	_, err = indexRows(&indexRowPages{query: namedDocumentsSQL, args: []interface{}{named.Name}}, batcher.add, func(err error) error {
+		if err != nil && strictIndexBuild {
+			return err
+		}
+		if err != nil {
+			log.Printf("Skipping document while building index '%s': %v", named.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return batcher.flush()
+}
+
+// 이름 붙은 인덱스 목록 핸들러 (GET /indexes)
+func listNamedIndexesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"indexes": namedIndexes.list()})
+}
+
+// 이름 붙은 인덱스 생성 핸들러 (POST /indexes/{name}, 이미 있으면 409)
+func createNamedIndexHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !indexNamePattern.MatchString(name) {
+		http.Error(w, "Invalid index name: use 1-64 letters, digits, '-' or '_'", http.StatusBadRequest)
+		return
+	}
+
+	namedIndexes.mu.Lock()
+	defer namedIndexes.mu.Unlock()
+	if _, ok := namedIndexes.indexes[name]; ok {
+		http.Error(w, fmt.Sprintf("Index already exists: %s", name), http.StatusConflict)
+		return
+	}
+
+	tx, err := beginTx(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	named := &namedIndex{Name: name, path: namedIndexPath(name)}
+	err = tx.QueryRowContext(r.Context(), "INSERT INTO indexes(name) VALUES($1) RETURNING created_at", name).Scan(&named.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		http.Error(w, fmt.Sprintf("Index already exists: %s", name), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create index: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// 이전에 같은 이름으로 만들었다가 남은 디렉터리는 지우고 새로 만듦
+	indexMapping, err := buildIndexMapping()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build index mapping: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := os.RemoveAll(named.path); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove previous index directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	named.index, err = newIndex(named.path, indexMapping)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create index: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		named.index.Close()
+		os.RemoveAll(named.path)
+		http.Error(w, fmt.Sprintf("Failed to commit index: %v", err), http.StatusInternalServerError)
+		return
+	}
+	namedIndexes.indexes[name] = named
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(named)
+}
+
+// 이름 붙은 인덱스 삭제 핸들러 (DELETE /indexes/{name})
+// 인덱스에 속한 문서 행도 함께 삭제하고 bleve 디렉터리를 지운다.
+func deleteNamedIndexHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	namedIndexes.mu.Lock()
+	defer namedIndexes.mu.Unlock()
+	named, ok := namedIndexes.indexes[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Index not found: %s", name), http.StatusNotFound)
+		return
+	}
+
+	// 문서 행은 외래 키의 ON DELETE CASCADE로 함께 삭제됨
+	if _, err := db.ExecContext(r.Context(), "DELETE FROM indexes WHERE name = $1", name); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete index: %v", err), http.StatusInternalServerError)
+		return
+	}
+	delete(namedIndexes.indexes, name)
+	if err := named.index.Close(); err != nil {
+		log.Printf("Failed to close index '%s': %v", name, err)
+	}
+	if err := os.RemoveAll(named.path); err != nil {
+		log.Printf("Failed to remove index directory %s: %v", named.path, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 경로의 이름으로 이름 붙은 인덱스를 찾는 함수 (없으면 404를 응답하고 nil을 반환)
+func requestNamedIndex(w http.ResponseWriter, r *http.Request) *namedIndex {
+	name := r.PathValue("name")
+	named := namedIndexes.get(name)
+	if named == nil {
+		http.Error(w, fmt.Sprintf("Index not found: %s", name), http.StatusNotFound)
+	}
+	return named
+}
+
+// 이름 붙은 인덱스 검색 핸들러 (GET|POST /indexes/{name}/search, 파라미터와 본문은 /search와 같음)
+func namedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	named := requestNamedIndex(w, r)
+	if named == nil {
+		return
+	}
+
+	var spec *searchSpec
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		spec, err = newSearchSpecFromParams(r.URL.Query(), named.index.Mapping())
+	case http.MethodPost:
+		spec, err = newSearchSpecFromBody(r.Body, named.index.Mapping())
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spec.target = named.index
+
+	executeSearch(w, r, spec)
+}
+
+// 이름 붙은 인덱스 데이터 삽입 핸들러 (POST /indexes/{name}/insert, 본문과 응답은 /insert와 같음)
+// id와 external_id는 기본 인덱스의 문서와 겹치지 않도록 아직 지원하지 않는다.
+func namedInsertHandler(w http.ResponseWriter, r *http.Request) {
+	named := requestNamedIndex(w, r)
+	if named == nil {
+		return
+	}
+
+	rejectDuplicates := r.URL.Query().Get("reject_duplicates") == "true"
+	req, err := decodeDocumentBody(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID != nil || req.ExternalID != nil {
+		http.Error(w, "Invalid request body: 'id' and 'external_id' are not supported for named indexes", http.StatusBadRequest)
+		return
+	}
+
+	// 내용이 같은 문서가 같은 인덱스에 이미 있으면 분석하지 않고 기존 문서로 응답
+	if respondIfDuplicate(w, r, &named.Name, req, rejectDuplicates) {
+		return
+	}
+
+	// OpenAI API를 사용하여 형태소 분석 수행
+	ctx := r.Context()
+	tokens, source, err := getMorphologicalAnalysis(ctx, req.Content)
+	if requestCancelled(r) {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to analyze text: %v", err), http.StatusInternalServerError)
+		return
+	}
+	analysis := analysisText(tokens)
+
+	tx, err := beginTx(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	var createdAt time.Time
+	var version int
+	err = tx.QueryRowContext(ctx, insertNamedDocumentSQL, append(req.upsertArgs(analysis, source), named.Name)...).Scan(&id, &createdAt, &version)
+	if requestCancelled(r) {
+		return
+	}
+	if isDuplicateContentError(err) && respondIfDuplicate(w, r, &named.Name, req, rejectDuplicates) {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// 색인이 실패하면 커밋하지 않아 DB와 인덱스가 어긋나지 않게 함
+	if err := named.index.Index(strconv.Itoa(id), req.indexDocument(analysis, createdAt, version)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to index data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Document %d was indexed in '%s' but the database commit failed: %v", id, named.Name, err)
+		http.Error(w, fmt.Sprintf("Failed to commit insert: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	setVersionHeader(w, version)
+	writeInsertResponse(w, r, http.StatusCreated, analyzedInsertResponse(id, tokens, source))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	indexapi "github.com/blevesearch/bleve_index_api"
+)
+
+// 텀 집계 결과 개수 기본값과 최대값
+const (
+	defaultTermsSize = 50
+	maxTermsSize     = 1000
+)
+
+// 문서 빈도가 가장 낮은 텀이 맨 앞에 오는 힙 (상위 N개 유지용)
+type termCountHeap []termCount
+
+func (h termCountHeap) Len() int            { return len(h) }
+func (h termCountHeap) Less(i, j int) bool  { return rankedBelow(h[i], h[j]) }
+func (h termCountHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *termCountHeap) Push(x interface{}) { *h = append(*h, x.(termCount)) }
+func (h *termCountHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// a가 b보다 순위가 낮은지 비교하는 함수 (문서 빈도가 같으면 사전순으로 뒤인 쪽이 낮음)
+func rankedBelow(a, b termCount) bool {
+	if a.Count != b.Count {
+		return a.Count < b.Count
+	}
+	return a.Term > b.Term
+}
+
+// 텀 집계 핸들러 (GET /terms?field=content&size=50&prefix=김&min_doc_count=2)
+func termsHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	field := values.Get("field")
+	if field == "" {
+		field = defaultSearchField
+	}
+	if err := validateFields("field", []string{field}, index.Mapping()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 숫자/날짜 필드의 사전은 인코딩된 값이므로 텍스트 필드만 허용
+	if mappedFields(index.Mapping())[field].Type != "text" {
+		http.Error(w, fmt.Sprintf("Invalid query parameter 'field': field '%s' is not a text field", field), http.StatusBadRequest)
+		return
+	}
+	size, err := parseIntParam(values, "size", defaultTermsSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	minDocCount, err := parseIntParam(values, "min_doc_count", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	terms, err := topTermsByDocCount(index, field, normalizeTerm(values.Get("prefix")), min(size, maxTermsSize), uint64(minDocCount))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"field": field,
+		"terms": terms,
+	})
+}
+
+// 필드 사전을 한 번 훑으며 문서 빈도 상위 N개 텀만 힙에 유지하는 함수
+// 사전 전체를 메모리에 올리지 않으므로 텀이 많은 인덱스에서도 메모리 사용량은 size에 비례한다.
+func topTermsByDocCount(idx bleve.Index, field, prefix string, size int, minDocCount uint64) ([]termCount, error) {
+	if size == 0 {
+		return []termCount{}, nil
+	}
+
+	var dict indexapi.FieldDict
+	var err error
+	if prefix != "" {
+		dict, err = idx.FieldDictPrefix(field, []byte(prefix))
+	} else {
+		dict, err = idx.FieldDict(field)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+	}
+	defer dict.Close()
+
+	top := termCountHeap{}
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Count < minDocCount {
+			continue
+		}
+
+		candidate := termCount{Term: entry.Term, Count: entry.Count}
+		if top.Len() < size {
+			heap.Push(&top, candidate)
+		} else if rankedBelow(top[0], candidate) {
+			top[0] = candidate
+			heap.Fix(&top, 0)
+		}
+	}
+
+	// 문서 빈도 내림차순, 같으면 사전순
+	terms := []termCount(top)
+	sort.Slice(terms, func(i, j int) bool { return rankedBelow(terms[j], terms[i]) })
+	return terms, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// 검색 검증 결과 (오류가 절에서 발생하면 path에 JSON 경로를 담음)
+type validateResponse struct {
+	Valid     bool   `json:"valid"`
+	QueryType string `json:"query_type,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// 검색 본문 검증 핸들러 (POST /search/validate)
+// POST /search와 같은 방식으로 쿼리를 구성하지만 실행하지 않으며, 인덱스는 매핑 조회에만 사용한다.
+func validateSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	spec, err := newSearchSpecFromBody(r.Body, index.Mapping())
+	if err == nil {
+		err = spec.request.Validate()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		response := validateResponse{Error: err.Error()}
+		var clauseErr *clauseError
+		if errors.As(err, &clauseErr) {
+			response.Path = clauseErr.Path
+			response.Error = clauseErr.Message
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	json.NewEncoder(w).Encode(validateResponse{Valid: true, QueryType: spec.queryType})
+}
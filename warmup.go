@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 예열 검색 하나를 기다리는 최대 시간
+const warmupQueryTimeout = 10 * time.Second
+
+// 예열 상태
+const (
+	warmupRunning   = "running"
+	warmupCompleted = "completed"
+	warmupSkipped   = "skipped"
+)
+
+// 시작할 때 실행하는 인덱스 예열 설정 (WARMUP_QUERIES, 비어 있으면 예열하지 않음)
+// 숫자면 본문 텀 사전에서 그 수만큼 무작위로 고른 텀을, 아니면 파일의 줄마다 적힌 검색을 실행한다.
+var warmupQueries string
+
+// 인덱스 예열 진행 상황 (GET /admin/stats의 warmup)
+type warmupStatus struct {
+	Status string `json:"status"`
+	// 검색 목록의 출처 (file 또는 sample)와 실행한 검색 수, 그중 실패한 검색 수
+	Source     string     `json:"source"`
+	Queries    int        `json:"queries"`
+	Failed     int        `json:"failed"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// 예열 진행 상황 (예열을 설정하지 않았으면 nil)
+type warmupRegistry struct {
+	mu     sync.Mutex
+	status *warmupStatus
+}
+
+var warmup = &warmupRegistry{}
+
+// 예열 진행 상황의 복사본을 반환하는 함수 (실행 중이면 지금까지 걸린 시간을 채움)
+func (r *warmupRegistry) current() (warmupStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status == nil {
+		return warmupStatus{}, false
+	}
+	status := *r.status
+	if status.Status == warmupRunning {
+		status.DurationMS = time.Since(status.StartedAt).Milliseconds()
+	}
+	return status, true
+}
+
+// 예열이 끝났는지 확인하는 함수 (예열을 설정하지 않았으면 true)
+func (r *warmupRegistry) done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status == nil || r.status.Status != warmupRunning
+}
+
+// 예열 검색 하나의 결과를 기록하는 함수
+func (r *warmupRegistry) advance(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Queries++
+	if err != nil {
+		r.status.Failed++
+	}
+}
+
+// 예열을 마친 것으로 기록하는 함수
+func (r *warmupRegistry) finish(status string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.status.Status = status
+	r.status.FinishedAt = &now
+	r.status.DurationMS = now.Sub(r.status.StartedAt).Milliseconds()
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+}
+
+// WARMUP_QUERIES 값을 확인하는 함수 (숫자면 양수여야 하고, 아니면 읽을 수 있는 파일이어야 함)
+func validateWarmupQueries(raw string) error {
+	if count, err := strconv.Atoi(raw); err == nil {
+		if count <= 0 {
+			return errors.New("sample count must be a positive integer")
+		}
+		return nil
+	}
+	_, err := readWarmupFile(raw)
+	return err
+}
+
+// 예열 검색 파일을 읽는 함수
+// 줄마다 GET /search의 쿼리 문자열(예: q=김치&sort=-created_at)을 적고, '='가 없는 줄은 검색어(q)로 사용한다.
+// 빈 줄과 #으로 시작하는 줄은 건너뛴다.
+func readWarmupFile(path string) ([]url.Values, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open warm-up query file: %w", err)
+	}
+	defer file.Close()
+
+	var queries []url.Values
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if !strings.Contains(text, "=") {
+			queries = append(queries, url.Values{"q": {text}})
+			continue
+		}
+		values, err := url.ParseQuery(text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid warm-up query on line %d: %v", line, err)
+		}
+		queries = append(queries, values)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read warm-up query file: %w", err)
+	}
+	return queries, nil
+}
+
+// 본문 텀 사전에서 count개의 텀을 무작위로 골라 검색 목록을 만드는 함수
+// 사전 전체를 훑으므로 사전도 함께 캐시에 올라간다.
+func sampleWarmupQueries(count int) ([]url.Values, error) {
+	dict, err := index.FieldDict(defaultSearchField)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+	}
+	defer dict.Close()
+
+	terms := make([]string, 0, count)
+	seen := 0
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read term dictionary: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+		// 사전 크기를 모르므로 저수지 표본 추출로 고름
+		seen++
+		if len(terms) < count {
+			terms = append(terms, entry.Term)
+		} else if i := rand.Intn(seen); i < count {
+			terms[i] = entry.Term
+		}
+	}
+
+	queries := make([]url.Values, 0, len(terms))
+	for _, term := range terms {
+		queries = append(queries, url.Values{"q": {term}})
+	}
+	return queries, nil
+}
+
+// 인덱스 예열을 시작하는 함수 (WARMUP_QUERIES를 설정하지 않았으면 아무것도 하지 않음)
+// 예열은 백그라운드에서 실행하며, 끝날 때까지 /readyz는 503을 응답한다.
+// 처음 인덱스를 만드는 중이면 새로 색인한 데이터가 이미 캐시에 있으므로 건너뛴다.
+func startWarmup() {
+	if warmupQueries == "" {
+		return
+	}
+	source := "file"
+	if _, err := strconv.Atoi(warmupQueries); err == nil {
+		source = "sample"
+	}
+	warmup.mu.Lock()
+	warmup.status = &warmupStatus{Status: warmupRunning, Source: source, StartedAt: time.Now()}
+	warmup.mu.Unlock()
+
+	if err := indexUnavailable(); err != nil {
+		log.Printf("Skipping index warm-up: %v", err)
+		warmup.finish(warmupSkipped, err)
+		return
+	}
+	go func() {
+		err := runWarmup()
+		if err != nil {
+			log.Printf("Index warm-up failed: %v", err)
+		}
+		warmup.finish(warmupCompleted, err)
+		status, _ := warmup.current()
+		log.Printf("Index warm-up ran %d queries (%d failed) in %dms", status.Queries, status.Failed, status.DurationMS)
+	}()
+}
+
+// 예열 검색 목록을 만들어 차례로 실행하는 함수 (검색 하나가 실패해도 나머지는 계속 실행)
+func runWarmup() error {
+	var queries []url.Values
+	var err error
+	if count, convErr := strconv.Atoi(warmupQueries); convErr == nil {
+		queries, err = sampleWarmupQueries(count)
+	} else {
+		queries, err = readWarmupFile(warmupQueries)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, values := range queries {
+		spec, err := newSearchSpecFromParams(values, index.Mapping())
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), warmupQueryTimeout)
+			_, err = runSearch(ctx, spec)
+			cancel()
+		}
+		if err != nil {
+			log.Printf("Warm-up query '%s' failed: %v", values.Encode(), err)
+		}
+		warmup.advance(err)
+	}
+	return nil
+}
+
+// 준비 상태 핸들러 (GET /readyz)
+// 검색할 인덱스가 있고 예열이 끝났으면 200, 아니면 이유와 함께 503을 응답한다.
+// 서버가 살아 있는지는 GET /livez로 확인하며, 인덱스를 만들거나 예열하는 중에도 200이다.
+func readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if !indexReady(w) {
+		return
+	}
+	if !warmup.done() {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Index is warming up", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}